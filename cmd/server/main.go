@@ -2,81 +2,155 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
-	chimiddleware "github.com/go-chi/chi/v5/middleware"
-	"github.com/go-chi/cors"
 
 	"github.com/Cerebrovinny/fizz-buzz-rest/internal/config"
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/fizzbuzz"
 	"github.com/Cerebrovinny/fizz-buzz-rest/internal/handler"
-	mw "github.com/Cerebrovinny/fizz-buzz-rest/internal/middleware"
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/metrics"
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/server"
 	"github.com/Cerebrovinny/fizz-buzz-rest/internal/statistics"
 )
 
 func main() {
+	checkConfig := flag.Bool("check-config", false, "validate configuration, print a summary, and exit")
+	flag.Parse()
+
 	cfg, err := config.Load()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "failed to load config: %v\n", err)
-		os.Exit(1)
+		os.Exit(config.ExitCode(err))
+	}
+
+	if *checkConfig || os.Getenv("CHECK_CONFIG") == "true" {
+		if err := config.ValidateAndSummarize(cfg, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid config: %v\n", err)
+			os.Exit(config.ExitCode(err))
+		}
+		fmt.Fprintln(os.Stdout, "config OK")
+		os.Exit(0)
 	}
 
-	logger := buildLogger(cfg)
+	level := new(slog.LevelVar)
+	logger := buildLogger(cfg, level)
 	slog.SetDefault(logger)
 	logger.Info("starting server",
 		slog.String("port", cfg.Port),
 		slog.String("log_level", cfg.LogLevel),
 		slog.String("log_format", cfg.LogFormat),
+		slog.Int("max_limit", cfg.MaxLimit),
+		slog.Bool("truncate_mode", cfg.TruncateMode),
 	)
 
-	store := statistics.NewStore()
-	router := chi.NewRouter()
-
-	router.Use(chimiddleware.RequestID)
-	router.Use(chimiddleware.RealIP)
-	router.Use(mw.RequestLogger(logger))
-	router.Use(chimiddleware.Recoverer)
-	router.Use(chimiddleware.Timeout(cfg.RequestTimeout))
-	router.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   cfg.CORSAllowedOrigins,
-		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Request-ID"},
-		ExposedHeaders:   []string{"Link"},
-		AllowCredentials: false,
-		MaxAge:           300,
-	}))
-
-	h := handler.NewHandler(store, logger)
-	router.With(mw.Statistics(store)).Get("/fizzbuzz", h.FizzBuzz)
-	router.Get("/statistics", h.Statistics)
-	router.Get("/health", h.Health)
-
-	logger.Info("routes registered", slog.Int("route_count", 3))
-
-	server := &http.Server{
-		Addr:         ":" + cfg.Port,
-		Handler:      router,
-		ReadTimeout:  cfg.ReadTimeout,
-		WriteTimeout: cfg.WriteTimeout,
-		IdleTimeout:  cfg.IdleTimeout,
+	if cfg.StartupSelfTest {
+		if err := fizzbuzz.SelfTest(); err != nil {
+			logger.Error("startup self-test failed", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		logger.Info("startup self-test passed")
+	}
+
+	store := statistics.NewStore(
+		statistics.WithMaxEntries(cfg.MaxDistinctParams),
+		statistics.WithDecay(cfg.StatsDecayHalfLife),
+		statistics.WithHotParams(cfg.StatsHotParams...),
+	)
+	if cfg.StatsPersistPath != "" {
+		if err := statistics.PreparePersistDir(cfg.StatsPersistPath, cfg.StatsCreateDir); err != nil {
+			logger.Error("statistics persistence unusable; fix STATS_PERSIST_PATH or set STATS_CREATE_DIR=true",
+				slog.String("path", cfg.StatsPersistPath), slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		if err := store.LoadFile(cfg.StatsPersistPath); err != nil {
+			logger.Error("failed to restore statistics", slog.String("path", cfg.StatsPersistPath), slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		logger.Info("statistics restored", slog.String("path", cfg.StatsPersistPath), slog.Int("entries", store.Len()))
+	}
+	registry := metrics.NewRegistry()
+	h := handler.NewHandler(store, logger,
+		handler.WithMaxLimit(cfg.MaxLimit, cfg.TruncateMode),
+		handler.WithConcurrencyLimit(cfg.MaxConcurrentGenerations, cfg.HeavyGenerationLimit),
+		handler.WithMaxResponseBytes(cfg.MaxResponseBytes),
+		handler.WithDivisorRange(cfg.MinDivisor, cfg.MaxDivisor),
+		handler.WithAllowedStrings(cfg.AllowedStrings),
+		handler.WithStrictIntegers(cfg.StrictIntegers),
+		handler.WithSemanticErrorStatus(cfg.SemanticErrorStatus),
+		handler.WithUsageHint(cfg.UsageHint),
+		handler.WithStreamWriteTimeout(cfg.WriteTimeout),
+		handler.WithStatisticsInterval(cfg.StreamInterval),
+		handler.WithMetrics(registry),
+		handler.WithForceUnhealthy(cfg.ForceUnhealthy),
+		handler.WithResponseShape(cfg.ResponseShape),
+		handler.WithMaxTopN(cfg.MaxTopN),
+		handler.WithMaxRules(cfg.MaxRules),
+	)
+	if cfg.StatsPersistPath != "" {
+		h.RegisterHealthChecker(handler.NewDirWritableChecker("statistics_persistence", filepath.Dir(cfg.StatsPersistPath)))
+	}
+	runtime := server.NewRuntime(cfg, level, h)
+	router := server.NewRouter(server.Options{
+		Config:    cfg,
+		Logger:    logger,
+		AccessLog: os.Stdout,
+		Store:     store,
+		Metrics:   registry,
+		Handlers:  h,
+		Runtime:   runtime,
+	})
+
+	routeCount := 0
+	_ = chi.Walk(router, func(string, string, http.Handler, ...func(http.Handler) http.Handler) error {
+		routeCount++
+		return nil
+	})
+	logger.Info("routes registered", slog.Int("route_count", routeCount))
+
+	// Cancelling the base context on shutdown ends open /statistics/stream
+	// feeds, which would otherwise hold Shutdown until its timeout.
+	baseCtx, cancelBase := context.WithCancel(context.Background())
+	defer cancelBase()
+	srv := server.NewHTTPServer(baseCtx, cfg, router)
+	srv.RegisterOnShutdown(cancelBase)
+	go store.RunDecay(baseCtx)
+	if cfg.StatsPersistPath != "" {
+		go persistStatistics(baseCtx, store, cfg.StatsPersistPath, cfg.StatsPersistInterval, cfg.StatsSaveRetries, logger)
 	}
 
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
 	go func() {
-		logger.Info("server listening", slog.String("addr", server.Addr))
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		logger.Info("server listening", slog.String("addr", srv.Addr))
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			logger.Error("server failed", slog.String("error", err.Error()))
 			os.Exit(1)
 		}
 	}()
 
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+	go func() {
+		for range hupChan {
+			next, err := config.Load()
+			if err != nil {
+				logger.Error("config reload failed, keeping current settings", slog.String("error", err.Error()))
+				continue
+			}
+			runtime.Reload(next, logger)
+		}
+	}()
+
 	sig := <-sigChan
 	logger.Info("shutdown signal received", slog.String("signal", sig.String()))
 
@@ -85,32 +159,68 @@ func main() {
 
 	logger.Info("shutting down server", slog.Duration("timeout", cfg.ShutdownTimeout))
 
-	if err := server.Shutdown(ctx); err != nil {
+	if err := srv.Shutdown(ctx); err != nil {
 		logger.Error("server shutdown error", slog.String("error", err.Error()))
 		os.Exit(1)
 	}
 
+	if cfg.StatsPersistPath != "" {
+		err := statistics.SaveWithRetry(context.Background(), cfg.StatsSaveRetries, func() error {
+			return store.SaveFile(cfg.StatsPersistPath)
+		})
+		if err != nil {
+			logger.Error("failed to save statistics", slog.String("path", cfg.StatsPersistPath), slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		logger.Info("statistics saved", slog.String("path", cfg.StatsPersistPath))
+	}
+
 	logger.Info("server stopped")
 }
 
-func buildLogger(cfg *config.Config) *slog.Logger {
-	level := slog.LevelInfo
+// persistStatistics saves the store to path every interval until ctx is done,
+// retrying each failed save up to retries times. The final save happens after
+// shutdown, once no more requests are recorded.
+func persistStatistics(ctx context.Context, store *statistics.Store, path string, interval time.Duration, retries int, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := statistics.SaveWithRetry(ctx, retries, func() error { return store.SaveFile(path) })
+			if err != nil {
+				logger.Error("failed to save statistics", slog.String("path", path), slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+// buildLogger sets level from cfg and returns a logger filtered by it, so the
+// level can be changed later through the LevelVar.
+func buildLogger(cfg *config.Config, level *slog.LevelVar) *slog.Logger {
 	switch cfg.LogLevel {
 	case "debug":
-		level = slog.LevelDebug
+		level.Set(slog.LevelDebug)
 	case "info":
-		level = slog.LevelInfo
+		level.Set(slog.LevelInfo)
 	case "warn":
-		level = slog.LevelWarn
+		level.Set(slog.LevelWarn)
 	case "error":
-		level = slog.LevelError
+		level.Set(slog.LevelError)
 	}
 
 	options := &slog.HandlerOptions{Level: level}
 	var handler slog.Handler
-	if cfg.LogFormat == "json" {
+	switch cfg.LogFormat {
+	case "json":
 		handler = slog.NewJSONHandler(os.Stdout, options)
-	} else {
+	case "clf":
+		// Access logs own stdout in CLF mode; keep application logs
+		// readable and off the access log stream.
+		handler = slog.NewTextHandler(os.Stderr, options)
+	default:
 		handler = slog.NewTextHandler(os.Stdout, options)
 	}
 
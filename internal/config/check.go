@@ -0,0 +1,108 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// ValidateAndSummarize validates cfg and writes one NAME=value line per
+// setting to w, redacting secrets. It backs the --check-config dry run.
+func ValidateAndSummarize(cfg *Config, w io.Writer) error {
+	if cfg == nil {
+		return fmt.Errorf("config is nil")
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	adminKey := "(unset)"
+	if cfg.AdminAPIKey != "" {
+		adminKey = "(redacted)"
+	}
+
+	extraHeaders := make([]string, 0, len(cfg.ExtraResponseHeaders))
+	for _, name := range slices.Sorted(maps.Keys(cfg.ExtraResponseHeaders)) {
+		extraHeaders = append(extraHeaders, name+":"+cfg.ExtraResponseHeaders[name])
+	}
+
+	type setting struct {
+		name  string
+		value any
+	}
+	settings := []setting{
+		{"PORT", cfg.Port},
+		{"ROUTE_PREFIX", cfg.RoutePrefix},
+		{"REQUIRED_HEADER", cfg.RequiredHeader},
+		{"EXTRA_RESPONSE_HEADERS", strings.Join(extraHeaders, ",")},
+		{"TRAILING_SLASH", cfg.TrailingSlash},
+		{"READ_TIMEOUT", cfg.ReadTimeout},
+		{"WRITE_TIMEOUT", cfg.WriteTimeout},
+		{"IDLE_TIMEOUT", cfg.IdleTimeout},
+		{"DISABLE_KEEPALIVE", cfg.DisableKeepAlive},
+		{"REQUEST_TIMEOUT", cfg.RequestTimeout},
+		{"FIZZBUZZ_TIMEOUT", cfg.FizzBuzzTimeout},
+		{"STATISTICS_TIMEOUT", cfg.StatisticsTimeout},
+		{"HEALTH_TIMEOUT", cfg.HealthTimeout},
+		{"SHUTDOWN_TIMEOUT", cfg.ShutdownTimeout},
+		{"STATISTICS_STREAM_INTERVAL", cfg.StreamInterval},
+		{"LOG_LEVEL", cfg.LogLevel},
+		{"LOG_FORMAT", cfg.LogFormat},
+		{"CORS_ALLOWED_ORIGINS", strings.Join(cfg.CORSAllowedOrigins, ",")},
+		{"OPS_CORS_ALLOWED_ORIGINS", strings.Join(cfg.OpsCORSOrigins, ",")},
+		{"MAX_LIMIT", cfg.MaxLimit},
+		{"TRUNCATE_MODE", cfg.TruncateMode},
+		{"IDEMPOTENCY_TTL", cfg.IdempotencyTTL},
+		{"TRUSTED_PROXIES", cfg.TrustedProxies},
+		{"FORCE_UNHEALTHY", cfg.ForceUnhealthy},
+		{"MAINTENANCE_MODE", cfg.MaintenanceMode},
+		{"COMPRESS_RESPONSES", cfg.CompressResponses},
+		{"ADMIN_API_KEY", adminKey},
+		{"RESPONSE_SHAPE", cfg.ResponseShape},
+		{"MAX_DISTINCT_PARAMS", cfg.MaxDistinctParams},
+		{"STATS_DECAY_HALFLIFE", cfg.StatsDecayHalfLife},
+		{"STATS_HOT_PARAMS", cfg.StatsHotParams},
+		{"STATS_PERSIST_PATH", cfg.StatsPersistPath},
+		{"STATS_PERSIST_INTERVAL", cfg.StatsPersistInterval},
+		{"STATS_SAVE_RETRIES", cfg.StatsSaveRetries},
+		{"STATS_CREATE_DIR", cfg.StatsCreateDir},
+		{"STARTUP_SELFTEST", cfg.StartupSelfTest},
+		{"MAX_CONNECTIONS", cfg.MaxConnections},
+		{"MAX_CONCURRENT_GENERATIONS", cfg.MaxConcurrentGenerations},
+		{"HEAVY_GENERATION_LIMIT", cfg.HeavyGenerationLimit},
+		{"MAX_RESPONSE_BYTES", cfg.MaxResponseBytes},
+		{"MAX_BODY_BYTES", cfg.MaxBodyBytes},
+		{"MAX_TOP_N", cfg.MaxTopN},
+		{"MAX_RULES", cfg.MaxRules},
+		{"MAX_DISTINCT_PER_IP", cfg.MaxDistinctPerIP},
+		{"DISTINCT_PER_IP_WINDOW", cfg.DistinctPerIPWindow},
+		{"MIN_DIVISOR", cfg.MinDivisor},
+		{"MAX_DIVISOR", cfg.MaxDivisor},
+		{"ALLOWED_STRINGS", strings.Join(cfg.AllowedStrings, ",")},
+		{"STRICT_INTEGERS", cfg.StrictIntegers},
+		{"ALLOW_SCIENTIFIC_LIMIT", cfg.AllowScientificLimit},
+		{"TOLERANT_NUMBERS", cfg.TolerantNumbers},
+		{"SEMANTIC_ERROR_STATUS", cfg.SemanticErrorStatus},
+		{"USAGE_HINT", cfg.UsageHint},
+		{"LATENCY_BUCKETS", cfg.LatencyBuckets},
+	}
+	for _, param := range defaultableParams {
+		if value, ok := cfg.DefaultParams[param.name]; ok {
+			settings = append(settings, setting{"DEFAULT_" + strings.ToUpper(param.name), value})
+		}
+	}
+	presets, err := json.Marshal(cfg.Presets)
+	if err != nil {
+		return err
+	}
+	settings = append(settings, setting{"FIZZBUZZ_PRESETS", string(presets)})
+	for _, s := range settings {
+		if _, err := fmt.Fprintf(w, "%s=%v\n", s.name, s.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
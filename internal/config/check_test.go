@@ -0,0 +1,71 @@
+package config
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestValidateAndSummarize_Valid(t *testing.T) {
+	clearEnv(t)
+	setEnvVars(t, map[string]string{"PORT": "3000", "ADMIN_API_KEY": "s3cret"})
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := ValidateAndSummarize(cfg, &out); err != nil {
+		t.Fatalf("ValidateAndSummarize() error = %v", err)
+	}
+
+	summary := out.String()
+	for _, want := range []string{"PORT=3000\n", "LOG_LEVEL=info\n", "MAX_LIMIT=0\n", "ADMIN_API_KEY=(redacted)\n"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("summary missing %q:\n%s", want, summary)
+		}
+	}
+	if strings.Contains(summary, "s3cret") {
+		t.Errorf("summary leaks the admin key:\n%s", summary)
+	}
+}
+
+func TestValidateAndSummarize_Invalid(t *testing.T) {
+	clearEnv(t)
+	valid, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(*Config)
+	}{
+		{"empty port", func(c *Config) { c.Port = "" }},
+		{"zero read timeout", func(c *Config) { c.ReadTimeout = 0 }},
+		{"negative idempotency ttl", func(c *Config) { c.IdempotencyTTL = -1 }},
+		{"unknown log level", func(c *Config) { c.LogLevel = "verbose" }},
+		{"unknown log format", func(c *Config) { c.LogFormat = "xml" }},
+		{"unknown response shape", func(c *Config) { c.ResponseShape = "deep" }},
+		{"unknown trailing slash mode", func(c *Config) { c.TrailingSlash = "lenient" }},
+		{"negative max limit", func(c *Config) { c.MaxLimit = -1 }},
+		{"negative max distinct params", func(c *Config) { c.MaxDistinctParams = -1 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := *valid
+			tt.mutate(&cfg)
+
+			if err := ValidateAndSummarize(&cfg, io.Discard); err == nil {
+				t.Fatalf("ValidateAndSummarize() error = nil, want error")
+			}
+		})
+	}
+
+	if err := ValidateAndSummarize(nil, io.Discard); err == nil {
+		t.Fatalf("ValidateAndSummarize(nil) error = nil, want error")
+	}
+}
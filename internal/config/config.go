@@ -1,34 +1,167 @@
 package config
 
 import (
+	"encoding/json"
 	"errors"
-	"fmt"
+	"maps"
+	"math"
+	"net/netip"
+	"net/textproto"
+	"net/url"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/query"
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/statistics"
 )
 
 // Config contains all runtime configuration derived from environment variables.
 // Environment variables:
 // - PORT: HTTP server port (default: 8080)
+// - ROUTE_PREFIX: Path every route is mounted under, e.g. "/api/v1"; a trailing slash is dropped (default: empty)
+// - REQUIRED_HEADER: Header, e.g. "X-Gateway-Auth", that /fizzbuzz and /statistics requests must carry or get 400; empty requires none (default: empty)
+// - EXTRA_RESPONSE_HEADERS: Comma-separated Name:value headers added to every response, e.g. "X-Env:prod,X-Team:platform"; values cannot contain commas (default: empty)
+// - TRAILING_SLASH: How paths with a trailing slash such as /health/ are handled - strict (404), strip (served as /health), redirect (301 to /health) (default: strict)
 // - READ_TIMEOUT: HTTP read timeout, e.g. "15s" (default: 15s)
 // - WRITE_TIMEOUT: HTTP write timeout, e.g. "15s" (default: 15s)
 // - IDLE_TIMEOUT: HTTP idle timeout, e.g. "60s" (default: 60s)
+// - DISABLE_KEEPALIVE: Close every connection after one response instead of keeping it alive, for proxies that mishandle reuse (default: false)
 // - REQUEST_TIMEOUT: Per-request timeout, e.g. "60s" (default: 60s)
+// - FIZZBUZZ_TIMEOUT: Timeout for /fizzbuzz (default: REQUEST_TIMEOUT)
+// - STATISTICS_TIMEOUT: Timeout for /statistics (default: REQUEST_TIMEOUT)
+// - HEALTH_TIMEOUT: Timeout for /health and /ready (default: REQUEST_TIMEOUT)
+// - STATISTICS_STREAM_INTERVAL: How often /statistics/stream pushes the most frequent request, e.g. "5s" (default: 5s); must be shorter than WRITE_TIMEOUT
 // - SHUTDOWN_TIMEOUT: Graceful shutdown timeout, e.g. "30s" (default: 30s)
 // - LOG_LEVEL: Log level - debug, info, warn, error (default: info)
-// - LOG_FORMAT: Log format - json, text (default: json)
-// - CORS_ALLOWED_ORIGINS: Comma-separated CORS origins, e.g. "https://example.com,https://app.example.com" (default: *)
+// - LOG_FORMAT: Log format - json, text, clf (default: json); clf writes access logs in Common Log Format
+// - CORS_ALLOWED_ORIGINS: Comma-separated CORS origins for the data routes, /fizzbuzz and /statistics, e.g. "https://example.com,https://app.example.com" (default: *)
+// - OPS_CORS_ALLOWED_ORIGINS: Comma-separated CORS origins for the ops routes: /health, /ready, /metrics and admin endpoints (default: CORS_ALLOWED_ORIGINS)
+// - MAX_LIMIT: Largest accepted FizzBuzz limit; 0 is unbounded (default: 0)
+// - TRUNCATE_MODE: Cap oversized limits at MAX_LIMIT instead of rejecting them (default: false)
+// - TRUSTED_PROXIES: Comma-separated CIDRs or IPs whose forwarding headers are honored (default: empty, trust all)
+// - FORCE_UNHEALTHY: Start with /health and /ready reporting 503, for chaos testing (default: false)
+// - COMPRESS_RESPONSES: Gzip /fizzbuzz and /statistics responses for clients that accept it, logging each compression ratio at debug (default: false)
+// - MAINTENANCE_MODE: Start in maintenance mode, answering 503 on everything but /health (default: false)
+// - ADMIN_API_KEY: Key required by admin endpoints such as POST /health/toggle; empty disables them (default: empty)
+// - MAX_CONNECTIONS: Cap on requests served at once; excess requests get 503; 0 is unbounded (default: 0)
+// - MAX_CONCURRENT_GENERATIONS: Cap on concurrent heavy FizzBuzz generations; 0 is unbounded (default: 0)
+// - HEAVY_GENERATION_LIMIT: Limit at or above which a request counts against MAX_CONCURRENT_GENERATIONS (default: 10000)
+// - MIN_DIVISOR: Smallest accepted int1/int2 (default: 1)
+// - MAX_DIVISOR: Largest accepted int1/int2; 0 is unbounded (default: 0)
+// - MAX_BODY_BYTES: Largest accepted POST /fizzbuzz JSON body; larger bodies get 413 (default: 65536)
+// - MAX_TOP_N: Largest page /statistics/top returns; larger limits are capped and flagged as truncated (default: 100)
+// - MAX_RULES: Most rule=divisor:word pairs one /fizzbuzz request may give (default: 16)
+// - MAX_RESPONSE_BYTES: Reject FizzBuzz requests whose estimated output, limit * max(len(str1), len(str2)), exceeds this; 0 disables the check (default: 0)
+// - ALLOWED_STRINGS: Comma-separated values str1 and str2 must come from; empty allows any value (default: empty)
+// - ALLOW_SCIENTIFIC_LIMIT: Accept a whole-number limit in scientific notation such as limit=1e6 instead of rejecting it (default: false)
+// - TOLERANT_NUMBERS: Strip thousands separators and underscores from int1, int2, limit and start, so limit=1,000 and limit=1_000 read as 1000 (default: false)
+// - SEMANTIC_ERROR_STATUS: Status for values that parse but are out of range, such as int1=0 - 400 or 422; malformed values stay 400 (default: 400)
+// - USAGE_HINT: Answer a /fizzbuzz request with no parameters with an example request alongside the missing-parameters error (default: false)
+// - STRICT_INTEGERS: Reject integer parameters with leading zeros such as limit=015 instead of reading them as decimal, and warn via X-FizzBuzz-Warning when no value would be replaced (default: false)
+// - DEFAULT_INT1, DEFAULT_INT2, DEFAULT_LIMIT, DEFAULT_STR1, DEFAULT_STR2: Values used for /fizzbuzz parameters the client omits; unset keeps them required (default: empty)
+// - FIZZBUZZ_PRESETS: JSON object of named /fizzbuzz parameter sets selected with ?preset=, e.g. '{"small":{"int1":2,"int2":7,"str1":"foo","str2":"bar"}}'; merged over the built-in "classic" (default: empty)
+// - MAX_DISTINCT_PER_IP: Cap on distinct /fizzbuzz parameter sets one client IP may request per DISTINCT_PER_IP_WINDOW; further new sets get 429; 0 is unbounded (default: 0)
+// - DISTINCT_PER_IP_WINDOW: How often the MAX_DISTINCT_PER_IP counts reset, e.g. "1h" (default: 1h)
+// - MAX_DISTINCT_PARAMS: Cap on distinct parameter sets kept in statistics, evicting the least recently recorded; 0 is unbounded (default: 0)
+// - STATS_PERSIST_PATH: File statistics are restored from at startup and saved to periodically and on shutdown; empty disables persistence (default: empty)
+// - STATS_PERSIST_INTERVAL: How often statistics are saved to STATS_PERSIST_PATH, e.g. "1m" (default: 1m)
+// - STATS_CREATE_DIR: Create the directory of STATS_PERSIST_PATH at startup when it is missing, instead of refusing to start (default: false)
+// - STATS_SAVE_RETRIES: How many times a failed statistics save is retried, with exponential backoff, before the error is logged (default: 3)
+// - STATS_HOT_PARAMS: Experimental. Semicolon-separated /fizzbuzz query strings, e.g. "int1=3&int2=5&limit=100&str1=fizz&str2=buzz", whose statistics are counted with lock-free atomics (default: empty)
+// - STATS_DECAY_HALFLIFE: Half-life after which a request weighs half as much when ranking the most frequent request, e.g. "1h"; 0 ranks by all-time hits (default: 0)
+// - STARTUP_SELFTEST: Verify FizzBuzz generation against a known sequence before serving (default: false)
+// - LATENCY_BUCKETS: Comma-separated, increasing upper bounds in seconds of the http_request_duration_seconds histogram (default: 0.005,0.01,0.025,0.05,0.1,0.25,0.5,1,2.5,5,10)
+// - RESPONSE_SHAPE: Default statistics response shape - nested, flat (default: nested)
+// - IDEMPOTENCY_TTL: How long responses are replayed for a repeated Idempotency-Key, e.g. "5m" (default: 5m)
 type Config struct {
 	Port               string
 	ReadTimeout        time.Duration
 	WriteTimeout       time.Duration
 	IdleTimeout        time.Duration
+	DisableKeepAlive   bool
 	RequestTimeout     time.Duration
+	FizzBuzzTimeout    time.Duration
+	StatisticsTimeout  time.Duration
+	HealthTimeout      time.Duration
 	ShutdownTimeout    time.Duration
+	StreamInterval     time.Duration
 	LogLevel           string
 	LogFormat          string
 	CORSAllowedOrigins []string
+	OpsCORSOrigins     []string
+	MaxLimit           int
+	TruncateMode       bool
+	IdempotencyTTL     time.Duration
+	TrustedProxies     []netip.Prefix
+	ForceUnhealthy     bool
+	AdminAPIKey        string
+	ResponseShape      string
+	MaxDistinctParams  int
+	StatsDecayHalfLife time.Duration
+	StartupSelfTest    bool
+
+	MaxConnections           int
+	MaxConcurrentGenerations int
+	HeavyGenerationLimit     int
+	MaxResponseBytes         int
+	MaxBodyBytes             int
+	MaxTopN                  int
+	MaxRules                 int
+	MaxDistinctPerIP         int
+	DistinctPerIPWindow      time.Duration
+	// DefaultParams maps /fizzbuzz parameter names to the value used when a
+	// request omits them.
+	DefaultParams map[string]string
+	// Presets maps preset names to the /fizzbuzz parameters they supply.
+	Presets map[string]map[string]string
+
+	// CompressResponses gzips data responses for clients that accept it.
+	CompressResponses bool
+	// RoutePrefix mounts every route under a path such as /api/v1.
+	RoutePrefix string
+	// RequiredHeader must be present on data requests when set.
+	RequiredHeader string
+	// ExtraResponseHeaders are set on every response, keyed by canonical
+	// header name.
+	ExtraResponseHeaders map[string]string
+	// TrailingSlash is strict, strip or redirect.
+	TrailingSlash string
+
+	MaintenanceMode bool
+	MinDivisor      int64
+	MaxDivisor      int64
+	StrictIntegers  bool
+	// AllowScientificLimit reads limit=1e6 as 1000000.
+	AllowScientificLimit bool
+	// TolerantNumbers reads limit=1,000 and limit=1_000 as 1000.
+	TolerantNumbers bool
+	// SemanticErrorStatus reports out-of-range values: 400 or 422.
+	SemanticErrorStatus int
+	// UsageHint adds an example request to the error for a bare /fizzbuzz.
+	UsageHint bool
+	// AllowedStrings restricts str1 and str2 when non-empty.
+	AllowedStrings []string
+
+	// StatsHotParams are counted on the lock-free statistics fast path.
+	StatsHotParams []statistics.RequestParams
+
+	StatsPersistPath     string
+	StatsPersistInterval time.Duration
+	StatsSaveRetries     int
+	StatsCreateDir       bool
+
+	// LatencyBuckets are the request duration histogram bounds, in seconds.
+	LatencyBuckets []float64
+}
+
+// defaultableParam is a /fizzbuzz parameter that DEFAULT_<NAME> and presets
+// can supply; integer marks integer parameters.
+type defaultableParam struct {
+	name    string
+	integer bool
 }
 
 var (
@@ -41,6 +174,30 @@ var (
 	allowedLogFormats = map[string]struct{}{
 		"json": {},
 		"text": {},
+		"clf":  {},
+	}
+	allowedResponseShapes = map[string]struct{}{
+		"nested": {},
+		"flat":   {},
+	}
+	allowedTrailingSlashes = map[string]struct{}{
+		"strict":   {},
+		"strip":    {},
+		"redirect": {},
+	}
+	// defaultableParams lists the /fizzbuzz parameters that DEFAULT_<NAME>
+	// can supply.
+	defaultableParams = []defaultableParam{
+		{"int1", true},
+		{"int2", true},
+		{"limit", true},
+		{"str1", false},
+		{"str2", false},
+	}
+	// builtinPresets are always available; FIZZBUZZ_PRESETS can add to or
+	// replace them.
+	builtinPresets = map[string]map[string]string{
+		"classic": {"int1": "3", "int2": "5", "str1": "fizz", "str2": "buzz"},
 	}
 )
 
@@ -51,8 +208,14 @@ func Load() (*Config, error) {
 	var err error
 
 	cfg.Port = getEnv("PORT", "8080")
+	cfg.RoutePrefix = strings.TrimRight(strings.TrimSpace(getEnv("ROUTE_PREFIX", "")), "/")
+	cfg.RequiredHeader = strings.TrimSpace(getEnv("REQUIRED_HEADER", ""))
+	cfg.TrailingSlash = strings.ToLower(strings.TrimSpace(getEnv("TRAILING_SLASH", "strict")))
 	if cfg.Port == "" {
-		return nil, errors.New("port must not be empty")
+		return nil, newError(CategoryServer, "port must not be empty")
+	}
+	if cfg.ExtraResponseHeaders, err = parseHeaders("EXTRA_RESPONSE_HEADERS"); err != nil {
+		return nil, err
 	}
 
 	if cfg.ReadTimeout, err = parseDuration("READ_TIMEOUT", "15s"); err != nil {
@@ -64,50 +227,285 @@ func Load() (*Config, error) {
 	if cfg.IdleTimeout, err = parseDuration("IDLE_TIMEOUT", "60s"); err != nil {
 		return nil, err
 	}
+	if cfg.DisableKeepAlive, err = parseBool("DISABLE_KEEPALIVE", "false"); err != nil {
+		return nil, err
+	}
 	if cfg.RequestTimeout, err = parseDuration("REQUEST_TIMEOUT", "60s"); err != nil {
 		return nil, err
 	}
-	if cfg.ShutdownTimeout, err = parseDuration("SHUTDOWN_TIMEOUT", "30s"); err != nil {
+	if cfg.FizzBuzzTimeout, err = parseDuration("FIZZBUZZ_TIMEOUT", cfg.RequestTimeout.String()); err != nil {
 		return nil, err
 	}
-
-	if err = validatePositiveDuration("READ_TIMEOUT", cfg.ReadTimeout); err != nil {
+	if cfg.StatisticsTimeout, err = parseDuration("STATISTICS_TIMEOUT", cfg.RequestTimeout.String()); err != nil {
 		return nil, err
 	}
-	if err = validatePositiveDuration("WRITE_TIMEOUT", cfg.WriteTimeout); err != nil {
+	if cfg.HealthTimeout, err = parseDuration("HEALTH_TIMEOUT", cfg.RequestTimeout.String()); err != nil {
 		return nil, err
 	}
-	if err = validatePositiveDuration("IDLE_TIMEOUT", cfg.IdleTimeout); err != nil {
+	if cfg.ShutdownTimeout, err = parseDuration("SHUTDOWN_TIMEOUT", "30s"); err != nil {
 		return nil, err
 	}
-	if err = validatePositiveDuration("REQUEST_TIMEOUT", cfg.RequestTimeout); err != nil {
+	if cfg.StreamInterval, err = parseDuration("STATISTICS_STREAM_INTERVAL", "5s"); err != nil {
 		return nil, err
 	}
-	if err = validatePositiveDuration("SHUTDOWN_TIMEOUT", cfg.ShutdownTimeout); err != nil {
+
+	if cfg.IdempotencyTTL, err = parseDuration("IDEMPOTENCY_TTL", "5m"); err != nil {
 		return nil, err
 	}
 
 	cfg.LogLevel = getEnv("LOG_LEVEL", "info")
 	if value, ok := os.LookupEnv("LOG_LEVEL"); ok && strings.TrimSpace(value) == "" {
-		return nil, errors.New("invalid log level: value cannot be empty")
-	}
-	if _, ok := allowedLogLevels[cfg.LogLevel]; !ok {
-		return nil, fmt.Errorf("invalid log level: %s", cfg.LogLevel)
+		return nil, newError(CategoryLogLevel, "invalid log level: value cannot be empty")
 	}
 
 	cfg.LogFormat = getEnv("LOG_FORMAT", "json")
 	if value, ok := os.LookupEnv("LOG_FORMAT"); ok && strings.TrimSpace(value) == "" {
-		return nil, errors.New("invalid log format: value cannot be empty")
-	}
-	if _, ok := allowedLogFormats[cfg.LogFormat]; !ok {
-		return nil, fmt.Errorf("invalid log format: %s", cfg.LogFormat)
+		return nil, newError(CategoryLogFormat, "invalid log format: value cannot be empty")
 	}
 
 	cfg.CORSAllowedOrigins = parseStringSlice("CORS_ALLOWED_ORIGINS", "*")
+	cfg.OpsCORSOrigins = parseStringSlice("OPS_CORS_ALLOWED_ORIGINS", strings.Join(cfg.CORSAllowedOrigins, ","))
+
+	if cfg.TrustedProxies, err = parsePrefixes("TRUSTED_PROXIES"); err != nil {
+		return nil, err
+	}
+
+	if cfg.ForceUnhealthy, err = parseBool("FORCE_UNHEALTHY", "false"); err != nil {
+		return nil, err
+	}
+	if cfg.StartupSelfTest, err = parseBool("STARTUP_SELFTEST", "false"); err != nil {
+		return nil, err
+	}
+	if cfg.MaintenanceMode, err = parseBool("MAINTENANCE_MODE", "false"); err != nil {
+		return nil, err
+	}
+	if cfg.CompressResponses, err = parseBool("COMPRESS_RESPONSES", "false"); err != nil {
+		return nil, err
+	}
+	cfg.AdminAPIKey = strings.TrimSpace(getEnv("ADMIN_API_KEY", ""))
+
+	cfg.ResponseShape = getEnv("RESPONSE_SHAPE", "nested")
+
+	if cfg.MaxLimit, err = parseNonNegativeInt("MAX_LIMIT", "0"); err != nil {
+		return nil, err
+	}
+	if cfg.TruncateMode, err = parseBool("TRUNCATE_MODE", "false"); err != nil {
+		return nil, err
+	}
+	if cfg.MaxDistinctParams, err = parseNonNegativeInt("MAX_DISTINCT_PARAMS", "0"); err != nil {
+		return nil, err
+	}
+	if cfg.StatsDecayHalfLife, err = parseDuration("STATS_DECAY_HALFLIFE", "0s"); err != nil {
+		return nil, err
+	}
+	if cfg.StatsHotParams, err = parseHotParams("STATS_HOT_PARAMS"); err != nil {
+		return nil, err
+	}
+	cfg.StatsPersistPath = strings.TrimSpace(getEnv("STATS_PERSIST_PATH", ""))
+	if cfg.StatsPersistInterval, err = parseDuration("STATS_PERSIST_INTERVAL", "1m"); err != nil {
+		return nil, err
+	}
+	if cfg.StatsSaveRetries, err = parseNonNegativeInt("STATS_SAVE_RETRIES", "3"); err != nil {
+		return nil, err
+	}
+	if cfg.StatsCreateDir, err = parseBool("STATS_CREATE_DIR", "false"); err != nil {
+		return nil, err
+	}
+	if cfg.MaxConnections, err = parseNonNegativeInt("MAX_CONNECTIONS", "0"); err != nil {
+		return nil, err
+	}
+	if cfg.MaxConcurrentGenerations, err = parseNonNegativeInt("MAX_CONCURRENT_GENERATIONS", "0"); err != nil {
+		return nil, err
+	}
+	if cfg.HeavyGenerationLimit, err = parsePositiveInt("HEAVY_GENERATION_LIMIT", "10000"); err != nil {
+		return nil, err
+	}
+	if cfg.MaxResponseBytes, err = parseNonNegativeInt("MAX_RESPONSE_BYTES", "0"); err != nil {
+		return nil, err
+	}
+	if cfg.MaxBodyBytes, err = parsePositiveInt("MAX_BODY_BYTES", "65536"); err != nil {
+		return nil, err
+	}
+	if cfg.MaxTopN, err = parsePositiveInt("MAX_TOP_N", "100"); err != nil {
+		return nil, err
+	}
+	if cfg.MaxRules, err = parsePositiveInt("MAX_RULES", "16"); err != nil {
+		return nil, err
+	}
+	if cfg.MaxDistinctPerIP, err = parseNonNegativeInt("MAX_DISTINCT_PER_IP", "0"); err != nil {
+		return nil, err
+	}
+	if cfg.DistinctPerIPWindow, err = parseDuration("DISTINCT_PER_IP_WINDOW", "1h"); err != nil {
+		return nil, err
+	}
+	cfg.DefaultParams = parseDefaultParams()
+	if cfg.Presets, err = parsePresets("FIZZBUZZ_PRESETS"); err != nil {
+		return nil, err
+	}
+	if cfg.MinDivisor, err = parseInt64("MIN_DIVISOR", "1"); err != nil {
+		return nil, err
+	}
+	if cfg.MaxDivisor, err = parseInt64("MAX_DIVISOR", "0"); err != nil {
+		return nil, err
+	}
+	cfg.AllowedStrings = parseOptionalList("ALLOWED_STRINGS")
+	if cfg.LatencyBuckets, err = parseBuckets("LATENCY_BUCKETS", "0.005,0.01,0.025,0.05,0.1,0.25,0.5,1,2.5,5,10"); err != nil {
+		return nil, err
+	}
+	if cfg.StrictIntegers, err = parseBool("STRICT_INTEGERS", "false"); err != nil {
+		return nil, err
+	}
+	if cfg.AllowScientificLimit, err = parseBool("ALLOW_SCIENTIFIC_LIMIT", "false"); err != nil {
+		return nil, err
+	}
+	if cfg.TolerantNumbers, err = parseBool("TOLERANT_NUMBERS", "false"); err != nil {
+		return nil, err
+	}
+	if cfg.SemanticErrorStatus, err = parsePositiveInt("SEMANTIC_ERROR_STATUS", "400"); err != nil {
+		return nil, err
+	}
+	if cfg.UsageHint, err = parseBool("USAGE_HINT", "false"); err != nil {
+		return nil, err
+	}
+
+	if err = cfg.Validate(); err != nil {
+		return nil, err
+	}
 
 	return cfg, nil
 }
 
+// Validate checks the invariants Load enforces on parsed values, so a Config
+// built or modified in code can be checked the same way.
+func (c *Config) Validate() error {
+	if c.Port == "" {
+		return newError(CategoryServer, "port must not be empty")
+	}
+	if c.RoutePrefix != "" && (!strings.HasPrefix(c.RoutePrefix, "/") || strings.ContainsAny(c.RoutePrefix, "?#*{} ")) {
+		return newError(CategoryServer, "route_prefix must be a plain path starting with /, got %q", c.RoutePrefix)
+	}
+	if strings.ContainsAny(c.RequiredHeader, " \t:\r\n") {
+		return newError(CategoryServer, "required_header must be a header name, got %q", c.RequiredHeader)
+	}
+
+	durations := []struct {
+		name string
+		d    time.Duration
+	}{
+		{"READ_TIMEOUT", c.ReadTimeout},
+		{"WRITE_TIMEOUT", c.WriteTimeout},
+		{"IDLE_TIMEOUT", c.IdleTimeout},
+		{"REQUEST_TIMEOUT", c.RequestTimeout},
+		{"FIZZBUZZ_TIMEOUT", c.FizzBuzzTimeout},
+		{"STATISTICS_TIMEOUT", c.StatisticsTimeout},
+		{"HEALTH_TIMEOUT", c.HealthTimeout},
+		{"SHUTDOWN_TIMEOUT", c.ShutdownTimeout},
+		{"STATISTICS_STREAM_INTERVAL", c.StreamInterval},
+		{"IDEMPOTENCY_TTL", c.IdempotencyTTL},
+		{"STATS_PERSIST_INTERVAL", c.StatsPersistInterval},
+		{"DISTINCT_PER_IP_WINDOW", c.DistinctPerIPWindow},
+	}
+	for _, d := range durations {
+		if err := validatePositiveDuration(d.name, d.d); err != nil {
+			return err
+		}
+	}
+	// Each /statistics/stream event extends the write deadline by
+	// WRITE_TIMEOUT, so the next event must be due before it passes.
+	if c.StreamInterval >= c.WriteTimeout {
+		return newError(CategoryDuration, "statistics_stream_interval (%s) must be shorter than write_timeout (%s)", c.StreamInterval, c.WriteTimeout)
+	}
+
+	if _, ok := allowedLogLevels[c.LogLevel]; !ok {
+		return newError(CategoryLogLevel, "invalid log level: %s", c.LogLevel)
+	}
+	if _, ok := allowedLogFormats[c.LogFormat]; !ok {
+		return newError(CategoryLogFormat, "invalid log format: %s", c.LogFormat)
+	}
+	if _, ok := allowedResponseShapes[c.ResponseShape]; !ok {
+		return newError(CategoryChoice, "invalid response shape: %s", c.ResponseShape)
+	}
+	if _, ok := allowedTrailingSlashes[c.TrailingSlash]; !ok {
+		return newError(CategoryChoice, "invalid trailing slash mode: %s", c.TrailingSlash)
+	}
+
+	if c.MaxLimit < 0 {
+		return newError(CategoryInteger, "max_limit must not be negative")
+	}
+	if c.HeavyGenerationLimit <= 0 {
+		return newError(CategoryInteger, "heavy_generation_limit must be greater than zero")
+	}
+	if c.MaxDistinctParams < 0 {
+		return newError(CategoryInteger, "max_distinct_params must not be negative")
+	}
+	if c.StatsDecayHalfLife < 0 {
+		return newError(CategoryDuration, "stats_decay_halflife must not be negative")
+	}
+	if c.StatsSaveRetries < 0 {
+		return newError(CategoryInteger, "stats_save_retries must not be negative")
+	}
+	if c.MaxConnections < 0 {
+		return newError(CategoryInteger, "max_connections must not be negative")
+	}
+	if c.MaxConcurrentGenerations < 0 {
+		return newError(CategoryInteger, "max_concurrent_generations must not be negative")
+	}
+	if c.MaxResponseBytes < 0 {
+		return newError(CategoryInteger, "max_response_bytes must not be negative")
+	}
+	if c.MaxBodyBytes <= 0 {
+		return newError(CategoryInteger, "max_body_bytes must be greater than zero")
+	}
+	if c.MaxTopN <= 0 {
+		return newError(CategoryInteger, "max_top_n must be greater than zero")
+	}
+	if c.MaxRules <= 0 {
+		return newError(CategoryInteger, "max_rules must be greater than zero")
+	}
+	if c.SemanticErrorStatus != 400 && c.SemanticErrorStatus != 422 {
+		return newError(CategoryInteger, "semantic_error_status must be 400 or 422, got %d", c.SemanticErrorStatus)
+	}
+	if c.MaxDistinctPerIP < 0 {
+		return newError(CategoryInteger, "max_distinct_per_ip must not be negative")
+	}
+	if c.MinDivisor <= 0 {
+		return newError(CategoryInteger, "min_divisor must be greater than zero")
+	}
+	if c.MaxDivisor < 0 {
+		return newError(CategoryInteger, "max_divisor must not be negative")
+	}
+	if c.MaxDivisor > 0 && c.MaxDivisor < c.MinDivisor {
+		return newError(CategoryInteger, "max_divisor %d must not be below min_divisor %d", c.MaxDivisor, c.MinDivisor)
+	}
+	for _, param := range defaultableParams {
+		value, ok := c.DefaultParams[param.name]
+		if !ok || !param.integer {
+			continue
+		}
+		if n, err := strconv.ParseInt(value, 10, 64); err != nil || n <= 0 {
+			return newError(CategoryInteger, "default_%s must be a positive integer", param.name)
+		}
+	}
+	if len(c.LatencyBuckets) == 0 {
+		return newError(CategoryList, "latency_buckets must list at least one bound")
+	}
+	for i, bound := range c.LatencyBuckets {
+		if bound <= 0 || math.IsInf(bound, 0) || math.IsNaN(bound) {
+			return newError(CategoryList, "latency_buckets bound %v must be a positive number", bound)
+		}
+		if i > 0 && bound <= c.LatencyBuckets[i-1] {
+			return newError(CategoryList, "latency_buckets must be strictly increasing, got %v after %v", bound, c.LatencyBuckets[i-1])
+		}
+	}
+	for name, preset := range c.Presets {
+		if err := validatePreset(name, preset); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func getEnv(key, defaultValue string) string {
 	if value, ok := os.LookupEnv(key); ok {
 		if strings.TrimSpace(value) != "" {
@@ -121,11 +519,53 @@ func parseDuration(key, defaultValue string) (time.Duration, error) {
 	value := getEnv(key, defaultValue)
 	d, err := time.ParseDuration(value)
 	if err != nil {
-		return 0, fmt.Errorf("invalid duration for %s: %w", key, err)
+		return 0, newError(CategoryDuration, "invalid duration for %s: %w", key, err)
 	}
 	return d, nil
 }
 
+func parsePositiveInt(key, defaultValue string) (int, error) {
+	value := getEnv(key, defaultValue)
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, newError(CategoryInteger, "invalid integer for %s: %w", key, err)
+	}
+	if n <= 0 {
+		return 0, newError(CategoryInteger, "%s must be greater than zero", strings.ToLower(key))
+	}
+	return n, nil
+}
+
+func parseNonNegativeInt(key, defaultValue string) (int, error) {
+	value := getEnv(key, defaultValue)
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, newError(CategoryInteger, "invalid integer for %s: %w", key, err)
+	}
+	if n < 0 {
+		return 0, newError(CategoryInteger, "%s must not be negative", strings.ToLower(key))
+	}
+	return n, nil
+}
+
+func parseInt64(key, defaultValue string) (int64, error) {
+	value := getEnv(key, defaultValue)
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, newError(CategoryInteger, "invalid integer for %s: %w", key, err)
+	}
+	return n, nil
+}
+
+func parseBool(key, defaultValue string) (bool, error) {
+	value := getEnv(key, defaultValue)
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, newError(CategoryBoolean, "invalid boolean for %s: %w", key, err)
+	}
+	return b, nil
+}
+
 func parseStringSlice(key, defaultValue string) []string {
 	value := getEnv(key, defaultValue)
 	parts := strings.Split(value, ",")
@@ -142,9 +582,193 @@ func parseStringSlice(key, defaultValue string) []string {
 	return result
 }
 
+// parseOptionalList splits a comma-separated value, returning nil when the
+// variable is unset or lists nothing.
+func parseOptionalList(key string) []string {
+	var values []string
+	for _, part := range strings.Split(getEnv(key, ""), ",") {
+		if trimmed := strings.TrimSpace(part); trimmed != "" {
+			values = append(values, trimmed)
+		}
+	}
+	return values
+}
+
+func parseDefaultParams() map[string]string {
+	var defaults map[string]string
+	for _, param := range defaultableParams {
+		value := getEnv("DEFAULT_"+strings.ToUpper(param.name), "")
+		if value == "" {
+			continue
+		}
+		if defaults == nil {
+			defaults = make(map[string]string)
+		}
+		defaults[param.name] = value
+	}
+	return defaults
+}
+
+// parseBuckets reads comma-separated histogram bounds. Their order and range
+// are checked by Validate.
+func parseBuckets(key, defaultValue string) ([]float64, error) {
+	var buckets []float64
+	for _, part := range strings.Split(getEnv(key, defaultValue), ",") {
+		trimmed := strings.TrimSpace(part)
+		if trimmed == "" {
+			continue
+		}
+		bound, err := strconv.ParseFloat(trimmed, 64)
+		if err != nil {
+			return nil, newError(CategoryList, "invalid bucket for %s: %q", key, trimmed)
+		}
+		buckets = append(buckets, bound)
+	}
+	return buckets, nil
+}
+
+// parsePresets reads a JSON object mapping preset names to parameter
+// objects whose values are strings or numbers, and merges it over the
+// built-in presets.
+func parsePresets(key string) (map[string]map[string]string, error) {
+	presets := make(map[string]map[string]string, len(builtinPresets))
+	for name, preset := range builtinPresets {
+		presets[name] = maps.Clone(preset)
+	}
+
+	value := getEnv(key, "")
+	if value == "" {
+		return presets, nil
+	}
+
+	var raw map[string]map[string]any
+	decoder := json.NewDecoder(strings.NewReader(value))
+	decoder.UseNumber()
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, newError(CategoryList, "invalid JSON for %s: %w", key, err)
+	}
+	for name, fields := range raw {
+		preset := make(map[string]string, len(fields))
+		for field, v := range fields {
+			switch v := v.(type) {
+			case string:
+				preset[field] = v
+			case json.Number:
+				preset[field] = v.String()
+			default:
+				return nil, newError(CategoryList, "preset %q field %s must be a string or a number", name, field)
+			}
+		}
+		presets[name] = preset
+	}
+	return presets, nil
+}
+
+// validatePreset checks that a preset only sets defaultable parameters and
+// that its integers are positive.
+func validatePreset(name string, preset map[string]string) error {
+	if name == "" {
+		return newError(CategoryList, "preset names must not be empty")
+	}
+	for field, value := range preset {
+		i := slices.IndexFunc(defaultableParams, func(p defaultableParam) bool { return p.name == field })
+		if i < 0 {
+			return newError(CategoryList, "preset %q sets unsupported parameter %s", name, field)
+		}
+		if !defaultableParams[i].integer {
+			continue
+		}
+		if n, err := strconv.ParseInt(value, 10, 64); err != nil || n <= 0 {
+			return newError(CategoryInteger, "preset %q %s must be a positive integer", name, field)
+		}
+	}
+	return nil
+}
+
+// parseHeaders reads comma-separated Name:value pairs, returning nil when the
+// variable is unset. Names are canonicalized and may appear only once.
+func parseHeaders(key string) (map[string]string, error) {
+	var headers map[string]string
+	for _, part := range strings.Split(getEnv(key, ""), ",") {
+		trimmed := strings.TrimSpace(part)
+		if trimmed == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(trimmed, ":")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || name == "" || strings.ContainsAny(name, " \t\r\n") {
+			return nil, newError(CategoryList, "%s entry %q must be Name:value", strings.ToLower(key), trimmed)
+		}
+		name = textproto.CanonicalMIMEHeaderKey(name)
+		if _, dup := headers[name]; dup {
+			return nil, newError(CategoryList, "%s sets %s more than once", strings.ToLower(key), name)
+		}
+		if headers == nil {
+			headers = make(map[string]string)
+		}
+		headers[name] = value
+	}
+	return headers, nil
+}
+
+// parseHotParams reads semicolon-separated query strings, each naming a full
+// /fizzbuzz parameter set.
+func parseHotParams(key string) ([]statistics.RequestParams, error) {
+	value := getEnv(key, "")
+	if value == "" {
+		return nil, nil
+	}
+
+	var params []statistics.RequestParams
+	for _, part := range strings.Split(value, ";") {
+		trimmed := strings.TrimSpace(part)
+		if trimmed == "" {
+			continue
+		}
+		values, err := url.ParseQuery(trimmed)
+		if err != nil {
+			return nil, newError(CategoryList, "invalid query for %s: %q", key, trimmed)
+		}
+		int1, err1 := query.ParseInt(values.Get("int1"), 64)
+		int2, err2 := query.ParseInt(values.Get("int2"), 64)
+		limit, err3 := query.Atoi(values.Get("limit"))
+		p := statistics.RequestParams{Int1: int1, Int2: int2, Limit: limit, Str1: values.Get("str1"), Str2: values.Get("str2")}
+		if errors.Join(err1, err2, err3) != nil || p.Int1 <= 0 || p.Int2 <= 0 || p.Limit <= 0 || p.Str1 == "" || p.Str2 == "" {
+			return nil, newError(CategoryList, "%s entry %q must set positive int1, int2 and limit and non-empty str1 and str2", strings.ToLower(key), trimmed)
+		}
+		params = append(params, p)
+	}
+	return params, nil
+}
+
+func parsePrefixes(key string) ([]netip.Prefix, error) {
+	value := getEnv(key, "")
+	if value == "" {
+		return nil, nil
+	}
+
+	var prefixes []netip.Prefix
+	for _, part := range strings.Split(value, ",") {
+		trimmed := strings.TrimSpace(part)
+		if trimmed == "" {
+			continue
+		}
+		if prefix, err := netip.ParsePrefix(trimmed); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(trimmed)
+		if err != nil {
+			return nil, newError(CategoryList, "invalid CIDR or IP for %s: %q", key, trimmed)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
 func validatePositiveDuration(name string, d time.Duration) error {
 	if d <= 0 {
-		return fmt.Errorf("%s must be greater than zero", strings.ToLower(name))
+		return newError(CategoryDuration, "%s must be greater than zero", strings.ToLower(name))
 	}
 	return nil
 }
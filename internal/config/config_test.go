@@ -1,9 +1,13 @@
 package config
 
 import (
+	"net/netip"
 	"os"
+	"reflect"
 	"testing"
 	"time"
+
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/statistics"
 )
 
 func TestLoad_Defaults(t *testing.T) {
@@ -20,10 +24,33 @@ func TestLoad_Defaults(t *testing.T) {
 		WriteTimeout:       15 * time.Second,
 		IdleTimeout:        60 * time.Second,
 		RequestTimeout:     60 * time.Second,
+		FizzBuzzTimeout:    60 * time.Second,
+		StatisticsTimeout:  60 * time.Second,
+		HealthTimeout:      60 * time.Second,
 		ShutdownTimeout:    30 * time.Second,
+		StreamInterval:     5 * time.Second,
 		LogLevel:           "info",
 		LogFormat:          "json",
 		CORSAllowedOrigins: []string{"*"},
+		OpsCORSOrigins:     []string{"*"},
+		MaxLimit:           0,
+		TruncateMode:       false,
+		IdempotencyTTL:     5 * time.Minute,
+		ResponseShape:      "nested",
+		TrailingSlash:      "strict",
+
+		HeavyGenerationLimit: 10000,
+		MaxBodyBytes:         65536,
+		MaxTopN:              100,
+		MaxRules:             16,
+		MinDivisor:           1,
+		DistinctPerIPWindow:  time.Hour,
+		Presets:              map[string]map[string]string{"classic": {"int1": "3", "int2": "5", "str1": "fizz", "str2": "buzz"}},
+
+		StatsPersistInterval: time.Minute,
+		StatsSaveRetries:     3,
+		SemanticErrorStatus:  400,
+		LatencyBuckets:       []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
 	}
 
 	assertConfig(t, cfg, expected)
@@ -38,26 +65,127 @@ func TestLoad_CustomValues(t *testing.T) {
 		{
 			name: "all custom",
 			vars: map[string]string{
-				"PORT":                 "3000",
-				"READ_TIMEOUT":         "5s",
-				"WRITE_TIMEOUT":        "10s",
-				"IDLE_TIMEOUT":         "2m",
-				"REQUEST_TIMEOUT":      "90s",
-				"SHUTDOWN_TIMEOUT":     "45s",
-				"LOG_LEVEL":            "debug",
-				"LOG_FORMAT":           "text",
-				"CORS_ALLOWED_ORIGINS": "https://example.com,https://app.example.com",
+				"PORT":                       "3000",
+				"ROUTE_PREFIX":               "/api/v1/",
+				"REQUIRED_HEADER":            "X-Gateway-Auth",
+				"EXTRA_RESPONSE_HEADERS":     "x-env: prod, X-Team:platform,",
+				"TRAILING_SLASH":             " Redirect ",
+				"READ_TIMEOUT":               "5s",
+				"WRITE_TIMEOUT":              "10s",
+				"IDLE_TIMEOUT":               "2m",
+				"DISABLE_KEEPALIVE":          "true",
+				"REQUEST_TIMEOUT":            "90s",
+				"FIZZBUZZ_TIMEOUT":           "5m",
+				"STATISTICS_TIMEOUT":         "20s",
+				"HEALTH_TIMEOUT":             "2s",
+				"SHUTDOWN_TIMEOUT":           "45s",
+				"STATISTICS_STREAM_INTERVAL": "2s",
+				"LOG_LEVEL":                  "debug",
+				"LOG_FORMAT":                 "text",
+				"CORS_ALLOWED_ORIGINS":       "https://example.com,https://app.example.com",
+				"OPS_CORS_ALLOWED_ORIGINS":   "https://ops.example.com",
+				"MAX_LIMIT":                  "500",
+				"TRUNCATE_MODE":              "true",
+				"IDEMPOTENCY_TTL":            "30s",
+				"FORCE_UNHEALTHY":            "true",
+				"ADMIN_API_KEY":              "s3cret",
+				"RESPONSE_SHAPE":             "flat",
+				"MAX_DISTINCT_PARAMS":        "1000",
+				"STATS_DECAY_HALFLIFE":       "1h",
+				"ALLOWED_STRINGS":            "fizz, buzz,,",
+				"STRICT_INTEGERS":            "true",
+				"ALLOW_SCIENTIFIC_LIMIT":     "true",
+				"TOLERANT_NUMBERS":           "true",
+				"SEMANTIC_ERROR_STATUS":      "422",
+				"USAGE_HINT":                 "true",
+				"STATS_PERSIST_PATH":         "/var/lib/fizzbuzz/stats.json",
+				"STATS_PERSIST_INTERVAL":     "30s",
+				"STATS_SAVE_RETRIES":         "5",
+				"STATS_CREATE_DIR":           "true",
+				"LATENCY_BUCKETS":            "0.1, 0.5,1,,",
+				"STATS_HOT_PARAMS":           "int1=3&int2=5&limit=100&str1=fizz&str2=buzz; int1=2&int2=7&limit=15&str1=a%3Bb&str2=c",
+				"MAX_CONNECTIONS":            "200",
+				"MAX_CONCURRENT_GENERATIONS": "4",
+				"HEAVY_GENERATION_LIMIT":     "5000",
+				"MAX_RESPONSE_BYTES":         "1048576",
+				"MAX_BODY_BYTES":             "4096",
+				"MAX_TOP_N":                  "25",
+				"MAX_RULES":                  "4",
+				"MAX_DISTINCT_PER_IP":        "50",
+				"DISTINCT_PER_IP_WINDOW":     "10m",
+				"DEFAULT_INT1":               "3",
+				"DEFAULT_STR1":               "fizz",
+				"FIZZBUZZ_PRESETS":           `{"small": {"int1": 2, "int2": "7", "str1": "foo", "str2": "bar"}, "classic": {"int1": 3, "int2": 5, "str1": "Fizz", "str2": "Buzz"}}`,
+				"MAINTENANCE_MODE":           "true",
+				"COMPRESS_RESPONSES":         "true",
+				"MIN_DIVISOR":                "2",
+				"MAX_DIVISOR":                "1000",
+				"STARTUP_SELFTEST":           "true",
 			},
 			expected: &Config{
 				Port:               "3000",
 				ReadTimeout:        5 * time.Second,
 				WriteTimeout:       10 * time.Second,
 				IdleTimeout:        2 * time.Minute,
+				DisableKeepAlive:   true,
 				RequestTimeout:     90 * time.Second,
+				FizzBuzzTimeout:    5 * time.Minute,
+				StatisticsTimeout:  20 * time.Second,
+				HealthTimeout:      2 * time.Second,
 				ShutdownTimeout:    45 * time.Second,
+				StreamInterval:     2 * time.Second,
 				LogLevel:           "debug",
 				LogFormat:          "text",
 				CORSAllowedOrigins: []string{"https://example.com", "https://app.example.com"},
+				OpsCORSOrigins:     []string{"https://ops.example.com"},
+				MaxLimit:           500,
+				TruncateMode:       true,
+				IdempotencyTTL:     30 * time.Second,
+				ForceUnhealthy:     true,
+				AdminAPIKey:        "s3cret",
+				ResponseShape:      "flat",
+				MaxDistinctParams:  1000,
+				StatsDecayHalfLife: time.Hour,
+				StartupSelfTest:    true,
+
+				MaxConnections:           200,
+				MaxConcurrentGenerations: 4,
+				HeavyGenerationLimit:     5000,
+				MaxResponseBytes:         1048576,
+				MaxBodyBytes:             4096,
+				MaxTopN:                  25,
+				MaxRules:                 4,
+				MaxDistinctPerIP:         50,
+				DistinctPerIPWindow:      10 * time.Minute,
+				DefaultParams:            map[string]string{"int1": "3", "str1": "fizz"},
+				Presets: map[string]map[string]string{
+					"classic": {"int1": "3", "int2": "5", "str1": "Fizz", "str2": "Buzz"},
+					"small":   {"int1": "2", "int2": "7", "str1": "foo", "str2": "bar"},
+				},
+				MaintenanceMode: true,
+				MinDivisor:      2,
+				MaxDivisor:      1000,
+				AllowedStrings:  []string{"fizz", "buzz"},
+				StrictIntegers:  true,
+
+				AllowScientificLimit: true,
+				TolerantNumbers:      true,
+				CompressResponses:    true,
+				RoutePrefix:          "/api/v1",
+				RequiredHeader:       "X-Gateway-Auth",
+				ExtraResponseHeaders: map[string]string{"X-Env": "prod", "X-Team": "platform"},
+				TrailingSlash:        "redirect",
+				SemanticErrorStatus:  422,
+				UsageHint:            true,
+				StatsHotParams: []statistics.RequestParams{
+					{Int1: 3, Int2: 5, Limit: 100, Str1: "fizz", Str2: "buzz"},
+					{Int1: 2, Int2: 7, Limit: 15, Str1: "a;b", Str2: "c"},
+				},
+				StatsPersistPath:     "/var/lib/fizzbuzz/stats.json",
+				StatsPersistInterval: 30 * time.Second,
+				StatsSaveRetries:     5,
+				StatsCreateDir:       true,
+				LatencyBuckets:       []float64{0.1, 0.5, 1},
 			},
 		},
 		{
@@ -75,10 +203,32 @@ func TestLoad_CustomValues(t *testing.T) {
 				WriteTimeout:       15 * time.Second,
 				IdleTimeout:        60 * time.Second,
 				RequestTimeout:     120 * time.Second,
+				FizzBuzzTimeout:    120 * time.Second,
+				StatisticsTimeout:  120 * time.Second,
+				HealthTimeout:      120 * time.Second,
 				ShutdownTimeout:    30 * time.Second,
+				StreamInterval:     5 * time.Second,
 				LogLevel:           "warn",
 				LogFormat:          "json",
 				CORSAllowedOrigins: []string{"https://example.com"},
+				OpsCORSOrigins:     []string{"https://example.com"},
+				MaxLimit:           0,
+				IdempotencyTTL:     5 * time.Minute,
+				ResponseShape:      "nested",
+				TrailingSlash:      "strict",
+
+				HeavyGenerationLimit: 10000,
+				MaxBodyBytes:         65536,
+				MaxTopN:              100,
+				MaxRules:             16,
+				MinDivisor:           1,
+				DistinctPerIPWindow:  time.Hour,
+				Presets:              map[string]map[string]string{"classic": {"int1": "3", "int2": "5", "str1": "fizz", "str2": "buzz"}},
+
+				StatsPersistInterval: time.Minute,
+				StatsSaveRetries:     3,
+				SemanticErrorStatus:  400,
+				LatencyBuckets:       []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
 			},
 		},
 	}
@@ -107,8 +257,14 @@ func TestLoad_InvalidDuration(t *testing.T) {
 		{"read timeout", "READ_TIMEOUT", "invalid"},
 		{"write timeout", "WRITE_TIMEOUT", "5x"},
 		{"idle timeout", "IDLE_TIMEOUT", "abc"},
+		{"disable keep-alive not a bool", "DISABLE_KEEPALIVE", "maybe"},
 		{"request timeout", "REQUEST_TIMEOUT", "ten"},
 		{"shutdown timeout", "SHUTDOWN_TIMEOUT", "not-a-duration"},
+		{"fizzbuzz timeout", "FIZZBUZZ_TIMEOUT", "slow"},
+		{"statistics timeout", "STATISTICS_TIMEOUT", "1z"},
+		{"health timeout", "HEALTH_TIMEOUT", "fast"},
+		{"idempotency ttl", "IDEMPOTENCY_TTL", "soon"},
+		{"statistics stream interval", "STATISTICS_STREAM_INTERVAL", "often"},
 	}
 
 	for _, tt := range tests {
@@ -151,6 +307,39 @@ func TestLoad_InvalidLogFormat(t *testing.T) {
 	}
 }
 
+func TestLoad_MaxDivisorBelowMinDivisor(t *testing.T) {
+	clearEnv(t)
+	setEnvVars(t, map[string]string{"MIN_DIVISOR": "10", "MAX_DIVISOR": "5"})
+
+	if _, err := Load(); err == nil {
+		t.Fatalf("Load() error = nil, want error")
+	}
+}
+
+func TestLoad_StreamIntervalNotBelowWriteTimeout(t *testing.T) {
+	for _, interval := range []string{"15s", "20s"} {
+		clearEnv(t)
+		setEnvVars(t, map[string]string{"WRITE_TIMEOUT": "15s", "STATISTICS_STREAM_INTERVAL": interval})
+
+		if _, err := Load(); err == nil {
+			t.Fatalf("interval %s: Load() error = nil, want error", interval)
+		}
+	}
+}
+
+func TestLoad_CLFLogFormat(t *testing.T) {
+	clearEnv(t)
+	setEnvVars(t, map[string]string{"LOG_FORMAT": "clf"})
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.LogFormat != "clf" {
+		t.Fatalf("LogFormat = %s, want clf", cfg.LogFormat)
+	}
+}
+
 func TestLoad_ZeroTimeout(t *testing.T) {
 	tests := []struct {
 		name string
@@ -161,6 +350,89 @@ func TestLoad_ZeroTimeout(t *testing.T) {
 		{"write timeout negative", "WRITE_TIMEOUT", "-5s"},
 		{"idle timeout zero", "IDLE_TIMEOUT", "0ms"},
 		{"request timeout zero", "REQUEST_TIMEOUT", "0s"},
+		{"health timeout zero", "HEALTH_TIMEOUT", "0s"},
+		{"idempotency ttl zero", "IDEMPOTENCY_TTL", "0s"},
+		{"statistics stream interval zero", "STATISTICS_STREAM_INTERVAL", "0s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			setEnvVars(t, map[string]string{tt.key: tt.val})
+
+			if _, err := Load(); err == nil {
+				t.Fatalf("Load() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestLoad_InvalidLimitSettings(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		val  string
+	}{
+		{"max limit not a number", "MAX_LIMIT", "lots"},
+		{"max limit negative", "MAX_LIMIT", "-10"},
+		{"truncate mode not a bool", "TRUNCATE_MODE", "sometimes"},
+		{"force unhealthy not a bool", "FORCE_UNHEALTHY", "maybe"},
+		{"strict integers not a bool", "STRICT_INTEGERS", "maybe"},
+		{"allow scientific limit not a bool", "ALLOW_SCIENTIFIC_LIMIT", "maybe"},
+		{"tolerant numbers not a bool", "TOLERANT_NUMBERS", "maybe"},
+		{"compress responses not a bool", "COMPRESS_RESPONSES", "maybe"},
+		{"route prefix without leading slash", "ROUTE_PREFIX", "api/v1"},
+		{"semantic error status unsupported", "SEMANTIC_ERROR_STATUS", "418"},
+		{"semantic error status not a number", "SEMANTIC_ERROR_STATUS", "unprocessable"},
+		{"usage hint not a bool", "USAGE_HINT", "maybe"},
+		{"route prefix with a pattern", "ROUTE_PREFIX", "/api/{version}"},
+		{"required header with a colon", "REQUIRED_HEADER", "X-Gateway-Auth: yes"},
+		{"extra response header without a value", "EXTRA_RESPONSE_HEADERS", "X-Env:prod,X-Team"},
+		{"extra response header without a name", "EXTRA_RESPONSE_HEADERS", ":prod"},
+		{"extra response header name with a space", "EXTRA_RESPONSE_HEADERS", "X Env:prod"},
+		{"extra response header set twice", "EXTRA_RESPONSE_HEADERS", "X-Env:prod,x-env:dev"},
+		{"startup self-test not a bool", "STARTUP_SELFTEST", "on"},
+		{"unknown response shape", "RESPONSE_SHAPE", "camel"},
+		{"unknown trailing slash mode", "TRAILING_SLASH", "lenient"},
+		{"max distinct params negative", "MAX_DISTINCT_PARAMS", "-1"},
+		{"max distinct params not a number", "MAX_DISTINCT_PARAMS", "many"},
+		{"stats decay halflife negative", "STATS_DECAY_HALFLIFE", "-1m"},
+		{"stats decay halflife not a duration", "STATS_DECAY_HALFLIFE", "weekly"},
+		{"stats hot params missing str2", "STATS_HOT_PARAMS", "int1=3&int2=5&limit=100&str1=fizz"},
+		{"stats hot params zero limit", "STATS_HOT_PARAMS", "int1=3&int2=5&limit=0&str1=fizz&str2=buzz"},
+		{"stats hot params bad query", "STATS_HOT_PARAMS", "int1=%zz"},
+		{"stats persist interval zero", "STATS_PERSIST_INTERVAL", "0s"},
+		{"stats persist interval invalid", "STATS_PERSIST_INTERVAL", "often"},
+		{"stats save retries negative", "STATS_SAVE_RETRIES", "-1"},
+		{"stats save retries not a number", "STATS_SAVE_RETRIES", "few"},
+		{"stats create dir not a bool", "STATS_CREATE_DIR", "maybe"},
+		{"latency buckets not a number", "LATENCY_BUCKETS", "0.1,fast"},
+		{"latency buckets not increasing", "LATENCY_BUCKETS", "0.5,0.1"},
+		{"latency buckets duplicate", "LATENCY_BUCKETS", "0.1,0.1"},
+		{"latency buckets non-positive", "LATENCY_BUCKETS", "0,1"},
+		{"latency buckets infinite", "LATENCY_BUCKETS", "1,+Inf"},
+		{"latency buckets empty", "LATENCY_BUCKETS", ","},
+		{"max connections negative", "MAX_CONNECTIONS", "-1"},
+		{"max concurrent generations negative", "MAX_CONCURRENT_GENERATIONS", "-1"},
+		{"heavy generation limit zero", "HEAVY_GENERATION_LIMIT", "0"},
+		{"max response bytes negative", "MAX_RESPONSE_BYTES", "-1"},
+		{"max body bytes zero", "MAX_BODY_BYTES", "0"},
+		{"max top n zero", "MAX_TOP_N", "0"},
+		{"max top n not a number", "MAX_TOP_N", "many"},
+		{"max rules zero", "MAX_RULES", "0"},
+		{"max rules not a number", "MAX_RULES", "many"},
+		{"max distinct per ip negative", "MAX_DISTINCT_PER_IP", "-1"},
+		{"distinct per ip window zero", "DISTINCT_PER_IP_WINDOW", "0s"},
+		{"default int1 not a number", "DEFAULT_INT1", "three"},
+		{"default limit zero", "DEFAULT_LIMIT", "0"},
+		{"presets not json", "FIZZBUZZ_PRESETS", "classic"},
+		{"presets unsupported param", "FIZZBUZZ_PRESETS", `{"x": {"start": 5}}`},
+		{"presets zero integer", "FIZZBUZZ_PRESETS", `{"x": {"int1": 0}}`},
+		{"presets boolean value", "FIZZBUZZ_PRESETS", `{"x": {"str1": true}}`},
+		{"min divisor zero", "MIN_DIVISOR", "0"},
+		{"min divisor not a number", "MIN_DIVISOR", "one"},
+		{"max divisor negative", "MAX_DIVISOR", "-5"},
+		{"max divisor not a number", "MAX_DIVISOR", "0x10"},
 	}
 
 	for _, tt := range tests {
@@ -204,6 +476,55 @@ func TestLoad_CORSOrigins(t *testing.T) {
 	}
 }
 
+func TestLoad_TrustedProxies(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected []netip.Prefix
+		wantErr  bool
+	}{
+		{"unset", "", nil, false},
+		{"single cidr", "10.0.0.0/8", []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}, false},
+		{
+			name:  "cidrs and bare ips",
+			value: "10.1.2.3/8, 192.168.1.1, ::1",
+			expected: []netip.Prefix{
+				netip.MustParsePrefix("10.0.0.0/8"),
+				netip.MustParsePrefix("192.168.1.1/32"),
+				netip.MustParsePrefix("::1/128"),
+			},
+		},
+		{"invalid", "10.0.0.0/8,proxy.internal", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			setEnvVars(t, map[string]string{"TRUSTED_PROXIES": tt.value})
+
+			cfg, err := Load()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Load() error = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+
+			if len(cfg.TrustedProxies) != len(tt.expected) {
+				t.Fatalf("TrustedProxies = %v, want %v", cfg.TrustedProxies, tt.expected)
+			}
+			for i := range tt.expected {
+				if cfg.TrustedProxies[i] != tt.expected[i] {
+					t.Fatalf("TrustedProxies = %v, want %v", cfg.TrustedProxies, tt.expected)
+				}
+			}
+		})
+	}
+}
+
 func setEnvVars(t *testing.T, vars map[string]string) {
 	t.Helper()
 	for key, value := range vars {
@@ -225,12 +546,27 @@ func assertConfig(t *testing.T, cfg *Config, expected *Config) {
 	if cfg.IdleTimeout != expected.IdleTimeout {
 		t.Fatalf("IdleTimeout = %s, want %s", cfg.IdleTimeout, expected.IdleTimeout)
 	}
+	if cfg.DisableKeepAlive != expected.DisableKeepAlive {
+		t.Fatalf("DisableKeepAlive = %t, want %t", cfg.DisableKeepAlive, expected.DisableKeepAlive)
+	}
 	if cfg.RequestTimeout != expected.RequestTimeout {
 		t.Fatalf("RequestTimeout = %s, want %s", cfg.RequestTimeout, expected.RequestTimeout)
 	}
+	if cfg.FizzBuzzTimeout != expected.FizzBuzzTimeout {
+		t.Fatalf("FizzBuzzTimeout = %s, want %s", cfg.FizzBuzzTimeout, expected.FizzBuzzTimeout)
+	}
+	if cfg.StatisticsTimeout != expected.StatisticsTimeout {
+		t.Fatalf("StatisticsTimeout = %s, want %s", cfg.StatisticsTimeout, expected.StatisticsTimeout)
+	}
+	if cfg.HealthTimeout != expected.HealthTimeout {
+		t.Fatalf("HealthTimeout = %s, want %s", cfg.HealthTimeout, expected.HealthTimeout)
+	}
 	if cfg.ShutdownTimeout != expected.ShutdownTimeout {
 		t.Fatalf("ShutdownTimeout = %s, want %s", cfg.ShutdownTimeout, expected.ShutdownTimeout)
 	}
+	if cfg.StreamInterval != expected.StreamInterval {
+		t.Fatalf("StreamInterval = %s, want %s", cfg.StreamInterval, expected.StreamInterval)
+	}
 	if cfg.LogLevel != expected.LogLevel {
 		t.Fatalf("LogLevel = %s, want %s", cfg.LogLevel, expected.LogLevel)
 	}
@@ -240,6 +576,123 @@ func assertConfig(t *testing.T, cfg *Config, expected *Config) {
 	if !equalStringSlices(cfg.CORSAllowedOrigins, expected.CORSAllowedOrigins) {
 		t.Fatalf("CORSAllowedOrigins = %v, want %v", cfg.CORSAllowedOrigins, expected.CORSAllowedOrigins)
 	}
+	if !equalStringSlices(cfg.OpsCORSOrigins, expected.OpsCORSOrigins) {
+		t.Fatalf("OpsCORSOrigins = %v, want %v", cfg.OpsCORSOrigins, expected.OpsCORSOrigins)
+	}
+	if cfg.MaxLimit != expected.MaxLimit {
+		t.Fatalf("MaxLimit = %d, want %d", cfg.MaxLimit, expected.MaxLimit)
+	}
+	if cfg.TruncateMode != expected.TruncateMode {
+		t.Fatalf("TruncateMode = %t, want %t", cfg.TruncateMode, expected.TruncateMode)
+	}
+	if cfg.IdempotencyTTL != expected.IdempotencyTTL {
+		t.Fatalf("IdempotencyTTL = %s, want %s", cfg.IdempotencyTTL, expected.IdempotencyTTL)
+	}
+	if cfg.ForceUnhealthy != expected.ForceUnhealthy {
+		t.Fatalf("ForceUnhealthy = %t, want %t", cfg.ForceUnhealthy, expected.ForceUnhealthy)
+	}
+	if cfg.AdminAPIKey != expected.AdminAPIKey {
+		t.Fatalf("AdminAPIKey = %q, want %q", cfg.AdminAPIKey, expected.AdminAPIKey)
+	}
+	if cfg.ResponseShape != expected.ResponseShape {
+		t.Fatalf("ResponseShape = %s, want %s", cfg.ResponseShape, expected.ResponseShape)
+	}
+	if cfg.MaxDistinctParams != expected.MaxDistinctParams {
+		t.Fatalf("MaxDistinctParams = %d, want %d", cfg.MaxDistinctParams, expected.MaxDistinctParams)
+	}
+	if cfg.StatsDecayHalfLife != expected.StatsDecayHalfLife {
+		t.Fatalf("StatsDecayHalfLife = %s, want %s", cfg.StatsDecayHalfLife, expected.StatsDecayHalfLife)
+	}
+	if !reflect.DeepEqual(cfg.AllowedStrings, expected.AllowedStrings) {
+		t.Fatalf("AllowedStrings = %v, want %v", cfg.AllowedStrings, expected.AllowedStrings)
+	}
+	if cfg.StrictIntegers != expected.StrictIntegers {
+		t.Fatalf("StrictIntegers = %v, want %v", cfg.StrictIntegers, expected.StrictIntegers)
+	}
+	if cfg.AllowScientificLimit != expected.AllowScientificLimit {
+		t.Fatalf("AllowScientificLimit = %v, want %v", cfg.AllowScientificLimit, expected.AllowScientificLimit)
+	}
+	if cfg.TolerantNumbers != expected.TolerantNumbers {
+		t.Fatalf("TolerantNumbers = %v, want %v", cfg.TolerantNumbers, expected.TolerantNumbers)
+	}
+	if !reflect.DeepEqual(cfg.StatsHotParams, expected.StatsHotParams) {
+		t.Fatalf("StatsHotParams = %v, want %v", cfg.StatsHotParams, expected.StatsHotParams)
+	}
+	if cfg.StatsPersistPath != expected.StatsPersistPath {
+		t.Fatalf("StatsPersistPath = %q, want %q", cfg.StatsPersistPath, expected.StatsPersistPath)
+	}
+	if cfg.StatsPersistInterval != expected.StatsPersistInterval {
+		t.Fatalf("StatsPersistInterval = %s, want %s", cfg.StatsPersistInterval, expected.StatsPersistInterval)
+	}
+	if cfg.StatsSaveRetries != expected.StatsSaveRetries {
+		t.Fatalf("StatsSaveRetries = %d, want %d", cfg.StatsSaveRetries, expected.StatsSaveRetries)
+	}
+	if cfg.StatsCreateDir != expected.StatsCreateDir {
+		t.Fatalf("StatsCreateDir = %t, want %t", cfg.StatsCreateDir, expected.StatsCreateDir)
+	}
+	if !reflect.DeepEqual(cfg.LatencyBuckets, expected.LatencyBuckets) {
+		t.Fatalf("LatencyBuckets = %v, want %v", cfg.LatencyBuckets, expected.LatencyBuckets)
+	}
+	if cfg.MaxConnections != expected.MaxConnections {
+		t.Fatalf("MaxConnections = %d, want %d", cfg.MaxConnections, expected.MaxConnections)
+	}
+	if cfg.MaxConcurrentGenerations != expected.MaxConcurrentGenerations {
+		t.Fatalf("MaxConcurrentGenerations = %d, want %d", cfg.MaxConcurrentGenerations, expected.MaxConcurrentGenerations)
+	}
+	if cfg.HeavyGenerationLimit != expected.HeavyGenerationLimit {
+		t.Fatalf("HeavyGenerationLimit = %d, want %d", cfg.HeavyGenerationLimit, expected.HeavyGenerationLimit)
+	}
+	if cfg.MaxResponseBytes != expected.MaxResponseBytes {
+		t.Fatalf("MaxResponseBytes = %d, want %d", cfg.MaxResponseBytes, expected.MaxResponseBytes)
+	}
+	if cfg.MaxBodyBytes != expected.MaxBodyBytes {
+		t.Fatalf("MaxBodyBytes = %d, want %d", cfg.MaxBodyBytes, expected.MaxBodyBytes)
+	}
+	if cfg.MaxDistinctPerIP != expected.MaxDistinctPerIP || cfg.DistinctPerIPWindow != expected.DistinctPerIPWindow {
+		t.Fatalf("distinct per IP = %d per %s, want %d per %s", cfg.MaxDistinctPerIP, cfg.DistinctPerIPWindow, expected.MaxDistinctPerIP, expected.DistinctPerIPWindow)
+	}
+	if cfg.MaxTopN != expected.MaxTopN {
+		t.Fatalf("MaxTopN = %d, want %d", cfg.MaxTopN, expected.MaxTopN)
+	}
+	if cfg.MaxRules != expected.MaxRules {
+		t.Fatalf("MaxRules = %d, want %d", cfg.MaxRules, expected.MaxRules)
+	}
+	if !reflect.DeepEqual(cfg.DefaultParams, expected.DefaultParams) {
+		t.Fatalf("DefaultParams = %v, want %v", cfg.DefaultParams, expected.DefaultParams)
+	}
+	if !reflect.DeepEqual(cfg.Presets, expected.Presets) {
+		t.Fatalf("Presets = %v, want %v", cfg.Presets, expected.Presets)
+	}
+	if cfg.MaintenanceMode != expected.MaintenanceMode {
+		t.Fatalf("MaintenanceMode = %v, want %v", cfg.MaintenanceMode, expected.MaintenanceMode)
+	}
+	if cfg.SemanticErrorStatus != expected.SemanticErrorStatus {
+		t.Fatalf("SemanticErrorStatus = %d, want %d", cfg.SemanticErrorStatus, expected.SemanticErrorStatus)
+	}
+	if cfg.UsageHint != expected.UsageHint {
+		t.Fatalf("UsageHint = %v, want %v", cfg.UsageHint, expected.UsageHint)
+	}
+	if cfg.RoutePrefix != expected.RoutePrefix {
+		t.Fatalf("RoutePrefix = %q, want %q", cfg.RoutePrefix, expected.RoutePrefix)
+	}
+	if cfg.RequiredHeader != expected.RequiredHeader {
+		t.Fatalf("RequiredHeader = %q, want %q", cfg.RequiredHeader, expected.RequiredHeader)
+	}
+	if !reflect.DeepEqual(cfg.ExtraResponseHeaders, expected.ExtraResponseHeaders) {
+		t.Fatalf("ExtraResponseHeaders = %v, want %v", cfg.ExtraResponseHeaders, expected.ExtraResponseHeaders)
+	}
+	if cfg.TrailingSlash != expected.TrailingSlash {
+		t.Fatalf("TrailingSlash = %q, want %q", cfg.TrailingSlash, expected.TrailingSlash)
+	}
+	if cfg.CompressResponses != expected.CompressResponses {
+		t.Fatalf("CompressResponses = %v, want %v", cfg.CompressResponses, expected.CompressResponses)
+	}
+	if cfg.MinDivisor != expected.MinDivisor || cfg.MaxDivisor != expected.MaxDivisor {
+		t.Fatalf("divisor range = [%d, %d], want [%d, %d]", cfg.MinDivisor, cfg.MaxDivisor, expected.MinDivisor, expected.MaxDivisor)
+	}
+	if cfg.StartupSelfTest != expected.StartupSelfTest {
+		t.Fatalf("StartupSelfTest = %t, want %t", cfg.StartupSelfTest, expected.StartupSelfTest)
+	}
 }
 
 func equalStringSlices(a, b []string) bool {
@@ -258,14 +711,65 @@ func clearEnv(t *testing.T) {
 	t.Helper()
 	keys := []string{
 		"PORT",
+		"ROUTE_PREFIX",
+		"REQUIRED_HEADER",
+		"EXTRA_RESPONSE_HEADERS",
+		"TRAILING_SLASH",
 		"READ_TIMEOUT",
 		"WRITE_TIMEOUT",
 		"IDLE_TIMEOUT",
+		"DISABLE_KEEPALIVE",
 		"REQUEST_TIMEOUT",
+		"FIZZBUZZ_TIMEOUT",
+		"STATISTICS_TIMEOUT",
+		"HEALTH_TIMEOUT",
 		"SHUTDOWN_TIMEOUT",
+		"STATISTICS_STREAM_INTERVAL",
 		"LOG_LEVEL",
 		"LOG_FORMAT",
 		"CORS_ALLOWED_ORIGINS",
+		"OPS_CORS_ALLOWED_ORIGINS",
+		"MAX_LIMIT",
+		"TRUNCATE_MODE",
+		"IDEMPOTENCY_TTL",
+		"TRUSTED_PROXIES",
+		"FORCE_UNHEALTHY",
+		"ADMIN_API_KEY",
+		"RESPONSE_SHAPE",
+		"MAX_DISTINCT_PARAMS",
+		"STATS_DECAY_HALFLIFE",
+		"STATS_HOT_PARAMS",
+		"STATS_PERSIST_PATH",
+		"STATS_PERSIST_INTERVAL",
+		"STATS_SAVE_RETRIES",
+		"STATS_CREATE_DIR",
+		"LATENCY_BUCKETS",
+		"ALLOWED_STRINGS",
+		"STRICT_INTEGERS",
+		"ALLOW_SCIENTIFIC_LIMIT",
+		"TOLERANT_NUMBERS",
+		"SEMANTIC_ERROR_STATUS",
+		"USAGE_HINT",
+		"MAX_CONNECTIONS",
+		"MAX_CONCURRENT_GENERATIONS",
+		"HEAVY_GENERATION_LIMIT",
+		"MAX_RESPONSE_BYTES",
+		"MAX_BODY_BYTES",
+		"MAX_TOP_N",
+		"MAX_RULES",
+		"MAX_DISTINCT_PER_IP",
+		"DISTINCT_PER_IP_WINDOW",
+		"DEFAULT_INT1",
+		"DEFAULT_INT2",
+		"DEFAULT_LIMIT",
+		"DEFAULT_STR1",
+		"DEFAULT_STR2",
+		"FIZZBUZZ_PRESETS",
+		"MAINTENANCE_MODE",
+		"COMPRESS_RESPONSES",
+		"MIN_DIVISOR",
+		"MAX_DIVISOR",
+		"STARTUP_SELFTEST",
 	}
 	for _, key := range keys {
 		unsetEnv(t, key)
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Category classifies a configuration error so deployment tooling can tell
+// kinds of mistakes apart without parsing messages.
+type Category int
+
+const (
+	// CategoryOther covers errors without a more specific category.
+	CategoryOther Category = iota
+	// CategoryDuration marks unparsable or out-of-range durations.
+	CategoryDuration
+	// CategoryLogLevel marks an empty or unknown LOG_LEVEL.
+	CategoryLogLevel
+	// CategoryLogFormat marks an empty or unknown LOG_FORMAT.
+	CategoryLogFormat
+	// CategoryInteger marks unparsable or out-of-range integers.
+	CategoryInteger
+	// CategoryBoolean marks unparsable booleans.
+	CategoryBoolean
+	// CategoryList marks a malformed entry in a list setting such as
+	// TRUSTED_PROXIES or STATS_HOT_PARAMS.
+	CategoryList
+	// CategoryChoice marks an unknown value for a setting with a fixed set
+	// of choices, such as RESPONSE_SHAPE or TRAILING_SLASH.
+	CategoryChoice
+	// CategoryServer marks a malformed listener or routing setting: PORT,
+	// ROUTE_PREFIX or REQUIRED_HEADER.
+	CategoryServer
+)
+
+// Error is a configuration error tagged with its Category.
+type Error struct {
+	Category Category
+	Err      error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func newError(category Category, format string, args ...any) error {
+	return &Error{Category: category, Err: fmt.Errorf(format, args...)}
+}
+
+// exitCodes maps categories to process exit codes. Anything unlisted exits
+// with 1.
+var exitCodes = map[Category]int{
+	CategoryDuration:  2,
+	CategoryLogLevel:  3,
+	CategoryLogFormat: 4,
+	CategoryInteger:   5,
+	CategoryBoolean:   6,
+	CategoryList:      7,
+	CategoryChoice:    8,
+	CategoryServer:    9,
+}
+
+// ExitCode returns the exit code for a Load or Validate error: 0 for nil,
+// a category-specific code for an *Error, and 1 otherwise.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var cerr *Error
+	if errors.As(err, &cerr) {
+		if code, ok := exitCodes[cerr.Category]; ok {
+			return code
+		}
+	}
+	return 1
+}
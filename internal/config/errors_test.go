@@ -0,0 +1,99 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestExitCode_Load(t *testing.T) {
+	tests := []struct {
+		name string
+		vars map[string]string
+		want int
+	}{
+		{"unparsable duration", map[string]string{"READ_TIMEOUT": "soon"}, 2},
+		{"zero duration", map[string]string{"SHUTDOWN_TIMEOUT": "0s"}, 2},
+		{"negative decay half-life", map[string]string{"STATS_DECAY_HALFLIFE": "-1m"}, 2},
+		{"unknown log level", map[string]string{"LOG_LEVEL": "verbose"}, 3},
+		{"empty log level", map[string]string{"LOG_LEVEL": " "}, 3},
+		{"unknown log format", map[string]string{"LOG_FORMAT": "xml"}, 4},
+		{"unparsable integer", map[string]string{"MAX_LIMIT": "lots"}, 5},
+		{"zero integer", map[string]string{"MAX_BODY_BYTES": "0"}, 5},
+		{"divisor range", map[string]string{"MIN_DIVISOR": "2", "MAX_DIVISOR": "1"}, 5},
+		{"unparsable boolean", map[string]string{"TRUNCATE_MODE": "sometimes"}, 6},
+		{"bad proxy entry", map[string]string{"TRUSTED_PROXIES": "10.0.0.0/33"}, 7},
+		{"bad hot params entry", map[string]string{"STATS_HOT_PARAMS": "int1=3"}, 7},
+		{"unknown response shape", map[string]string{"RESPONSE_SHAPE": "camel"}, 8},
+		{"unknown trailing slash mode", map[string]string{"TRAILING_SLASH": "lenient"}, 8},
+		{"bad route prefix", map[string]string{"ROUTE_PREFIX": "api"}, 9},
+		{"bad required header", map[string]string{"REQUIRED_HEADER": "X-Auth: yes"}, 9},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			setEnvVars(t, tt.vars)
+
+			_, err := Load()
+			if err == nil {
+				t.Fatalf("Load() error = nil, want error")
+			}
+			if got := ExitCode(err); got != tt.want {
+				t.Fatalf("ExitCode(%v) = %d, want %d", err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExitCode_Validate(t *testing.T) {
+	clearEnv(t)
+	valid, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(*Config)
+		want   int
+	}{
+		{"empty port", func(c *Config) { c.Port = "" }, 9},
+		{"route prefix with a query", func(c *Config) { c.RoutePrefix = "/api?v=1" }, 9},
+		{"required header with a colon", func(c *Config) { c.RequiredHeader = "X-Auth:" }, 9},
+		{"unknown response shape", func(c *Config) { c.ResponseShape = "deep" }, 8},
+		{"unknown trailing slash mode", func(c *Config) { c.TrailingSlash = "lenient" }, 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := *valid
+			tt.mutate(&cfg)
+
+			if got := ExitCode(cfg.Validate()); got != tt.want {
+				t.Fatalf("ExitCode(Validate()) = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"nil", nil, 0},
+		{"plain error", errors.New("boom"), 1},
+		{"other category", newError(CategoryOther, "boom"), 1},
+		{"wrapped category", fmt.Errorf("reload: %w", newError(CategoryBoolean, "bad")), 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExitCode(tt.err); got != tt.want {
+				t.Fatalf("ExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,36 +1,251 @@
 package fizzbuzz
 
-import "strconv"
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Placeholder is replaced by the current number in templated words.
+const Placeholder = "{n}"
+
+// Category identifies which replacement a FizzBuzz value receives.
+type Category int
+
+const (
+	// CategoryNumber marks values that are rendered as plain numbers.
+	CategoryNumber Category = iota
+	// CategoryStr1 marks values divisible by int1 only.
+	CategoryStr1
+	// CategoryStr2 marks values divisible by int2 only.
+	CategoryStr2
+	// CategoryBoth marks values divisible by both int1 and int2.
+	CategoryBoth
+)
+
+// Rule decides which numbers a divisor matches.
+type Rule int
+
+const (
+	// RuleDivisible matches numbers divisible by the divisor.
+	RuleDivisible Rule = iota
+	// RuleDigitSum matches numbers whose digit sum is divisible by the
+	// divisor, so with 3, 12 (1+2) matches and 13 does not.
+	RuleDigitSum
+)
+
+// Options selects the optional behaviors of GenerateWith. The zero value
+// generates the classic sequence.
+type Options struct {
+	// Templated replaces every Placeholder in the words with the number.
+	Templated bool
+	// Base renders numbers in base 2 to 36; zero means 10.
+	Base int
+	// Rule decides which numbers are replaced.
+	Rule Rule
+	// CollapseEqual emits a single word instead of str1+str2 when both
+	// match and str1 == str2, so "foo" rather than "foofoo".
+	CollapseEqual bool
+	// Both replaces values divisible by both divisors instead of str1+str2.
+	// Empty falls back to concatenation; when set it takes precedence over
+	// CollapseEqual.
+	Both string
+}
 
 // Generate returns a slice containing the FizzBuzz sequence
 func Generate(int1, int2, limit int, str1, str2 string) []string {
-	if limit <= 0 {
+	return generate(int64(int1), int64(int2), 1, limit, str1, str2, Options{})
+}
+
+// KeyByNumber keys values, a sequence beginning at start, by the number each
+// one stands for, for lookup-style consumers such as format=map.
+func KeyByNumber(start int64, values []string) map[int64]string {
+	result := make(map[int64]string, len(values))
+	for i, value := range values {
+		result[start+int64(i)] = value
+	}
+	return result
+}
+
+// GenerateWith returns count FizzBuzz values starting at start, which may be
+// negative, with the behaviors chosen in opts. Divisibility uses Go's
+// truncated modulo, so -6 is divisible by 3 just like 6, and 0 is divisible
+// by every non-zero divisor. Divisors and start are 64-bit, so values beyond
+// the int32 range behave the same on every platform. With opts.Templated,
+// every Placeholder in the words is replaced with the value being rendered,
+// so "item-{n}" yields "item-3" at 3. In a base other than 10, digits above 9
+// are lower-case letters, so 10 is "a" in base 16; words are unchanged, but
+// numbers substituted for Placeholder use the same base.
+func GenerateWith(int1, int2, start int64, count int, str1, str2 string, opts Options) []string {
+	return generate(int1, int2, start, count, str1, str2, opts)
+}
+
+// generate computes in int64 throughout and advances by offset rather than
+// comparing against start+count, so no intermediate value can overflow.
+func generate(int1, int2, start int64, count int, str1, str2 string, opts Options) []string {
+	if count <= 0 {
 		return []string{}
 	}
 
-	result := make([]string, 0, limit)
+	base := cmp.Or(opts.Base, 10)
+	both := str1 + str2
+	switch {
+	case opts.Both != "":
+		both = opts.Both
+	case opts.CollapseEqual && str1 == str2:
+		both = str1
+	}
+	templated := opts.Templated && (strings.Contains(str1, Placeholder) || strings.Contains(str2, Placeholder) || strings.Contains(both, Placeholder))
+	result := make([]string, 0, count)
 
-	for n := 1; n <= limit; n++ {
-		divisibleByInt1 := false
-		if int1 != 0 {
-			divisibleByInt1 = n%int1 == 0
+	for i := 0; i < count; i++ {
+		n := start + int64(i)
+		var word string
+		switch classify(n, int1, int2, opts.Rule) {
+		case CategoryBoth:
+			word = both
+		case CategoryStr1:
+			word = str1
+		case CategoryStr2:
+			word = str2
+		default:
+			result = append(result, formatNumber(n, base))
+			continue
 		}
-		divisibleByInt2 := false
-		if int2 != 0 {
-			divisibleByInt2 = n%int2 == 0
+		if templated {
+			word = strings.ReplaceAll(word, Placeholder, formatNumber(n, base))
 		}
+		result = append(result, word)
+	}
 
-		switch {
-		case divisibleByInt1 && divisibleByInt2:
-			result = append(result, str1+str2)
-		case divisibleByInt1:
-			result = append(result, str1)
-		case divisibleByInt2:
-			result = append(result, str2)
-		default:
-			result = append(result, strconv.Itoa(n))
+	return result
+}
+
+// Pair replaces the numbers its Divisor matches with Word.
+type Pair struct {
+	Divisor int64
+	Word    string
+}
+
+// GenerateRules is GenerateWith for any number of divisor-word pairs. A value
+// matched by several pairs gets their words concatenated in the order given,
+// so 3:fizz and 5:buzz yield "fizzbuzz" at 15. opts.CollapseEqual and
+// opts.Both do not apply.
+func GenerateRules(pairs []Pair, start int64, count int, opts Options) []string {
+	if count <= 0 {
+		return []string{}
+	}
+
+	base := cmp.Or(opts.Base, 10)
+	templated := opts.Templated && slices.ContainsFunc(pairs, func(p Pair) bool {
+		return strings.Contains(p.Word, Placeholder)
+	})
+	result := make([]string, 0, count)
+
+	var word strings.Builder
+	for i := 0; i < count; i++ {
+		n := start + int64(i)
+		m := n
+		if opts.Rule == RuleDigitSum {
+			m = digitSum(n)
 		}
+		word.Reset()
+		for _, pair := range pairs {
+			if pair.Divisor != 0 && m%pair.Divisor == 0 {
+				word.WriteString(pair.Word)
+			}
+		}
+		if word.Len() == 0 {
+			result = append(result, formatNumber(n, base))
+			continue
+		}
+		out := word.String()
+		if templated {
+			out = strings.ReplaceAll(out, Placeholder, formatNumber(n, base))
+		}
+		result = append(result, out)
 	}
 
 	return result
 }
+
+// smallNumberCacheSize covers limits up to 100,000, so a sequence in that
+// range never formats a number at request time.
+const smallNumberCacheSize = 100_000
+
+// smallNumbers holds the decimal form of 0 through smallNumberCacheSize,
+// built on first use and shared by every request.
+var smallNumbers = sync.OnceValue(func() []string {
+	numbers := make([]string, smallNumberCacheSize+1)
+	for i := range numbers {
+		numbers[i] = strconv.Itoa(i)
+	}
+	return numbers
+})
+
+// formatNumber is strconv.FormatInt(n, base), served from smallNumbers when
+// base is 10 and n is in range.
+func formatNumber(n int64, base int) string {
+	if base != 10 {
+		return strconv.FormatInt(n, base)
+	}
+	if n >= 0 && n <= smallNumberCacheSize {
+		return smallNumbers()[n]
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+// IndicesWith returns the 1-based positions within the sequence of count
+// values starting at start whose category matches the requested one, with
+// numbers matched by rule.
+func IndicesWith(int1, int2, start int64, count int, category Category, rule Rule) []int {
+	result := []int{}
+	for i := 0; i < count; i++ {
+		if classify(start+int64(i), int1, int2, rule) == category {
+			result = append(result, i+1)
+		}
+	}
+	return result
+}
+
+func classify(n, int1, int2 int64, rule Rule) Category {
+	if rule == RuleDigitSum {
+		n = digitSum(n)
+	}
+
+	divisibleByInt1 := false
+	if int1 != 0 {
+		divisibleByInt1 = n%int1 == 0
+	}
+	divisibleByInt2 := false
+	if int2 != 0 {
+		divisibleByInt2 = n%int2 == 0
+	}
+
+	switch {
+	case divisibleByInt1 && divisibleByInt2:
+		return CategoryBoth
+	case divisibleByInt1:
+		return CategoryStr1
+	case divisibleByInt2:
+		return CategoryStr2
+	default:
+		return CategoryNumber
+	}
+}
+
+// digitSum returns the sum of the decimal digits of n, ignoring its sign.
+func digitSum(n int64) int64 {
+	var sum int64
+	for n != 0 {
+		digit := n % 10
+		if digit < 0 {
+			digit = -digit
+		}
+		sum += digit
+		n /= 10
+	}
+	return sum
+}
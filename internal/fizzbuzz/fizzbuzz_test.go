@@ -1,7 +1,10 @@
 package fizzbuzz
 
 import (
+	"cmp"
+	"math"
 	"reflect"
+	"strconv"
 	"testing"
 )
 
@@ -182,3 +185,514 @@ func TestGenerate(t *testing.T) {
 		})
 	}
 }
+
+func TestKeyByNumber(t *testing.T) {
+	t.Parallel()
+
+	for _, limit := range []int{0, 1, 15, 100} {
+		values := Generate(3, 5, limit, "fizz", "buzz")
+		got := KeyByNumber(1, values)
+
+		if len(got) != len(values) {
+			t.Fatalf("limit %d: expected %d entries, got %d", limit, len(values), len(got))
+		}
+		for i, value := range values {
+			if got[int64(i+1)] != value {
+				t.Fatalf("limit %d: entry %d = %q, want %q", limit, i+1, got[int64(i+1)], value)
+			}
+		}
+	}
+
+	if got := KeyByNumber(-1, []string{"-1", "fizzbuzz", "1"}); !reflect.DeepEqual(got, map[int64]string{-1: "-1", 0: "fizzbuzz", 1: "1"}) {
+		t.Fatalf("KeyByNumber from -1 = %v", got)
+	}
+}
+
+func TestGenerateWith_Start(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		int1  int
+		int2  int
+		start int
+		count int
+		str1  string
+		str2  string
+		want  []string
+	}{
+		{
+			name:  "start at one matches Generate",
+			int1:  3,
+			int2:  5,
+			start: 1,
+			count: 5,
+			str1:  "fizz",
+			str2:  "buzz",
+			want:  []string{"1", "2", "fizz", "4", "buzz"},
+		},
+		{
+			name:  "negative to positive range",
+			int1:  3,
+			int2:  5,
+			start: -6,
+			count: 13,
+			str1:  "fizz",
+			str2:  "buzz",
+			want: []string{
+				"fizz", "buzz", "-4", "fizz", "-2", "-1",
+				"fizzbuzz",
+				"1", "2", "fizz", "4", "buzz", "fizz",
+			},
+		},
+		{
+			name:  "zero is divisible by both divisors",
+			int1:  2,
+			int2:  7,
+			start: 0,
+			count: 1,
+			str1:  "foo",
+			str2:  "bar",
+			want:  []string{"foobar"},
+		},
+		{
+			name:  "zero renders as number when divisors are ignored",
+			int1:  0,
+			int2:  0,
+			start: -1,
+			count: 3,
+			str1:  "foo",
+			str2:  "bar",
+			want:  []string{"-1", "0", "1"},
+		},
+		{
+			name:  "zero count",
+			int1:  3,
+			int2:  5,
+			start: -10,
+			count: 0,
+			str1:  "fizz",
+			str2:  "buzz",
+			want:  []string{},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := GenerateWith(int64(tc.int1), int64(tc.int2), int64(tc.start), tc.count, tc.str1, tc.str2, Options{})
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("GenerateWith(%d, %d, %d, %d, %q, %q) = %v, want %v",
+					tc.int1, tc.int2, tc.start, tc.count, tc.str1, tc.str2, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGenerateWith_LargeDivisors(t *testing.T) {
+	t.Parallel()
+
+	const maxInt32 = math.MaxInt32
+
+	tests := []struct {
+		name  string
+		int1  int64
+		int2  int64
+		start int64
+		count int
+		want  []string
+	}{
+		{
+			name:  "divisor at int32 max",
+			int1:  maxInt32,
+			int2:  2,
+			start: maxInt32 - 1,
+			count: 3,
+			want:  []string{"buzz", "fizz", "buzz"},
+		},
+		{
+			name:  "divisor beyond int32 range",
+			int1:  maxInt32 + 1,
+			int2:  3,
+			start: maxInt32,
+			count: 2,
+			want:  []string{"2147483647", "fizz"},
+		},
+		{
+			name:  "product of int32-sized divisors",
+			int1:  65536,
+			int2:  maxInt32 + 1,
+			start: 1 << 32,
+			count: 1,
+			want:  []string{"fizzbuzz"},
+		},
+		{
+			name:  "start near int64 max does not overflow",
+			int1:  math.MaxInt64,
+			int2:  7,
+			start: math.MaxInt64 - 1,
+			count: 2,
+			want:  []string{"9223372036854775806", "fizzbuzz"},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := GenerateWith(tc.int1, tc.int2, tc.start, tc.count, "fizz", "buzz", Options{})
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("GenerateWith(%d, %d, %d, %d) = %v, want %v",
+					tc.int1, tc.int2, tc.start, tc.count, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGenerateWith_Templated(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		start int
+		count int
+		str1  string
+		str2  string
+		want  []string
+	}{
+		{
+			name:  "placeholder replaced per element",
+			start: 1,
+			count: 6,
+			str1:  "item-{n}",
+			str2:  "buzz",
+			want:  []string{"1", "2", "item-3", "4", "buzz", "item-6"},
+		},
+		{
+			name:  "both words templated when combined",
+			start: 15,
+			count: 1,
+			str1:  "{n}:",
+			str2:  "{n}!",
+			want:  []string{"15:15!"},
+		},
+		{
+			name:  "negative numbers keep their sign",
+			start: -3,
+			count: 1,
+			str1:  "n{n}",
+			str2:  "buzz",
+			want:  []string{"n-3"},
+		},
+		{
+			name:  "words without placeholder unchanged",
+			start: 1,
+			count: 3,
+			str1:  "fizz",
+			str2:  "buzz",
+			want:  []string{"1", "2", "fizz"},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := GenerateWith(3, 5, int64(tc.start), tc.count, tc.str1, tc.str2, Options{Templated: true})
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("GenerateWith(3, 5, %d, %d, %q, %q, templated) = %v, want %v",
+					tc.start, tc.count, tc.str1, tc.str2, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGenerateWith_PlaceholderIsLiteral(t *testing.T) {
+	t.Parallel()
+
+	got := GenerateWith(3, 5, 1, 3, "item-{n}", "buzz", Options{})
+	want := []string{"1", "2", "item-{n}"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GenerateWith() = %v, want %v", got, want)
+	}
+}
+
+func TestGenerateWith_Base(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		start     int64
+		count     int
+		base      int
+		templated bool
+		str1      string
+		want      []string
+	}{
+		{name: "base 10 matches the default", start: 1, count: 5, base: 10, want: []string{"1", "2", "fizz", "4", "buzz"}},
+		{name: "base 2", start: 1, count: 8, base: 2, want: []string{"1", "10", "fizz", "100", "buzz", "fizz", "111", "1000"}},
+		{name: "base 16", start: 10, count: 5, base: 16, want: []string{"buzz", "b", "fizz", "d", "e"}},
+		{name: "base 36", start: 34, count: 2, base: 36, want: []string{"y", "buzz"}},
+		{name: "negative base 16", start: -11, count: 2, base: 16, want: []string{"-b", "buzz"}},
+		{name: "templated placeholder uses base", start: 11, count: 2, base: 16, templated: true, str1: "fizz-{n}", want: []string{"b", "fizz-c"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			str1 := cmp.Or(tc.str1, "fizz")
+			got := GenerateWith(3, 5, tc.start, tc.count, str1, "buzz", Options{Templated: tc.templated, Base: tc.base})
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGenerateWith_DigitSum(t *testing.T) {
+	t.Parallel()
+
+	// Digit sums of 10..21 are 1 2 3 4 5 6 7 8 9 10 2 3.
+	got := GenerateWith(3, 5, 10, 12, "fizz", "buzz", Options{Rule: RuleDigitSum})
+	want := []string{"10", "11", "fizz", "13", "buzz", "fizz", "16", "17", "fizz", "buzz", "20", "fizz"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GenerateWith(digitsum) = %v, want %v", got, want)
+	}
+
+	// 0 (digit sum 0) and 159 (digit sum 15) match both divisors.
+	got = GenerateWith(3, 5, 0, 1, "fizz", "buzz", Options{Rule: RuleDigitSum})
+	if want := []string{"fizzbuzz"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GenerateWith(digitsum, 0) = %v, want %v", got, want)
+	}
+	got = GenerateWith(3, 5, 159, 1, "fizz", "buzz", Options{Rule: RuleDigitSum})
+	if want := []string{"fizzbuzz"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GenerateWith(digitsum, 159) = %v, want %v", got, want)
+	}
+
+	indices := IndicesWith(3, 5, 10, 12, CategoryStr1, RuleDigitSum)
+	if want := []int{3, 6, 9, 12}; !reflect.DeepEqual(indices, want) {
+		t.Errorf("IndicesWith(digitsum) = %v, want %v", indices, want)
+	}
+}
+
+func TestGenerateWith_CollapseEqual(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		str1     string
+		str2     string
+		collapse bool
+		want     string
+	}{
+		{name: "equal words concatenated by default", str1: "foo", str2: "foo", want: "foofoo"},
+		{name: "equal words collapsed", str1: "foo", str2: "foo", collapse: true, want: "foo"},
+		{name: "different words unaffected", str1: "fizz", str2: "buzz", collapse: true, want: "fizzbuzz"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := GenerateWith(3, 5, 14, 3, tc.str1, tc.str2, Options{CollapseEqual: tc.collapse})
+			want := []string{"14", tc.want, "16"}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("GenerateWith(%q, %q, collapse=%v) = %v, want %v", tc.str1, tc.str2, tc.collapse, got, want)
+			}
+		})
+	}
+}
+
+func TestGenerateWith_Both(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{name: "falls back to concatenation", opts: Options{}, want: []string{"14", "fizzbuzz"}},
+		{name: "override", opts: Options{Both: "bang"}, want: []string{"14", "bang"}},
+		{name: "override wins over collapse", opts: Options{Both: "bang", CollapseEqual: true}, want: []string{"14", "bang"}},
+		{name: "templated override", opts: Options{Both: "both-{n}", Templated: true}, want: []string{"14", "both-15"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := GenerateWith(3, 5, 14, 2, "fizz", "buzz", tc.opts)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("GenerateWith(%+v) = %v, want %v", tc.opts, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGenerateRules(t *testing.T) {
+	t.Parallel()
+
+	fizzBuzzBazz := []Pair{{Divisor: 3, Word: "fizz"}, {Divisor: 5, Word: "buzz"}, {Divisor: 7, Word: "bazz"}}
+	tests := []struct {
+		name  string
+		pairs []Pair
+		start int64
+		count int
+		opts  Options
+		want  []string
+	}{
+		{name: "three pairs", pairs: fizzBuzzBazz, start: 1, count: 7, want: []string{"1", "2", "fizz", "4", "buzz", "fizz", "bazz"}},
+		{name: "words concatenate in order", pairs: fizzBuzzBazz, start: 104, count: 2, want: []string{"104", "fizzbuzzbazz"}},
+		{name: "order follows pairs", pairs: []Pair{{Divisor: 5, Word: "buzz"}, {Divisor: 3, Word: "fizz"}}, start: 15, count: 1, want: []string{"buzzfizz"}},
+		{name: "single pair", pairs: []Pair{{Divisor: 2, Word: "even"}}, start: 1, count: 3, want: []string{"1", "even", "3"}},
+		{name: "no pairs", start: 1, count: 2, want: []string{"1", "2"}},
+		{name: "templated", pairs: []Pair{{Divisor: 3, Word: "x{n}"}}, start: 3, count: 1, opts: Options{Templated: true}, want: []string{"x3"}},
+		{name: "base", pairs: []Pair{{Divisor: 3, Word: "fizz"}}, start: 10, count: 3, opts: Options{Base: 16}, want: []string{"a", "b", "fizz"}},
+		{name: "digit sum", pairs: []Pair{{Divisor: 3, Word: "fizz"}}, start: 12, count: 2, opts: Options{Rule: RuleDigitSum}, want: []string{"fizz", "13"}},
+		{name: "empty", pairs: fizzBuzzBazz, start: 1, count: 0, want: []string{}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := GenerateRules(tc.pairs, tc.start, tc.count, tc.opts)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("GenerateRules(%v, %d, %d) = %v, want %v", tc.pairs, tc.start, tc.count, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGenerateWith_EmptyIsNotNil(t *testing.T) {
+	for _, count := range []int{0, -1} {
+		got := GenerateWith(3, 5, 1, count, "fizz", "buzz", Options{})
+		if got == nil || len(got) != 0 {
+			t.Fatalf("GenerateWith(count=%d) = %#v, want an empty non-nil slice", count, got)
+		}
+	}
+}
+
+func TestDigitSum(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		n    int64
+		want int64
+	}{
+		{0, 0},
+		{7, 7},
+		{12, 3},
+		{999, 27},
+		{-12, 3},
+		{math.MaxInt64, 88},
+		{math.MinInt64, 89},
+	}
+
+	for _, tc := range tests {
+		if got := digitSum(tc.n); got != tc.want {
+			t.Errorf("digitSum(%d) = %d, want %d", tc.n, got, tc.want)
+		}
+	}
+}
+
+func TestIndicesWith(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		category Category
+		want     []int
+	}{
+		{name: "str1 only", category: CategoryStr1, want: []int{3, 6, 9, 12}},
+		{name: "str2 only", category: CategoryStr2, want: []int{5, 10}},
+		{name: "both", category: CategoryBoth, want: []int{15}},
+		{name: "plain numbers", category: CategoryNumber, want: []int{1, 2, 4, 7, 8, 11, 13, 14}},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := IndicesWith(3, 5, 1, 15, tc.category, RuleDivisible)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("IndicesWith(3, 5, 1, 15, %d) = %v, want %v", tc.category, got, tc.want)
+			}
+		})
+	}
+}
+
+// naiveGenerate formats every number with strconv, without the small number
+// cache; it is kept as the reference its output must match byte for byte.
+func naiveGenerate(int1, int2, start int64, count int, str1, str2 string) []string {
+	result := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		n := start + int64(i)
+		switch classify(n, int1, int2, RuleDivisible) {
+		case CategoryBoth:
+			result = append(result, str1+str2)
+		case CategoryStr1:
+			result = append(result, str1)
+		case CategoryStr2:
+			result = append(result, str2)
+		default:
+			result = append(result, strconv.FormatInt(n, 10))
+		}
+	}
+	return result
+}
+
+func TestGenerate_MatchesNaive(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		int1, int2 int64
+		start      int64
+		count      int
+	}{
+		{name: "classic", int1: 3, int2: 5, start: 1, count: 100},
+		{name: "digit boundaries", int1: 7, int2: 11, start: 1, count: 100_000},
+		{name: "cache boundary", int1: 3, int2: 5, start: smallNumberCacheSize - 50, count: 100},
+		{name: "negative to positive", int1: 3, int2: 5, start: -1_000, count: 2_001},
+		{name: "all numbers", int1: 0, int2: 0, start: 95, count: 10},
+		{name: "no numbers", int1: 1, int2: 1, start: 1, count: 10},
+		{name: "near max int64", int1: 3, int2: 5, start: math.MaxInt64 - 99, count: 100},
+		{name: "near min int64", int1: 3, int2: 5, start: math.MinInt64, count: 100},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := GenerateWith(tc.int1, tc.int2, tc.start, tc.count, "fizz", "buzz", Options{})
+			want := naiveGenerate(tc.int1, tc.int2, tc.start, tc.count, "fizz", "buzz")
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("GenerateWith differs from the naive implementation")
+			}
+		})
+	}
+}
+
+func BenchmarkGenerateLarge(b *testing.B) {
+	for _, bc := range []struct {
+		name     string
+		generate func() []string
+	}{
+		{"optimized", func() []string { return GenerateWith(3, 5, 1, 100_000, "fizz", "buzz", Options{}) }},
+		{"naive", func() []string { return naiveGenerate(3, 5, 1, 100_000, "fizz", "buzz") }},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				bc.generate()
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package fizzbuzz
+
+import "sync"
+
+// GenerateParallel returns the same sequence as Generate, splitting the work
+// across up to workers goroutines. Each worker fills its own contiguous range
+// of the result, so the output is in index order without reassembly. A
+// workers value below 2 generates sequentially.
+func GenerateParallel(int1, int2, limit int, str1, str2 string, workers int) []string {
+	if workers < 2 || limit < 2 {
+		return Generate(int1, int2, limit, str1, str2)
+	}
+	workers = min(workers, limit)
+
+	result := make([]string, limit)
+	chunk := (limit + workers - 1) / workers
+	var wg sync.WaitGroup
+	for offset := 0; offset < limit; offset += chunk {
+		count := min(chunk, limit-offset)
+		wg.Go(func() {
+			copy(result[offset:offset+count], generate(int64(int1), int64(int2), int64(offset)+1, count, str1, str2, Options{}))
+		})
+	}
+	wg.Wait()
+
+	return result
+}
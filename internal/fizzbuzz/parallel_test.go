@@ -0,0 +1,48 @@
+package fizzbuzz
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// TestGenerate_Ordered pins the ordering contract every generator must keep:
+// element i is the value for i+1.
+func TestGenerate_Ordered(t *testing.T) {
+	t.Parallel()
+
+	got := Generate(3, 5, 10_000, "fizz", "buzz")
+	for i, value := range got {
+		n := i + 1
+		want := strconv.Itoa(n)
+		switch {
+		case n%15 == 0:
+			want = "fizzbuzz"
+		case n%3 == 0:
+			want = "fizz"
+		case n%5 == 0:
+			want = "buzz"
+		}
+		if value != want {
+			t.Fatalf("element %d = %q, want %q", i, value, want)
+		}
+	}
+}
+
+func TestGenerateParallel_MatchesGenerate(t *testing.T) {
+	t.Parallel()
+
+	for _, limit := range []int{0, 1, 2, 15, 1000, 100_003} {
+		want := Generate(3, 5, limit, "fizz", "buzz")
+		for _, workers := range []int{-1, 0, 1, 2, 3, 7, 16, 2000} {
+			t.Run(strconv.Itoa(limit)+"/"+strconv.Itoa(workers), func(t *testing.T) {
+				t.Parallel()
+
+				got := GenerateParallel(3, 5, limit, "fizz", "buzz", workers)
+				if !reflect.DeepEqual(got, want) {
+					t.Fatalf("GenerateParallel(limit=%d, workers=%d) differs from Generate", limit, workers)
+				}
+			})
+		}
+	}
+}
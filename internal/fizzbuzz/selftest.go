@@ -0,0 +1,28 @@
+package fizzbuzz
+
+import "fmt"
+
+var classicSequence = []string{
+	"1", "2", "fizz", "4", "buzz",
+	"fizz", "7", "8", "fizz", "buzz",
+	"11", "fizz", "13", "14", "fizzbuzz",
+}
+
+// SelfTest generates the classic 3/5/15 sequence and compares it with the
+// known answer, catching a broken build before it takes traffic.
+func SelfTest() error {
+	return verify(3, 5, 15, "fizz", "buzz", classicSequence)
+}
+
+func verify(int1, int2, limit int, str1, str2 string, want []string) error {
+	got := Generate(int1, int2, limit, str1, str2)
+	if len(got) != len(want) {
+		return fmt.Errorf("self-test: expected %d values, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			return fmt.Errorf("self-test: position %d: expected %q, got %q", i+1, want[i], got[i])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package fizzbuzz
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSelfTest(t *testing.T) {
+	if err := SelfTest(); err != nil {
+		t.Fatalf("SelfTest() error = %v", err)
+	}
+}
+
+func TestVerify_WrongExpectation(t *testing.T) {
+	wrong := slices.Clone(classicSequence)
+	wrong[14] = "buzzfizz"
+
+	err := verify(3, 5, 15, "fizz", "buzz", wrong)
+	if err == nil {
+		t.Fatal("verify() error = nil, want error")
+	}
+	if want := `self-test: position 15: expected "buzzfizz", got "fizzbuzz"`; err.Error() != want {
+		t.Fatalf("verify() error = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestVerify_WrongLength(t *testing.T) {
+	if err := verify(3, 5, 15, "fizz", "buzz", classicSequence[:10]); err == nil {
+		t.Fatal("verify() error = nil, want error")
+	}
+}
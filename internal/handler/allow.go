@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+)
+
+// fizzBuzzMethods are the methods routed to /fizzbuzz.
+var fizzBuzzMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodOptions}
+
+// FizzBuzzDescription describes /fizzbuzz in reply to a plain OPTIONS
+// request.
+type FizzBuzzDescription struct {
+	Methods  []string `json:"methods"`
+	Required []string `json:"required"`
+	Optional []string `json:"optional"`
+}
+
+// FizzBuzzOptions answers OPTIONS requests that are not CORS preflights
+// with an Allow header and a short description of the endpoint. Preflights
+// are answered by the CORS middleware before reaching it.
+func (h *Handler) FizzBuzzOptions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Allow", strings.Join(fizzBuzzMethods, ", "))
+	h.respondJSON(w, r, http.StatusOK, FizzBuzzDescription{
+		Methods:  fizzBuzzMethods,
+		Required: []string{"int1", "int2", "limit", "str1", "str2"},
+		Optional: []string{
+			"str3", "start", "only", "rule", "base", "numeric", "templated", "collapse_equal",
+			"shuffle", "seed", "stream", "download", "preview", "echo_params",
+			"format",
+		},
+	})
+}
@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"os"
+)
+
+// dirWritableChecker fails while files cannot be created in dir.
+type dirWritableChecker struct {
+	name string
+	dir  string
+}
+
+// NewDirWritableChecker returns a HealthChecker that creates, writes and
+// removes a small file in dir on every check, so /ready fails as soon as a
+// directory the service persists to stops accepting writes.
+func NewDirWritableChecker(name, dir string) HealthChecker {
+	return dirWritableChecker{name: name, dir: dir}
+}
+
+func (c dirWritableChecker) Name() string {
+	return c.name
+}
+
+func (c dirWritableChecker) Check(context.Context) error {
+	f, err := os.CreateTemp(c.dir, ".ready-*")
+	if err != nil {
+		return err
+	}
+	_, writeErr := f.Write([]byte{'\n'})
+	closeErr := f.Close()
+	removeErr := os.Remove(f.Name())
+	return errors.Join(writeErr, closeErr, removeErr)
+}
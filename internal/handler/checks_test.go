@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/statistics"
+)
+
+func TestDirWritableChecker(t *testing.T) {
+	tests := []struct {
+		name    string
+		dir     func(t *testing.T) string
+		healthy bool
+	}{
+		{
+			name:    "writable dir",
+			dir:     func(t *testing.T) string { return t.TempDir() },
+			healthy: true,
+		},
+		{
+			name: "read-only dir",
+			dir: func(t *testing.T) string {
+				if os.Geteuid() == 0 {
+					t.Skip("root can write to read-only directories")
+				}
+				dir := t.TempDir()
+				if err := os.Chmod(dir, 0o555); err != nil {
+					t.Fatalf("chmod: %v", err)
+				}
+				t.Cleanup(func() { _ = os.Chmod(dir, 0o755) })
+				return dir
+			},
+		},
+		{
+			name: "missing dir",
+			dir:  func(t *testing.T) string { return filepath.Join(t.TempDir(), "gone") },
+		},
+		{
+			name: "not a dir",
+			dir: func(t *testing.T) string {
+				path := filepath.Join(t.TempDir(), "file")
+				if err := os.WriteFile(path, nil, 0o644); err != nil {
+					t.Fatalf("write file: %v", err)
+				}
+				return path
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := tc.dir(t)
+			checker := NewDirWritableChecker("statistics_persistence", dir)
+
+			err := checker.Check(context.Background())
+			if tc.healthy && err != nil {
+				t.Fatalf("expected healthy, got %v", err)
+			}
+			if !tc.healthy && err == nil {
+				t.Fatal("expected an error")
+			}
+
+			if tc.healthy {
+				leftovers, _ := os.ReadDir(dir)
+				if len(leftovers) != 0 {
+					t.Fatalf("expected the probe file to be removed, found %d entries", len(leftovers))
+				}
+			}
+		})
+	}
+}
+
+func TestHandler_Ready_DirWritable(t *testing.T) {
+	h := NewHandler(statistics.NewStore(), nil)
+	h.RegisterHealthChecker(NewDirWritableChecker("statistics_persistence", filepath.Join(t.TempDir(), "gone")))
+
+	rec := httptest.NewRecorder()
+	h.Ready(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status %d, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+	var body ReadyResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	if len(body.Checks) != 1 || body.Checks[0].Name != "statistics_persistence" || body.Checks[0].Status != "error" {
+		t.Fatalf("expected a failing statistics_persistence check, got %+v", body.Checks)
+	}
+}
@@ -0,0 +1,60 @@
+package handler
+
+import "sync"
+
+type generationCall struct {
+	done   chan struct{}
+	result []string
+	ok     bool
+}
+
+// generationGroup coalesces concurrent identical generations: a request for
+// a key already being generated waits for that generation and shares its
+// result instead of starting another. The zero value is ready to use.
+type generationGroup struct {
+	mu       sync.Mutex
+	inflight map[GenerationParams]*generationCall
+}
+
+// do returns fn's result for key, running fn only if no call for key is in
+// flight. The result may be handed to several callers, so none of them may
+// modify it. If the running fn panics, the waiting callers run fn
+// themselves.
+func (g *generationGroup) do(key GenerationParams, fn func() []string) []string {
+	g.mu.Lock()
+	if call, ok := g.inflight[key]; ok {
+		g.mu.Unlock()
+		<-call.done
+		if call.ok {
+			return call.result
+		}
+		return fn()
+	}
+	if g.inflight == nil {
+		g.inflight = make(map[GenerationParams]*generationCall)
+	}
+	call := &generationCall{done: make(chan struct{})}
+	g.inflight[key] = call
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.inflight, key)
+		g.mu.Unlock()
+		close(call.done)
+	}()
+
+	call.result = fn()
+	call.ok = true
+	return call.result
+}
+
+// generateSequence generates the sequence for params up to limit, sharing
+// the work with any identical request in flight. The result may be shared
+// and must be copied before it is modified.
+func (h *Handler) generateSequence(params fizzBuzzParams, limit int) []string {
+	key := params.generation(params.start, limit)
+	return h.inflight.do(key, func() []string {
+		return h.generator.Generate(key)
+	})
+}
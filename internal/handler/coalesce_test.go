@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/statistics"
+)
+
+// blockingGenerator returns a Generator that counts its calls and waits for
+// release before generating.
+func blockingGenerator(calls *atomic.Int32, release <-chan struct{}) Generator {
+	return GeneratorFunc(func(params GenerationParams) []string {
+		calls.Add(1)
+		<-release
+		return DefaultGenerator.Generate(params)
+	})
+}
+
+// serveConcurrently starts a FizzBuzz request per target inside the
+// synctest bubble and returns once every request is blocked or done.
+func serveConcurrently(h *Handler, targets []string) []*httptest.ResponseRecorder {
+	recs := make([]*httptest.ResponseRecorder, len(targets))
+	for i, target := range targets {
+		recs[i] = httptest.NewRecorder()
+		go h.FizzBuzz(recs[i], httptest.NewRequest(http.MethodGet, target, nil))
+	}
+	synctest.Wait()
+	return recs
+}
+
+func TestHandler_FizzBuzz_CoalescesIdenticalRequests(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const requests = 10
+		var calls atomic.Int32
+		release := make(chan struct{})
+		h := NewHandler(statistics.NewStore(), nil)
+		h.generator = blockingGenerator(&calls, release)
+
+		targets := make([]string, requests)
+		for i := range targets {
+			targets[i] = "/fizzbuzz?int1=3&int2=5&limit=15&str1=fizz&str2=buzz"
+		}
+		recs := serveConcurrently(h, targets)
+		close(release)
+		synctest.Wait()
+
+		if got := calls.Load(); got != 1 {
+			t.Fatalf("expected 1 generation for %d identical requests, got %d", requests, got)
+		}
+		for i, rec := range recs {
+			if rec.Code != http.StatusOK {
+				t.Fatalf("request %d: expected status %d, got %d", i, http.StatusOK, rec.Code)
+			}
+			if rec.Body.String() != recs[0].Body.String() {
+				t.Fatalf("request %d: body %s differs from %s", i, rec.Body.String(), recs[0].Body.String())
+			}
+		}
+	})
+}
+
+func TestHandler_FizzBuzz_CoalescingKeepsDistinctRequestsApart(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var calls atomic.Int32
+		release := make(chan struct{})
+		h := NewHandler(statistics.NewStore(), nil)
+		h.generator = blockingGenerator(&calls, release)
+
+		recs := serveConcurrently(h, []string{
+			"/fizzbuzz?int1=3&int2=5&limit=5&str1=fizz&str2=buzz",
+			"/fizzbuzz?int1=3&int2=5&limit=5&str1=fizz&str2=buzz&base=2",
+			"/fizzbuzz?int1=3&int2=5&limit=5&str1=fizz&str2=buzz&shuffle=true&seed=1",
+		})
+		close(release)
+		synctest.Wait()
+
+		// The shuffled request shares the plain one's generation but must
+		// shuffle a copy, leaving the plain response in order.
+		if got := calls.Load(); got != 2 {
+			t.Fatalf("expected 2 generations, got %d", got)
+		}
+		assertJSONResponse(t, recs[0].Body.Bytes(), FizzBuzzResponse{Result: []string{"1", "2", "fizz", "4", "buzz"}})
+		assertJSONResponse(t, recs[1].Body.Bytes(), FizzBuzzResponse{Result: []string{"1", "10", "fizz", "100", "buzz"}})
+	})
+}
+
+func TestHandler_FizzBuzz_GenerationsAfterCompletionAreNotShared(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	close(release)
+	h := NewHandler(statistics.NewStore(), nil)
+	h.generator = blockingGenerator(&calls, release)
+
+	for range 3 {
+		rec := httptest.NewRecorder()
+		h.FizzBuzz(rec, httptest.NewRequest(http.MethodGet, "/fizzbuzz?int1=3&int2=5&limit=5&str1=fizz&str2=buzz", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+	}
+	if got := calls.Load(); got != 3 {
+		t.Fatalf("expected 3 sequential generations, got %d", got)
+	}
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/statistics"
+)
+
+// maxSnapshots bounds how many /statistics/diff snapshots are kept. Once a
+// snapshot is evicted its id is no longer accepted as since.
+const maxSnapshots = 16
+
+// snapshotLog holds recent statistics snapshots by id, oldest first. The
+// zero value is ready to use.
+type snapshotLog struct {
+	mu     sync.Mutex
+	lastID uint64
+	ids    []string
+	byID   map[string]map[statistics.RequestParams]int
+}
+
+// add stores counts and returns the id it was assigned.
+func (l *snapshotLog) add(counts map[statistics.RequestParams]int) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.byID == nil {
+		l.byID = make(map[string]map[statistics.RequestParams]int)
+	}
+	l.lastID++
+	id := strconv.FormatUint(l.lastID, 10)
+	l.ids = append(l.ids, id)
+	l.byID[id] = counts
+
+	if len(l.ids) > maxSnapshots {
+		delete(l.byID, l.ids[0])
+		l.ids = l.ids[1:]
+	}
+	return id
+}
+
+func (l *snapshotLog) get(id string) (map[statistics.RequestParams]int, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	counts, ok := l.byID[id]
+	return counts, ok
+}
+
+// DiffResponse represents the payload returned by the diff endpoint. ID
+// names the snapshot taken by this request, to pass as since next time.
+type DiffResponse struct {
+	ID     string `json:"id"`
+	Since  string `json:"since,omitempty"`
+	Deltas []any  `json:"deltas"`
+}
+
+// DiffStatistics snapshots the statistics and, given since, the id of an
+// earlier snapshot, returns how many hits each parameter set gained since
+// then, largest first. Without since the deltas are empty, so a dashboard
+// polling with the previous id gets the rate of change between polls.
+func (h *Handler) DiffStatistics(w http.ResponseWriter, r *http.Request) {
+	since := r.URL.Query().Get("since")
+
+	var previous map[statistics.RequestParams]int
+	if since != "" {
+		var ok bool
+		if previous, ok = h.snapshots.get(since); !ok {
+			h.respondValidationError(w, r, newParamError("since", "is not a known snapshot id"))
+			return
+		}
+	}
+
+	current := map[statistics.RequestParams]int{}
+	if h.store != nil {
+		current = h.store.Clone()
+	}
+	response := DiffResponse{ID: h.snapshots.add(current), Since: since, Deltas: []any{}}
+
+	if since != "" {
+		var deltas []statistics.Stats
+		for params, hits := range statistics.Diff(current, previous) {
+			deltas = append(deltas, statistics.Stats{Params: params, Hits: hits})
+		}
+		slices.SortFunc(deltas, func(a, b statistics.Stats) int {
+			return cmp.Or(cmp.Compare(b.Hits, a.Hits), statistics.CompareParams(a.Params, b.Params))
+		})
+		for i := range deltas {
+			response.Deltas = append(response.Deltas, h.statisticsPayload(r, &deltas[i]))
+		}
+	}
+
+	h.respondJSON(w, r, http.StatusOK, response)
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/statistics"
+)
+
+// diffBody is DiffResponse with deltas decoded in the default shape.
+type diffBody struct {
+	ID     string               `json:"id"`
+	Since  string               `json:"since"`
+	Deltas []StatisticsResponse `json:"deltas"`
+}
+
+func callDiff(t *testing.T, h *Handler, query string) (*httptest.ResponseRecorder, diffBody) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, "/statistics/diff"+query, nil)
+	rec := httptest.NewRecorder()
+	h.DiffStatistics(rec, req)
+
+	var body diffBody
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("failed to decode body: %v", err)
+		}
+	}
+	return rec, body
+}
+
+func TestHandler_DiffStatistics(t *testing.T) {
+	store := statistics.NewStore()
+	fizz := statistics.RequestParams{Int1: 3, Int2: 5, Limit: 15, Str1: "fizz", Str2: "buzz"}
+	foo := statistics.RequestParams{Int1: 2, Int2: 7, Limit: 30, Str1: "foo", Str2: "bar"}
+	idle := statistics.RequestParams{Int1: 9, Int2: 9, Limit: 9, Str1: "x", Str2: "y"}
+	recordRequest(store, fizz, 5)
+	recordRequest(store, idle, 1)
+
+	h := NewHandler(store, nil)
+
+	rec, first := callDiff(t, h, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if first.ID == "" || len(first.Deltas) != 0 {
+		t.Fatalf("expected an id and no deltas without since, got %+v", first)
+	}
+
+	recordRequest(store, fizz, 1)
+	recordRequest(store, foo, 3)
+
+	rec, second := callDiff(t, h, "?since="+first.ID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if second.ID == first.ID || second.Since != first.ID {
+		t.Fatalf("expected a new id since %s, got id %s since %s", first.ID, second.ID, second.Since)
+	}
+	want := []StatisticsResponse{
+		{Params: StatisticsParams{Int1: 2, Int2: 7, Limit: 30, Str1: "foo", Str2: "bar"}, Hits: 3},
+		{Params: StatisticsParams{Int1: 3, Int2: 5, Limit: 15, Str1: "fizz", Str2: "buzz"}, Hits: 1},
+	}
+	if !reflect.DeepEqual(second.Deltas, want) {
+		t.Fatalf("expected deltas %+v, got %+v", want, second.Deltas)
+	}
+
+	_, third := callDiff(t, h, "?since="+second.ID)
+	if len(third.Deltas) != 0 {
+		t.Fatalf("expected no deltas without new requests, got %+v", third.Deltas)
+	}
+}
+
+func TestHandler_DiffStatistics_UnknownSnapshot(t *testing.T) {
+	h := NewHandler(statistics.NewStore(), nil)
+
+	_, first := callDiff(t, h, "")
+	for range maxSnapshots {
+		callDiff(t, h, "")
+	}
+
+	for _, since := range []string{"nope", first.ID} {
+		rec, _ := callDiff(t, h, "?since="+since)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("since=%s: expected status %d, got %d", since, http.StatusBadRequest, rec.Code)
+		}
+		assertErrorResponse(t, rec.Body.Bytes(), "since is not a known snapshot id")
+	}
+}
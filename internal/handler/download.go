@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// downloadParams is the download value that names the file after the
+// request instead of the fixed default.
+const downloadParams = "params"
+
+// respondDownload writes result as a plain-text attachment, one value per
+// line. There is no body field to carry truncation, so a capped response is
+// flagged with X-Truncated instead.
+func (h *Handler) respondDownload(w http.ResponseWriter, r *http.Request, params fizzBuzzParams, result []string, truncated bool) {
+	var body strings.Builder
+	for _, value := range result {
+		body.WriteString(value)
+		body.WriteByte('\n')
+	}
+
+	payload := []byte(body.String())
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", downloadFilename(params)))
+	w.Header().Set("Content-Length", strconv.Itoa(body.Len()))
+	setChecksum(w, payload)
+	if truncated {
+		w.Header().Set("X-Truncated", "true")
+	}
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+	if _, err := w.Write(payload); err != nil {
+		h.logger.Error("download write error", slog.String("error", err.Error()))
+		h.writeErrors.Inc()
+	}
+}
+
+// downloadFilename is fizzbuzz.txt, or with download=params the numeric
+// parameters, e.g. fizzbuzz-3-5-100.txt. The words are left out since they
+// can hold anything, including characters a filename cannot.
+func downloadFilename(params fizzBuzzParams) string {
+	if !params.downloadNamed {
+		return "fizzbuzz.txt"
+	}
+	name := fmt.Sprintf("fizzbuzz-%d-%d-%d", params.int1, params.int2, params.limit)
+	if params.start != 1 {
+		name += fmt.Sprintf("-from%d", params.start)
+	}
+	return name + ".txt"
+}
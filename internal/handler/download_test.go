@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/statistics"
+)
+
+func TestHandler_FizzBuzz_Download(t *testing.T) {
+	tests := []struct {
+		name                string
+		method              string
+		queryParams         string
+		opts                []Option
+		expectedDisposition string
+		expectedBody        string
+		expectedTruncated   string
+	}{
+		{
+			name:                "default filename",
+			method:              http.MethodGet,
+			queryParams:         "int1=3&int2=5&limit=5&str1=fizz&str2=buzz&download=true",
+			expectedDisposition: `attachment; filename="fizzbuzz.txt"`,
+			expectedBody:        "1\n2\nfizz\n4\nbuzz\n",
+		},
+		{
+			name:                "filename from params",
+			method:              http.MethodGet,
+			queryParams:         "int1=3&int2=5&limit=3&str1=fizz&str2=buzz&download=params",
+			expectedDisposition: `attachment; filename="fizzbuzz-3-5-3.txt"`,
+			expectedBody:        "1\n2\nfizz\n",
+		},
+		{
+			name:                "filename from params with start",
+			method:              http.MethodGet,
+			queryParams:         "int1=3&int2=5&limit=2&str1=fizz&str2=buzz&start=-1&download=params",
+			expectedDisposition: `attachment; filename="fizzbuzz-3-5-2-from-1.txt"`,
+			expectedBody:        "-1\nfizzbuzz\n",
+		},
+		{
+			name:                "truncated",
+			method:              http.MethodGet,
+			queryParams:         "int1=3&int2=5&limit=50&str1=fizz&str2=buzz&download=true",
+			opts:                []Option{WithMaxLimit(3, true)},
+			expectedDisposition: `attachment; filename="fizzbuzz.txt"`,
+			expectedBody:        "1\n2\nfizz\n",
+			expectedTruncated:   "true",
+		},
+		{
+			name:                "head",
+			method:              http.MethodHead,
+			queryParams:         "int1=3&int2=5&limit=5&str1=fizz&str2=buzz&download=true",
+			expectedDisposition: `attachment; filename="fizzbuzz.txt"`,
+			expectedBody:        "",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(statistics.NewStore(), nil, tc.opts...)
+
+			rec := httptest.NewRecorder()
+			h.FizzBuzz(rec, httptest.NewRequest(tc.method, "/fizzbuzz?"+tc.queryParams, nil))
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+			}
+			if got := rec.Header().Get("Content-Type"); got != "text/plain; charset=utf-8" {
+				t.Errorf("expected Content-Type text/plain; charset=utf-8, got %s", got)
+			}
+			if got := rec.Header().Get("Content-Disposition"); got != tc.expectedDisposition {
+				t.Errorf("expected Content-Disposition %s, got %s", tc.expectedDisposition, got)
+			}
+			if got := rec.Header().Get("X-Truncated"); got != tc.expectedTruncated {
+				t.Errorf("expected X-Truncated %q, got %q", tc.expectedTruncated, got)
+			}
+			if got := rec.Body.String(); got != tc.expectedBody {
+				t.Errorf("expected body %q, got %q", tc.expectedBody, got)
+			}
+		})
+	}
+}
+
+func TestHandler_FizzBuzz_DownloadValidation(t *testing.T) {
+	tests := []struct {
+		name          string
+		queryParams   string
+		expectedError string
+	}{
+		{
+			name:          "invalid download",
+			queryParams:   "int1=3&int2=5&limit=15&str1=fizz&str2=buzz&download=file",
+			expectedError: "download must be a boolean or params",
+		},
+		{
+			name:          "download with only",
+			queryParams:   "int1=3&int2=5&limit=15&str1=fizz&str2=buzz&download=true&only=str1",
+			expectedError: "download cannot be combined with only",
+		},
+		{
+			name:          "download with stream",
+			queryParams:   "int1=3&int2=5&limit=15&str1=fizz&str2=buzz&download=true&stream=true",
+			expectedError: "download cannot be combined with stream",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(statistics.NewStore(), nil)
+
+			rec := httptest.NewRecorder()
+			h.FizzBuzz(rec, httptest.NewRequest(http.MethodGet, "/fizzbuzz?"+tc.queryParams, nil))
+
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+			}
+			assertErrorResponse(t, rec.Body.Bytes(), tc.expectedError)
+		})
+	}
+}
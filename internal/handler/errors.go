@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Sentinel errors classify request validation failures, so callers can
+// branch with errors.Is instead of matching messages.
+var (
+	// ErrMissingParams reports required parameters absent from the request.
+	ErrMissingParams = errors.New("missing required parameters")
+	// ErrInvalidInt reports a parameter that is not a valid integer.
+	ErrInvalidInt = errors.New("invalid integer")
+	// ErrNonPositive reports an integer that must be greater than zero.
+	ErrNonPositive = errors.New("integer must be greater than zero")
+	// ErrOutOfRange reports a value that parses but falls outside its
+	// allowed range, such as a limit above the maximum.
+	ErrOutOfRange = errors.New("value out of range")
+	// ErrEmptyStr reports a string parameter that must not be empty.
+	ErrEmptyStr = errors.New("empty string")
+	// ErrInvalidParam reports any other rejected parameter.
+	ErrInvalidParam = errors.New("invalid parameter")
+)
+
+// errorStatuses maps each sentinel to the status it is reported with.
+var errorStatuses = map[error]int{
+	ErrMissingParams: http.StatusBadRequest,
+	ErrInvalidInt:    http.StatusBadRequest,
+	ErrNonPositive:   http.StatusBadRequest,
+	ErrOutOfRange:    http.StatusBadRequest,
+	ErrEmptyStr:      http.StatusBadRequest,
+	ErrInvalidParam:  http.StatusBadRequest,
+}
+
+// statusOf returns the status for a validation error, 400 unless its
+// sentinel maps elsewhere.
+func statusOf(err error) int {
+	for sentinel, status := range errorStatuses {
+		if errors.Is(err, sentinel) {
+			return status
+		}
+	}
+	return http.StatusBadRequest
+}
+
+// semanticErrors are the sentinels for well-formed values the handler cannot
+// accept, reported with the handler's semantic status when one is set.
+var semanticErrors = []error{ErrNonPositive, ErrOutOfRange}
+
+// validationStatus is statusOf, except that semantic failures get
+// h.semanticStatus when it is set.
+func (h *Handler) validationStatus(err error) int {
+	if h != nil && h.semanticStatus != 0 {
+		for _, sentinel := range semanticErrors {
+			if errors.Is(err, sentinel) {
+				return h.semanticStatus
+			}
+		}
+	}
+	return statusOf(err)
+}
+
+// InvalidParam identifies a rejected query parameter and why it was rejected.
+type InvalidParam struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// validationError is returned by request parsing. Its message is the
+// client-facing error text; params lists the offending parameters and kind
+// is the sentinel it matches with errors.Is.
+type validationError struct {
+	kind    error
+	message string
+	params  []InvalidParam
+}
+
+func (e *validationError) Error() string {
+	return e.message
+}
+
+func (e *validationError) Unwrap() error {
+	return e.kind
+}
+
+func newParamError(name, reason string) *validationError {
+	return newKindError(ErrInvalidParam, name, reason)
+}
+
+func newKindError(kind error, name, reason string) *validationError {
+	return &validationError{
+		kind:    kind,
+		message: name + " " + reason,
+		params:  []InvalidParam{{Name: name, Reason: reason}},
+	}
+}
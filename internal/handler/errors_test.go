@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/statistics"
+)
+
+func TestParseFizzBuzzParams_ErrorKinds(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  error
+	}{
+		{"missing params", "int1=3&int2=5", ErrMissingParams},
+		{"invalid int", "int1=three&int2=5&limit=15&str1=fizz&str2=buzz", ErrInvalidInt},
+		{"invalid start", "int1=3&int2=5&limit=15&str1=fizz&str2=buzz&start=x", ErrInvalidInt},
+		{"zero divisor", "int1=0&int2=5&limit=15&str1=fizz&str2=buzz", ErrNonPositive},
+		{"negative limit", "int1=3&int2=5&limit=-1&str1=fizz&str2=buzz", ErrNonPositive},
+		{"empty string", "int1=3&int2=5&limit=15&str1=&str2=buzz", ErrEmptyStr},
+		{"base out of range", "int1=3&int2=5&limit=15&str1=fizz&str2=buzz&base=40", ErrOutOfRange},
+		{"other parameter", "int1=3&int2=5&limit=15&str1=fizz&str2=buzz&only=odd", ErrInvalidParam},
+	}
+
+	sentinels := []error{ErrMissingParams, ErrInvalidInt, ErrNonPositive, ErrOutOfRange, ErrEmptyStr, ErrInvalidParam}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tc.query)
+			if err != nil {
+				t.Fatalf("bad test query: %v", err)
+			}
+
+			_, err = parseFizzBuzzParams(values)
+			for _, sentinel := range sentinels {
+				if got, want := errors.Is(err, sentinel), sentinel == tc.want; got != want {
+					t.Fatalf("errors.Is(%v, %v) = %t, want %t", err, sentinel, got, want)
+				}
+			}
+			if status := statusOf(err); status != http.StatusBadRequest {
+				t.Fatalf("statusOf(%v) = %d, want %d", err, status, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+func TestHandler_SemanticErrorStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		semantic int
+		want     int
+	}{
+		{"non-positive by default", "int1=0&int2=5&limit=15&str1=fizz&str2=buzz", 0, http.StatusBadRequest},
+		{"above max limit by default", "int1=3&int2=5&limit=101&str1=fizz&str2=buzz", 0, http.StatusBadRequest},
+		{"non-positive as 422", "int1=0&int2=5&limit=15&str1=fizz&str2=buzz", http.StatusUnprocessableEntity, http.StatusUnprocessableEntity},
+		{"above max limit as 422", "int1=3&int2=5&limit=101&str1=fizz&str2=buzz", http.StatusUnprocessableEntity, http.StatusUnprocessableEntity},
+		{"base out of range as 422", "int1=3&int2=5&limit=15&str1=fizz&str2=buzz&base=1", http.StatusUnprocessableEntity, http.StatusUnprocessableEntity},
+		{"malformed integer stays 400", "int1=abc&int2=5&limit=15&str1=fizz&str2=buzz", http.StatusUnprocessableEntity, http.StatusBadRequest},
+		{"missing params stay 400", "int1=3", http.StatusUnprocessableEntity, http.StatusBadRequest},
+		{"malformed flag stays 400", "int1=3&int2=5&limit=15&str1=fizz&str2=buzz&numeric=maybe", http.StatusUnprocessableEntity, http.StatusBadRequest},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(statistics.NewStore(), nil, WithMaxLimit(100, false), WithSemanticErrorStatus(tc.semantic))
+
+			for _, accept := range []string{"", problemContentType} {
+				req := httptest.NewRequest(http.MethodGet, "/fizzbuzz?"+tc.query, nil)
+				if accept != "" {
+					req.Header.Set("Accept", accept)
+				}
+				rec := httptest.NewRecorder()
+				h.FizzBuzz(rec, req)
+
+				if rec.Code != tc.want {
+					t.Fatalf("Accept %q: expected status %d, got %d", accept, tc.want, rec.Code)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/statistics"
+)
+
+// statisticsTick is one reading of the most frequent request; ok is false
+// while nothing has been recorded.
+type statisticsTick struct {
+	stats statistics.Stats
+	ok    bool
+}
+
+// statisticsFeed reads the most frequent request once per interval and fans
+// the reading out to every /statistics/stream subscriber, so the store is
+// read once per tick however many clients are connected. The reading
+// goroutine runs only while someone is subscribed. The zero value is ready
+// to use.
+type statisticsFeed struct {
+	mu          sync.Mutex
+	subscribers map[chan statisticsTick]struct{}
+	stop        chan struct{}
+}
+
+// subscribe returns a channel receiving every tick from now on and a
+// function that ends the subscription. The first subscriber starts a
+// goroutine calling read every interval; the last one to leave stops it. A
+// subscriber that falls behind only ever has the latest tick waiting.
+func (f *statisticsFeed) subscribe(interval time.Duration, read func() statisticsTick) (<-chan statisticsTick, func()) {
+	ch := make(chan statisticsTick, 1)
+
+	f.mu.Lock()
+	if f.subscribers == nil {
+		f.subscribers = make(map[chan statisticsTick]struct{})
+	}
+	f.subscribers[ch] = struct{}{}
+	if len(f.subscribers) == 1 {
+		f.stop = make(chan struct{})
+		go f.run(interval, read, f.stop)
+	}
+	f.mu.Unlock()
+
+	return ch, func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		delete(f.subscribers, ch)
+		if len(f.subscribers) == 0 {
+			close(f.stop)
+		}
+	}
+}
+
+func (f *statisticsFeed) run(interval time.Duration, read func() statisticsTick, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			f.publish(read())
+		}
+	}
+}
+
+// publish hands tick to every subscriber, replacing any tick still waiting.
+func (f *statisticsFeed) publish(tick statisticsTick) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for ch := range f.subscribers {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- tick
+	}
+}
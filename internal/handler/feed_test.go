@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/statistics"
+)
+
+func TestStatisticsFeed_FansOutOneReadPerTick(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const subscribers = 5
+		var feed statisticsFeed
+		var reads atomic.Int32
+		read := func() statisticsTick {
+			return statisticsTick{stats: statistics.Stats{Hits: int(reads.Add(1))}, ok: true}
+		}
+
+		channels := make([]<-chan statisticsTick, subscribers)
+		unsubscribes := make([]func(), subscribers)
+		for i := range subscribers {
+			channels[i], unsubscribes[i] = feed.subscribe(time.Second, read)
+		}
+
+		for want := 1; want <= 2; want++ {
+			time.Sleep(time.Second)
+			synctest.Wait()
+
+			if got := int(reads.Load()); got != want {
+				t.Fatalf("expected %d reads for %d subscribers, got %d", want, subscribers, got)
+			}
+			for i, ch := range channels {
+				if tick := <-ch; tick.stats.Hits != want {
+					t.Fatalf("subscriber %d: expected tick %d, got %d", i, want, tick.stats.Hits)
+				}
+			}
+		}
+
+		// The reader stops with the last subscriber, or synctest.Test
+		// reports it as leaked.
+		for _, unsubscribe := range unsubscribes {
+			unsubscribe()
+		}
+	})
+}
+
+func TestStatisticsFeed_KeepsOnlyTheLatestTick(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var feed statisticsFeed
+		var reads atomic.Int32
+		ticks, unsubscribe := feed.subscribe(time.Second, func() statisticsTick {
+			return statisticsTick{stats: statistics.Stats{Hits: int(reads.Add(1))}, ok: true}
+		})
+		defer unsubscribe()
+
+		time.Sleep(3 * time.Second)
+		synctest.Wait()
+
+		if tick := <-ticks; tick.stats.Hits != 3 {
+			t.Fatalf("expected the latest tick 3, got %d", tick.stats.Hits)
+		}
+		select {
+		case tick := <-ticks:
+			t.Fatalf("expected no stale tick, got %d", tick.stats.Hits)
+		default:
+		}
+	})
+}
+
+func TestHandler_StreamStatistics_SubscribersShareTicks(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		params := statistics.RequestParams{Int1: 3, Int2: 5, Limit: 15, Str1: "fizz", Str2: "buzz"}
+		store := statistics.NewStore()
+		store.Record(params)
+		h := NewHandler(store, nil, WithStatisticsInterval(time.Second))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		recs := make([]*httptest.ResponseRecorder, 3)
+		for i := range recs {
+			recs[i] = httptest.NewRecorder()
+			go h.StreamStatistics(recs[i], httptest.NewRequestWithContext(ctx, http.MethodGet, "/statistics/stream", nil))
+		}
+		synctest.Wait()
+
+		store.Record(params)
+		time.Sleep(time.Second)
+		synctest.Wait()
+		cancel()
+		synctest.Wait()
+
+		want := "event: statistics\ndata: " + `{"params":{"int1":3,"int2":5,"limit":15,"str1":"fizz","str2":"buzz"},"hits":1}` + "\n\n" +
+			"event: statistics\ndata: " + `{"params":{"int1":3,"int2":5,"limit":15,"str1":"fizz","str2":"buzz"},"hits":2}` + "\n\n"
+		for i, rec := range recs {
+			if got := rec.Body.String(); got != want {
+				t.Fatalf("subscriber %d: expected %q, got %q", i, want, got)
+			}
+		}
+	})
+}
@@ -2,138 +2,902 @@ package handler
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
 	"log/slog"
+	"math"
+	"math/rand/v2"
 	"net/http"
 	"net/url"
+	"slices"
 	"strconv"
+	"sync/atomic"
+	"time"
 
 	"github.com/Cerebrovinny/fizz-buzz-rest/internal/fizzbuzz"
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/metrics"
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/query"
 	"github.com/Cerebrovinny/fizz-buzz-rest/internal/statistics"
 )
 
 type Handler struct {
-	store  *statistics.Store
-	logger *slog.Logger
+	store     *statistics.Store
+	logger    *slog.Logger
+	limits    atomic.Pointer[limitSettings]
+	checkers  []HealthChecker
+	unhealthy atomic.Bool
+	shape     string
+
+	// generations bounds concurrent generations of at least heavyLimit
+	// items; nil means unbounded.
+	generations chan struct{}
+	heavyLimit  int
+
+	maxResponseBytes int64
+
+	// minDivisor and maxDivisor bound int1 and int2; maxDivisor 0 is unbounded.
+	minDivisor int64
+	maxDivisor int64
+
+	// allowedStrings restricts str1 and str2 when non-nil.
+	allowedStrings map[string]struct{}
+	// strictIntegers rejects integer parameters with leading zeros.
+	strictIntegers bool
+	// semanticStatus, when non-zero, replaces 400 for values that parse
+	// but fall outside their allowed range.
+	semanticStatus int
+	// usageHint adds an example request to the error for a bare /fizzbuzz.
+	usageHint bool
+
+	streamWriteTimeout time.Duration
+	statisticsInterval time.Duration
+	// statisticsFeed shares one store read per interval among all
+	// /statistics/stream clients.
+	statisticsFeed statisticsFeed
+
+	// maxTopN caps the /statistics/top page size; zero means
+	// defaultMaxTopN.
+	maxTopN int
+	// maxRules caps the rule=divisor:word pairs per request; zero means
+	// defaultMaxRules.
+	maxRules int
+
+	// snapshots backs the since ids accepted by /statistics/diff.
+	snapshots snapshotLog
+
+	// inflight coalesces identical concurrent generations of generator.
+	inflight  generationGroup
+	generator Generator
+
+	writeErrors *metrics.Counter
+}
+
+// Option configures optional Handler behavior.
+type Option func(*Handler)
+
+// WithMaxLimit caps the accepted limit. Requests above the cap are rejected
+// with 400, or generated up to the cap when truncate is true. A maxLimit of 0
+// leaves the limit unbounded.
+func WithMaxLimit(maxLimit int, truncate bool) Option {
+	return func(h *Handler) {
+		h.SetMaxLimit(maxLimit, truncate)
+	}
+}
+
+// limitSettings is swapped as a unit so requests never see a cap from one
+// configuration paired with the truncate mode of another.
+type limitSettings struct {
+	maxLimit int
+	truncate bool
+}
+
+// SetMaxLimit replaces the limit cap at runtime, as WithMaxLimit does at
+// construction. It is safe to call while requests are being served.
+func (h *Handler) SetMaxLimit(maxLimit int, truncate bool) {
+	h.limits.Store(&limitSettings{maxLimit: maxLimit, truncate: truncate})
+}
+
+// WithForceUnhealthy makes /health and /ready report 503 from startup.
+func WithForceUnhealthy(unhealthy bool) Option {
+	return func(h *Handler) {
+		h.unhealthy.Store(unhealthy)
+	}
+}
+
+// WithResponseShape sets the default response shape, ShapeNested or
+// ShapeFlat. Clients can still override it with an Accept profile.
+func WithResponseShape(shape string) Option {
+	return func(h *Handler) {
+		h.shape = shape
+	}
+}
+
+// WithConcurrencyLimit allows at most maxConcurrent generations with a limit
+// of heavyLimit or more to run at once. Further heavy requests receive 503
+// with Retry-After; smaller requests are never queued. maxConcurrent <= 0
+// disables the cap.
+func WithConcurrencyLimit(maxConcurrent, heavyLimit int) Option {
+	return func(h *Handler) {
+		if maxConcurrent <= 0 {
+			h.generations = nil
+			return
+		}
+		h.generations = make(chan struct{}, maxConcurrent)
+		h.heavyLimit = heavyLimit
+	}
+}
+
+// WithMaxResponseBytes rejects requests whose estimated output,
+// limit * max(len(str1), len(str2)), exceeds maxBytes. Zero disables the check.
+func WithMaxResponseBytes(maxBytes int) Option {
+	return func(h *Handler) {
+		h.maxResponseBytes = int64(maxBytes)
+	}
 }
 
-func NewHandler(store *statistics.Store, logger *slog.Logger) *Handler {
-	return &Handler{
+// WithDivisorRange rejects int1 or int2 values outside [minDivisor,
+// maxDivisor]. A maxDivisor of zero leaves the upper end unbounded.
+func WithDivisorRange(minDivisor, maxDivisor int64) Option {
+	return func(h *Handler) {
+		h.minDivisor = minDivisor
+		h.maxDivisor = maxDivisor
+	}
+}
+
+// WithAllowedStrings rejects str1 or str2 values not in allowed. An empty
+// list allows any value.
+func WithAllowedStrings(allowed []string) Option {
+	return func(h *Handler) {
+		if len(allowed) == 0 {
+			h.allowedStrings = nil
+			return
+		}
+		h.allowedStrings = make(map[string]struct{}, len(allowed))
+		for _, value := range allowed {
+			h.allowedStrings[value] = struct{}{}
+		}
+	}
+}
+
+// WithStrictIntegers rejects /fizzbuzz integer parameters written with
+// leading zeros, such as limit=015, instead of reading them as decimal, and
+// flags requests in which no value would be replaced with an
+// X-FizzBuzz-Warning header.
+func WithStrictIntegers(strict bool) Option {
+	return func(h *Handler) {
+		h.strictIntegers = strict
+	}
+}
+
+// WithSemanticErrorStatus reports values that parse but fall outside their
+// allowed range, such as int1=0 or a limit above the maximum, with status
+// instead of 400. Malformed values are still 400. Zero or 400 keeps 400
+// everywhere.
+func WithSemanticErrorStatus(status int) Option {
+	return func(h *Handler) {
+		h.semanticStatus = status
+	}
+}
+
+// WithUsageHint answers a /fizzbuzz request with no parameters with the
+// usual missing-parameters error plus an example request, for people
+// trying the endpoint by hand.
+func WithUsageHint(enabled bool) Option {
+	return func(h *Handler) {
+		h.usageHint = enabled
+	}
+}
+
+// WithMetrics registers the handler's counters on registry.
+func WithMetrics(registry *metrics.Registry) Option {
+	return func(h *Handler) {
+		h.writeErrors = registry.Counter("response_write_errors_total", "Responses that failed to write to the client.")
+	}
+}
+
+// NewHandler returns a Handler serving from store. A nil logger discards
+// everything, so handlers never need to check for one.
+func NewHandler(store *statistics.Store, logger *slog.Logger, opts ...Option) *Handler {
+	if logger == nil {
+		logger = discardLogger
+	}
+	h := &Handler{
 		store:  store,
 		logger: logger,
 	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if h.generator == nil {
+		h.generator = DefaultGenerator
+	}
+	return h
 }
 
 type FizzBuzzResponse struct {
-	Result []string `json:"result"`
+	Params    *StatisticsParams `json:"params,omitempty"`
+	Result    []string          `json:"result"`
+	Truncated bool              `json:"truncated,omitempty"`
+	Returned  int               `json:"returned,omitempty"`
+}
+
+// MapFizzBuzzResponse is returned for format=map: the sequence as a JSON
+// object keyed by number. JSON objects are unordered, so clients must not
+// rely on the order of its keys.
+type MapFizzBuzzResponse struct {
+	Params    *StatisticsParams `json:"params,omitempty"`
+	Result    map[int64]string  `json:"result"`
+	Truncated bool              `json:"truncated,omitempty"`
+	Returned  int               `json:"returned,omitempty"`
+}
+
+// NumericFizzBuzzResponse is returned for numeric=true: plain numbers are
+// JSON numbers and only replacement words are strings.
+type NumericFizzBuzzResponse struct {
+	Params    *StatisticsParams `json:"params,omitempty"`
+	Result    NumericResult     `json:"result"`
+	Truncated bool              `json:"truncated,omitempty"`
+	Returned  int               `json:"returned,omitempty"`
+}
+
+// NumericResult is a FizzBuzz sequence that marshals the values at the
+// positions flagged in Numbers unquoted.
+type NumericResult struct {
+	Values  []string
+	Numbers []bool
+}
+
+// MarshalJSON renders the mixed array, e.g. [1,2,"fizz"]. Numbers is derived
+// from the divisors, not from what the Generator returned, so a flagged value
+// is only written unquoted when it is a canonical decimal integer; anything
+// else is quoted, keeping the output valid JSON whatever the Generator does.
+func (n NumericResult) MarshalJSON() ([]byte, error) {
+	buf := make([]byte, 0, 8*len(n.Values)+2)
+	buf = append(buf, '[')
+	for i, value := range n.Values {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		if i < len(n.Numbers) && n.Numbers[i] {
+			if number, err := strconv.ParseInt(value, 10, 64); err == nil {
+				mark := len(buf)
+				if buf = strconv.AppendInt(buf, number, 10); string(buf[mark:]) == value {
+					continue
+				}
+				buf = buf[:mark]
+			}
+		}
+		quoted, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, quoted...)
+	}
+	return append(buf, ']'), nil
+}
+
+// IndicesResponse lists the 1-based positions matching the requested category.
+type IndicesResponse struct {
+	Params  *StatisticsParams `json:"params,omitempty"`
+	Indices []int             `json:"indices"`
 }
 
 type ErrorResponse struct {
 	Error string `json:"error"`
+	// Code identifies errors clients may want to handle specially, such as
+	// "generation_panic"; most errors leave it out.
+	Code string `json:"code,omitempty"`
 }
 
 type fizzBuzzParams struct {
-	int1  int
-	int2  int
+	int1  int64
+	int2  int64
+	start int64
 	limit int
 	str1  string
 	str2  string
+	str3  string
+	only  *fizzbuzz.Category
+
+	templated bool
+	numeric   bool
+	shuffle   bool
+	seed      int64
+	stream    bool
+	base      int
+	rule      fizzbuzz.Rule
+	collapse  bool
+
+	download      bool
+	downloadNamed bool
+
+	echoParams bool
+	asMap      bool
 }
 
-func respondJSON(logger *slog.Logger, w http.ResponseWriter, status int, data interface{}) {
-	payload, err := json.Marshal(data)
-	if err != nil {
-		if logger != nil {
-			logger.Error("json marshal error", slog.String("error", err.Error()))
-		}
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-		return
+// options returns the generation options params asks for: templating, base,
+// rule, collapsing and the word for values divisible by both.
+func (p fizzBuzzParams) options() fizzbuzz.Options {
+	return fizzbuzz.Options{Templated: p.templated, Base: p.base, Rule: p.rule, CollapseEqual: p.collapse, Both: p.str3}
+}
+
+// generation returns the generation of count values from start that params
+// asks for.
+func (p fizzBuzzParams) generation(start int64, count int) GenerationParams {
+	return GenerationParams{
+		Int1: p.int1, Int2: p.int2, Start: start, Count: count,
+		Str1: p.str1, Str2: p.str2, Options: p.options(),
 	}
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	if _, err := w.Write(payload); err != nil {
-		if logger != nil {
-			logger.Error("json response write error", slog.String("error", err.Error()))
-		}
+// echoed returns the parameters to include in the response for
+// echo_params=true, or nil to leave them out.
+func (p fizzBuzzParams) echoed() *StatisticsParams {
+	if !p.echoParams {
+		return nil
 	}
+	return &StatisticsParams{Int1: p.int1, Int2: p.int2, Limit: p.limit, Str1: p.str1, Str2: p.str2}
+}
+
+// retryAfterBusy is the Retry-After value, in seconds, sent when the
+// generation concurrency limit is saturated.
+const retryAfterBusy = "1"
+
+// warningHeader carries advisories about requests that succeed but are
+// probably not what the client meant.
+const warningHeader = "X-FizzBuzz-Warning"
+
+var rules = map[string]fizzbuzz.Rule{
+	"divisible": fizzbuzz.RuleDivisible,
+	"digitsum":  fizzbuzz.RuleDigitSum,
 }
 
-func respondError(logger *slog.Logger, w http.ResponseWriter, status int, message string) {
-	respondJSON(logger, w, status, ErrorResponse{Error: message})
+var onlyCategories = map[string]fizzbuzz.Category{
+	"str1":    fizzbuzz.CategoryStr1,
+	"str2":    fizzbuzz.CategoryStr2,
+	"both":    fizzbuzz.CategoryBoth,
+	"numbers": fizzbuzz.CategoryNumber,
 }
 
 func (h *Handler) FizzBuzz(w http.ResponseWriter, r *http.Request) {
+	if hasRulePairs(r.URL.Query()) {
+		h.fizzBuzzRules(w, r)
+		return
+	}
+
 	params, err := parseFizzBuzzParams(r.URL.Query())
 	if err != nil {
-		if h.logger != nil {
-			h.logger.Debug("validation error",
-				slog.String("error", err.Error()),
-				slog.String("path", r.URL.Path),
-			)
+		h.logger.Debug("validation error",
+			slog.String("error", err.Error()),
+			slog.String("path", r.URL.Path),
+		)
+		if h.usageHint && len(r.URL.Query()) == 0 && !wantsProblem(r) {
+			h.respondUsage(w, r, err)
+			return
 		}
-		respondError(h.logger, w, http.StatusBadRequest, err.Error())
+		h.respondValidationError(w, r, err)
+		return
+	}
+
+	if err := h.checkParams(params, r.URL.Query()); err != nil {
+		h.respondValidationError(w, r, err)
+		return
+	}
+
+	limit, truncated, err := h.capLimit(params.limit, max(len(params.str1), len(params.str2), len(params.str3)))
+	if err != nil {
+		h.respondValidationError(w, r, err)
+		return
+	}
+
+	if h.strictIntegers && !replacesAny(params, limit) {
+		w.Header().Set(warningHeader, "no replacements will occur")
+	}
+
+	release, ok := h.acquireGeneration(w, r, limit)
+	if !ok {
+		return
+	}
+	defer release()
+
+	if params.only != nil && *params.only == fizzbuzz.CategoryNumber {
+		h.respondNumbersOnly(w, r, params, limit)
+		return
+	}
+
+	if params.only != nil {
+		indices := fizzbuzz.IndicesWith(params.int1, params.int2, params.start, limit, *params.only, params.rule)
+		h.respondChecksummedJSON(w, r, http.StatusOK, IndicesResponse{Params: params.echoed(), Indices: indices})
+		return
+	}
+
+	if params.stream && r.Method != http.MethodHead {
+		h.streamFizzBuzz(w, r, params, limit, truncated)
 		return
 	}
 
-	result := fizzbuzz.Generate(params.int1, params.int2, params.limit, params.str1, params.str2)
+	generationStart := time.Now()
+	result, err := h.recoverGeneration(r, func() []string { return h.generateSequence(params, limit) })
+	if err != nil {
+		h.respondGenerationPanic(w, r)
+		return
+	}
+	setServerTiming(w, time.Since(generationStart))
+	if result == nil {
+		// An empty sequence is {"result":[]}; clients should never see null.
+		result = []string{}
+	}
 
-	respondJSON(h.logger, w, http.StatusOK, FizzBuzzResponse{Result: result})
+	var numbers []bool
+	if params.numeric {
+		numbers = make([]bool, len(result))
+		for _, index := range fizzbuzz.IndicesWith(params.int1, params.int2, params.start, limit, fizzbuzz.CategoryNumber, params.rule) {
+			numbers[index-1] = true
+		}
+	}
+
+	if params.shuffle {
+		// The generated sequence may be shared with identical requests, so
+		// shuffle a copy. Swapping the mask alongside keeps numeric=true
+		// aligned.
+		result = slices.Clone(result)
+		rng := rand.New(rand.NewPCG(uint64(params.seed), 0))
+		rng.Shuffle(len(result), func(i, j int) {
+			result[i], result[j] = result[j], result[i]
+			if numbers != nil {
+				numbers[i], numbers[j] = numbers[j], numbers[i]
+			}
+		})
+	}
+
+	if params.download {
+		h.respondDownload(w, r, params, result, truncated)
+		return
+	}
+
+	if params.asMap {
+		response := MapFizzBuzzResponse{Params: params.echoed(), Result: fizzbuzz.KeyByNumber(params.start, result)}
+		if truncated {
+			response.Truncated = true
+			response.Returned = len(result)
+		}
+		h.respondChecksummedJSON(w, r, http.StatusOK, response)
+		return
+	}
+
+	if params.numeric {
+		response := NumericFizzBuzzResponse{Params: params.echoed(), Result: NumericResult{Values: result, Numbers: numbers}}
+		if truncated {
+			response.Truncated = true
+			response.Returned = len(result)
+		}
+		h.respondChecksummedJSON(w, r, http.StatusOK, response)
+		return
+	}
+
+	response := FizzBuzzResponse{Params: params.echoed(), Result: result}
+	if truncated {
+		response.Truncated = true
+		response.Returned = len(result)
+	}
+
+	h.respondChecksummedJSON(w, r, http.StatusOK, response)
+}
+
+// capLimit applies the limit cap, truncating or rejecting limit, then
+// rejects the result if limit values of up to width bytes would exceed the
+// response size limit.
+func (h *Handler) capLimit(limit, width int) (int, bool, error) {
+	truncated := false
+	if caps := h.limits.Load(); caps != nil && caps.maxLimit > 0 && limit > caps.maxLimit {
+		if !caps.truncate {
+			return 0, false, newKindError(ErrOutOfRange, "limit", fmt.Sprintf("must not exceed %d", caps.maxLimit))
+		}
+		limit = caps.maxLimit
+		truncated = true
+	}
+
+	if h.maxResponseBytes > 0 {
+		estimate := int64(limit) * int64(width)
+		if estimate > h.maxResponseBytes {
+			return 0, false, newKindError(ErrOutOfRange, "limit",
+				fmt.Sprintf("produces an estimated %d bytes, exceeding the %d byte response limit", estimate, h.maxResponseBytes))
+		}
+	}
+	return limit, truncated, nil
+}
+
+// acquireGeneration takes a slot for a generation of limit items when the
+// concurrency limit applies to it. When none is free it answers 503 and
+// reports false; otherwise the caller must call release when done.
+func (h *Handler) acquireGeneration(w http.ResponseWriter, r *http.Request, limit int) (release func(), ok bool) {
+	if h.generations == nil || limit < h.heavyLimit {
+		return func() {}, true
+	}
+	select {
+	case h.generations <- struct{}{}:
+		return func() { <-h.generations }, true
+	default:
+		w.Header().Set("Retry-After", retryAfterBusy)
+		h.respondError(w, r, http.StatusServiceUnavailable, "too many concurrent generations, retry later")
+		return nil, false
+	}
+}
+
+// checkParams applies the configured policies on top of parsing: divisor
+// bounds, allowed strings and strict integer forms.
+func (h *Handler) checkParams(params fizzBuzzParams, values url.Values) error {
+	if err := h.checkDivisors(params); err != nil {
+		return err
+	}
+	if err := h.checkStrings(params); err != nil {
+		return err
+	}
+	return h.checkIntegers(values)
+}
+
+func (h *Handler) checkDivisors(params fizzBuzzParams) error {
+	if err := h.checkDivisor("int1", params.int1); err != nil {
+		return err
+	}
+	return h.checkDivisor("int2", params.int2)
+}
+
+func (h *Handler) checkDivisor(name string, value int64) error {
+	if value < h.minDivisor {
+		return newKindError(ErrOutOfRange, name, fmt.Sprintf("must be at least %d", h.minDivisor))
+	}
+	if h.maxDivisor > 0 && value > h.maxDivisor {
+		return newKindError(ErrOutOfRange, name, fmt.Sprintf("must not exceed %d", h.maxDivisor))
+	}
+	return nil
+}
+
+func (h *Handler) checkStrings(params fizzBuzzParams) error {
+	if h.allowedStrings == nil {
+		return nil
+	}
+	for _, str := range []struct {
+		name  string
+		value string
+	}{{"str1", params.str1}, {"str2", params.str2}, {"str3", params.str3}} {
+		if str.name == "str3" && str.value == "" {
+			continue
+		}
+		if err := h.checkString(str.name, str.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *Handler) checkString(name, value string) error {
+	if h.allowedStrings == nil {
+		return nil
+	}
+	if _, ok := h.allowedStrings[value]; !ok {
+		return newParamError(name, "is not an allowed value")
+	}
+	return nil
+}
+
+// integerParams are the /fizzbuzz parameters read with query.ParseInt.
+var integerParams = []string{"int1", "int2", "limit", "start", "seed", "base"}
+
+func (h *Handler) checkIntegers(values url.Values) error {
+	if !h.strictIntegers {
+		return nil
+	}
+	for _, name := range integerParams {
+		if query.HasLeadingZeros(values.Get(name)) {
+			return newParamError(name, "must not have leading zeros")
+		}
+	}
+	return nil
+}
+
+// setServerTiming reports how long generation took in a Server-Timing
+// header, in milliseconds, so clients can tell it apart from network and
+// queueing time.
+func setServerTiming(w http.ResponseWriter, d time.Duration) {
+	ms := float64(d) / float64(time.Millisecond)
+	w.Header().Set("Server-Timing", "gen;dur="+strconv.FormatFloat(ms, 'f', 3, 64))
+}
+
+// replacesAny reports whether str1 or str2 replaces at least one of the
+// limit values from start. Only the divisible rule is checked; other rules
+// are assumed to replace something.
+func replacesAny(params fizzBuzzParams, limit int) bool {
+	if params.rule != fizzbuzz.RuleDivisible {
+		return true
+	}
+	span := int64(limit) - 1
+	return hasMultiple(params.int1, params.start, span) || hasMultiple(params.int2, params.start, span)
+}
+
+// hasMultiple reports whether [start, start+span] holds a multiple of d,
+// without computing values that could overflow.
+func hasMultiple(d, start, span int64) bool {
+	var distance int64
+	switch r := start % d; {
+	case r > 0:
+		distance = d - r
+	case r < 0:
+		distance = -r
+	}
+	return distance <= span
+}
+
+// respondNumbersOnly answers only=numbers with the values that no word
+// replaced, the complement of the str1/str2/both index queries.
+func (h *Handler) respondNumbersOnly(w http.ResponseWriter, r *http.Request, params fizzBuzzParams, limit int) {
+	indices := fizzbuzz.IndicesWith(params.int1, params.int2, params.start, limit, fizzbuzz.CategoryNumber, params.rule)
+	numbers := make([]string, len(indices))
+	for i, index := range indices {
+		numbers[i] = strconv.FormatInt(params.start+int64(index-1), params.base)
+	}
+
+	if params.numeric {
+		mask := make([]bool, len(numbers))
+		for i := range mask {
+			mask[i] = true
+		}
+		h.respondChecksummedJSON(w, r, http.StatusOK, NumericFizzBuzzResponse{Params: params.echoed(), Result: NumericResult{Values: numbers, Numbers: mask}})
+		return
+	}
+	h.respondChecksummedJSON(w, r, http.StatusOK, FizzBuzzResponse{Params: params.echoed(), Result: numbers})
 }
 
 func parseFizzBuzzParams(values url.Values) (fizzBuzzParams, error) {
 	const missingParamsMessage = "missing required parameters: int1, int2, limit, str1, str2"
 
 	requiredParams := []string{"int1", "int2", "limit", "str1", "str2"}
+	var missing []InvalidParam
 	for _, param := range requiredParams {
 		if _, exists := values[param]; !exists || len(values[param]) == 0 {
-			return fizzBuzzParams{}, errors.New(missingParamsMessage)
+			missing = append(missing, InvalidParam{Name: param, Reason: "is required"})
 		}
 	}
+	if len(missing) > 0 {
+		return fizzBuzzParams{}, &validationError{kind: ErrMissingParams, message: missingParamsMessage, params: missing}
+	}
 
 	str1 := values.Get("str1")
 	if str1 == "" {
-		return fizzBuzzParams{}, fmt.Errorf("str1 cannot be empty")
+		return fizzBuzzParams{}, newKindError(ErrEmptyStr, "str1", "cannot be empty")
 	}
 
 	str2 := values.Get("str2")
 	if str2 == "" {
-		return fizzBuzzParams{}, fmt.Errorf("str2 cannot be empty")
+		return fizzBuzzParams{}, newKindError(ErrEmptyStr, "str2", "cannot be empty")
+	}
+
+	// str3 is optional, but when given it must say something; values
+	// divisible by both otherwise get str1+str2.
+	str3 := values.Get("str3")
+	if values.Has("str3") && str3 == "" {
+		return fizzBuzzParams{}, newKindError(ErrEmptyStr, "str3", "cannot be empty")
 	}
 
-	int1, err := parsePositiveInt(values.Get("int1"), "int1")
+	int1, err := parsePositiveInt64(values.Get("int1"), "int1")
 	if err != nil {
 		return fizzBuzzParams{}, err
 	}
 
-	int2, err := parsePositiveInt(values.Get("int2"), "int2")
+	int2, err := parsePositiveInt64(values.Get("int2"), "int2")
 	if err != nil {
 		return fizzBuzzParams{}, err
 	}
 
+	if query.IsScientific(values.Get("limit")) {
+		return fizzBuzzParams{}, newKindError(ErrInvalidInt, "limit", "must be a plain integer (scientific notation not allowed)")
+	}
 	limit, err := parsePositiveInt(values.Get("limit"), "limit")
 	if err != nil {
 		return fizzBuzzParams{}, err
 	}
 
+	start := int64(1)
+	if raw := values.Get("start"); raw != "" {
+		start, err = query.ParseInt(raw, 64)
+		if err != nil {
+			return fizzBuzzParams{}, newKindError(ErrInvalidInt, "start", err.Error())
+		}
+		if start > math.MaxInt64-int64(limit)+1 {
+			return fizzBuzzParams{}, newKindError(ErrOutOfRange, "start", "is too large for the requested limit")
+		}
+	}
+
+	var only *fizzbuzz.Category
+	if raw := values.Get("only"); raw != "" {
+		category, ok := onlyCategories[raw]
+		if !ok {
+			return fizzBuzzParams{}, newParamError("only", "must be one of: str1, str2, both, numbers")
+		}
+		only = &category
+	}
+
+	rule := fizzbuzz.RuleDivisible
+	if raw := values.Get("rule"); raw != "" {
+		var ok bool
+		if rule, ok = rules[raw]; !ok {
+			return fizzBuzzParams{}, newParamError("rule", "must be one of: divisible, digitsum")
+		}
+	}
+
+	templated := false
+	if raw := values.Get("templated"); raw != "" {
+		templated, err = strconv.ParseBool(raw)
+		if err != nil {
+			return fizzBuzzParams{}, newParamError("templated", "must be a boolean")
+		}
+	}
+
+	collapse := false
+	if raw := values.Get("collapse_equal"); raw != "" {
+		collapse, err = strconv.ParseBool(raw)
+		if err != nil {
+			return fizzBuzzParams{}, newParamError("collapse_equal", "must be a boolean")
+		}
+	}
+
+	numeric := false
+	if raw := values.Get("numeric"); raw != "" {
+		numeric, err = strconv.ParseBool(raw)
+		if err != nil {
+			return fizzBuzzParams{}, newParamError("numeric", "must be a boolean")
+		}
+	}
+
+	shuffle := false
+	if raw := values.Get("shuffle"); raw != "" {
+		shuffle, err = strconv.ParseBool(raw)
+		if err != nil {
+			return fizzBuzzParams{}, newParamError("shuffle", "must be a boolean")
+		}
+	}
+
+	var seed int64
+	if raw := values.Get("seed"); raw != "" {
+		seed, err = query.ParseInt(raw, 64)
+		if err != nil {
+			return fizzBuzzParams{}, newKindError(ErrInvalidInt, "seed", err.Error())
+		}
+	} else if shuffle {
+		seed = rand.Int64()
+	}
+
+	stream := false
+	if raw := values.Get("stream"); raw != "" {
+		stream, err = strconv.ParseBool(raw)
+		if err != nil {
+			return fizzBuzzParams{}, newParamError("stream", "must be a boolean")
+		}
+		switch {
+		case stream && shuffle:
+			return fizzBuzzParams{}, newParamError("stream", "cannot be combined with shuffle")
+		case stream && only != nil:
+			return fizzBuzzParams{}, newParamError("stream", "cannot be combined with only")
+		}
+	}
+
+	base := 10
+	if raw := values.Get("base"); raw != "" {
+		if base, err = query.Atoi(raw); err != nil {
+			return fizzBuzzParams{}, newKindError(ErrInvalidInt, "base", err.Error())
+		}
+		if base < 2 || base > 36 {
+			return fizzBuzzParams{}, newKindError(ErrOutOfRange, "base", "must be between 2 and 36")
+		}
+		// Digits above 9 are letters, which are not JSON numbers.
+		if base != 10 && numeric {
+			return fizzBuzzParams{}, newParamError("base", "cannot be combined with numeric")
+		}
+	}
+
+	download, downloadNamed := false, false
+	if raw := values.Get("download"); raw != "" {
+		if raw == downloadParams {
+			download, downloadNamed = true, true
+		} else if download, err = strconv.ParseBool(raw); err != nil {
+			return fizzBuzzParams{}, newParamError("download", "must be a boolean or params")
+		}
+		switch {
+		case download && only != nil:
+			return fizzBuzzParams{}, newParamError("download", "cannot be combined with only")
+		case download && stream:
+			return fizzBuzzParams{}, newParamError("download", "cannot be combined with stream")
+		}
+	}
+
+	echoParams := false
+	if raw := values.Get("echo_params"); raw != "" {
+		if echoParams, err = strconv.ParseBool(raw); err != nil {
+			return fizzBuzzParams{}, newParamError("echo_params", "must be a boolean")
+		}
+		switch {
+		case echoParams && stream:
+			return fizzBuzzParams{}, newParamError("echo_params", "cannot be combined with stream")
+		case echoParams && download:
+			return fizzBuzzParams{}, newParamError("echo_params", "cannot be combined with download")
+		}
+	}
+
+	asMap := false
+	switch raw := values.Get("format"); raw {
+	case "", "list":
+	case "map":
+		asMap = true
+		// A keyed object has no order to shuffle or stream, and its values
+		// are always strings.
+		switch {
+		case numeric:
+			return fizzBuzzParams{}, newParamError("format", "map cannot be combined with numeric")
+		case only != nil:
+			return fizzBuzzParams{}, newParamError("format", "map cannot be combined with only")
+		case shuffle:
+			return fizzBuzzParams{}, newParamError("format", "map cannot be combined with shuffle")
+		case stream:
+			return fizzBuzzParams{}, newParamError("format", "map cannot be combined with stream")
+		case download:
+			return fizzBuzzParams{}, newParamError("format", "map cannot be combined with download")
+		}
+	default:
+		return fizzBuzzParams{}, newParamError("format", "must be one of: list, map")
+	}
+
+	// preview only affects statistics, which the middleware handles; it is
+	// validated here so a typo is not silently counted.
+	if raw := values.Get("preview"); raw != "" {
+		if _, err := strconv.ParseBool(raw); err != nil {
+			return fizzBuzzParams{}, newParamError("preview", "must be a boolean")
+		}
+	}
+
 	return fizzBuzzParams{
 		int1:  int1,
 		int2:  int2,
+		start: start,
 		limit: limit,
 		str1:  str1,
 		str2:  str2,
+		str3:  str3,
+		only:  only,
+
+		templated: templated,
+		numeric:   numeric,
+		shuffle:   shuffle,
+		seed:      seed,
+		stream:    stream,
+		base:      base,
+		rule:      rule,
+		collapse:  collapse,
+
+		download:      download,
+		downloadNamed: downloadNamed,
+
+		echoParams: echoParams,
+		asMap:      asMap,
 	}, nil
 }
 
 func parsePositiveInt(value string, name string) (int, error) {
-	parsed, err := strconv.Atoi(value)
+	parsed, err := parsePositive(value, name, strconv.IntSize)
+	return int(parsed), err
+}
+
+// parsePositiveInt64 parses divisors, which are 64-bit on every platform.
+func parsePositiveInt64(value string, name string) (int64, error) {
+	return parsePositive(value, name, 64)
+}
+
+func parsePositive(value string, name string, bitSize int) (int64, error) {
+	parsed, err := query.ParseInt(value, bitSize)
 	if err != nil {
-		return 0, fmt.Errorf("%s must be a valid integer", name)
+		return 0, newKindError(ErrInvalidInt, name, err.Error())
 	}
 
 	if parsed <= 0 {
-		return 0, fmt.Errorf("%s must be greater than 0", name)
+		return 0, newKindError(ErrNonPositive, name, "must be greater than 0")
 	}
 
 	return parsed, nil
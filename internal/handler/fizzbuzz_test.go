@@ -1,12 +1,21 @@
 package handler
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
+	"testing/synctest"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 
@@ -77,19 +86,31 @@ func TestHandler_FizzBuzz(t *testing.T) {
 			name:           "invalid int1 parameter",
 			queryParams:    "int1=abc&int2=5&limit=15&str1=fizz&str2=buzz",
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   ErrorResponse{Error: "int1 must be a valid integer"},
+			expectedBody:   ErrorResponse{Error: `int1 must be a valid integer (got "abc")`},
 		},
 		{
 			name:           "invalid int2 parameter",
 			queryParams:    "int1=3&int2=xyz&limit=15&str1=fizz&str2=buzz",
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   ErrorResponse{Error: "int2 must be a valid integer"},
+			expectedBody:   ErrorResponse{Error: `int2 must be a valid integer (got "xyz")`},
 		},
 		{
 			name:           "invalid limit parameter",
 			queryParams:    "int1=3&int2=5&limit=abc&str1=fizz&str2=buzz",
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   ErrorResponse{Error: "limit must be a valid integer"},
+			expectedBody:   ErrorResponse{Error: `limit must be a valid integer (got "abc")`},
+		},
+		{
+			name:           "scientific limit",
+			queryParams:    "int1=3&int2=5&limit=1e6&str1=fizz&str2=buzz",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   ErrorResponse{Error: "limit must be a plain integer (scientific notation not allowed)"},
+		},
+		{
+			name:           "long invalid value is truncated",
+			queryParams:    "int1=3&int2=5&limit=" + strings.Repeat("x", 100) + "&str1=fizz&str2=buzz",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   ErrorResponse{Error: `limit must be a valid integer (got "` + strings.Repeat("x", 32) + `...")`},
 		},
 		{
 			name:           "zero int1 parameter",
@@ -139,6 +160,20 @@ func TestHandler_FizzBuzz(t *testing.T) {
 			expectedStatus: http.StatusBadRequest,
 			expectedBody:   ErrorResponse{Error: "str2 cannot be empty"},
 		},
+		{
+			name:           "negative start spans zero",
+			queryParams:    "int1=3&int2=5&start=-3&limit=7&str1=fizz&str2=buzz",
+			expectedStatus: http.StatusOK,
+			expectedBody: FizzBuzzResponse{Result: []string{
+				"fizz", "-2", "-1", "fizzbuzz", "1", "2", "fizz",
+			}},
+		},
+		{
+			name:           "invalid start parameter",
+			queryParams:    "int1=3&int2=5&start=abc&limit=15&str1=fizz&str2=buzz",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   ErrorResponse{Error: `start must be a valid integer (got "abc")`},
+		},
 		{
 			name:           "large limit request",
 			queryParams:    "int1=7&int2=11&limit=1000&str1=seven&str2=eleven",
@@ -218,6 +253,38 @@ func TestHandler_FizzBuzz(t *testing.T) {
 	}
 }
 
+func TestNewHandler_ServesAllEndpoints(t *testing.T) {
+	store := statistics.NewStore()
+	store.Record(statistics.RequestParams{Int1: 3, Int2: 5, Limit: 15, Str1: "fizz", Str2: "buzz"})
+	h := NewHandler(store, slog.New(slog.DiscardHandler))
+
+	rec := httptest.NewRecorder()
+	h.FizzBuzz(rec, httptest.NewRequest(http.MethodGet, "/fizzbuzz?int1=3&int2=5&limit=3&str1=fizz&str2=buzz", nil))
+	if body := strings.TrimSpace(rec.Body.String()); rec.Code != http.StatusOK || body != `{"result":["1","2","fizz"]}` {
+		t.Fatalf("FizzBuzz = %d %s", rec.Code, body)
+	}
+
+	rec = httptest.NewRecorder()
+	h.Statistics(rec, httptest.NewRequest(http.MethodGet, "/statistics", nil))
+	var stats StatisticsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("failed to decode statistics: %v", err)
+	}
+	if rec.Code != http.StatusOK || stats.Hits != 1 || stats.Params.Str1 != "fizz" {
+		t.Fatalf("Statistics = %d %+v", rec.Code, stats)
+	}
+
+	rec = httptest.NewRecorder()
+	h.Health(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	var health HealthResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &health); err != nil {
+		t.Fatalf("failed to decode health: %v", err)
+	}
+	if rec.Code != http.StatusOK || health.Status != "ok" {
+		t.Fatalf("Health = %d %+v", rec.Code, health)
+	}
+}
+
 func TestHandler_FizzBuzz_ThroughRouter(t *testing.T) {
 	h := NewHandler(statistics.NewStore(), nil)
 	router := chi.NewRouter()
@@ -254,29 +321,1292 @@ func TestHandler_FizzBuzz_ThroughRouter(t *testing.T) {
 	}})
 }
 
-func assertJSONResponse(t *testing.T, body []byte, expected interface{}) {
-	t.Helper()
+func TestHandler_FizzBuzz_OnlyIndices(t *testing.T) {
+	tests := []struct {
+		name           string
+		only           string
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name:           "str1",
+			only:           "str1",
+			expectedStatus: http.StatusOK,
+			expectedBody:   IndicesResponse{Indices: []int{3, 6, 9, 12}},
+		},
+		{
+			name:           "str2",
+			only:           "str2",
+			expectedStatus: http.StatusOK,
+			expectedBody:   IndicesResponse{Indices: []int{5, 10}},
+		},
+		{
+			name:           "both",
+			only:           "both",
+			expectedStatus: http.StatusOK,
+			expectedBody:   IndicesResponse{Indices: []int{15}},
+		},
+		{
+			name:           "numbers returns the unreplaced values",
+			only:           "numbers",
+			expectedStatus: http.StatusOK,
+			expectedBody:   FizzBuzzResponse{Result: []string{"1", "2", "4", "7", "8", "11", "13", "14"}},
+		},
+		{
+			name:           "unknown category",
+			only:           "fizz",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   ErrorResponse{Error: "only must be one of: str1, str2, both, numbers"},
+		},
+	}
 
-	value := reflect.New(reflect.TypeOf(expected))
-	if err := json.Unmarshal(body, value.Interface()); err != nil {
-		t.Fatalf("failed to unmarshal response: %v", err)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(statistics.NewStore(), nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/fizzbuzz?int1=3&int2=5&limit=15&str1=fizz&str2=buzz&only="+tc.only, nil)
+			rec := httptest.NewRecorder()
+
+			h.FizzBuzz(rec, req)
+
+			if rec.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tc.expectedStatus, rec.Code)
+			}
+
+			switch expected := tc.expectedBody.(type) {
+			case IndicesResponse:
+				assertJSONResponse(t, rec.Body.Bytes(), expected)
+			case FizzBuzzResponse:
+				assertJSONResponse(t, rec.Body.Bytes(), expected)
+			case ErrorResponse:
+				assertErrorResponse(t, rec.Body.Bytes(), expected.Error)
+			}
+		})
 	}
+}
 
-	got := value.Elem().Interface()
-	if !reflect.DeepEqual(got, expected) {
-		t.Fatalf("expected body %+v, got %+v", expected, got)
+func TestHandler_FizzBuzz_ProblemJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected ProblemResponse
+	}{
+		{
+			name:  "invalid integer",
+			query: "int1=abc&int2=5&limit=15&str1=fizz&str2=buzz",
+			expected: ProblemResponse{
+				Type:          "about:blank",
+				Title:         "Bad Request",
+				Status:        http.StatusBadRequest,
+				Detail:        `int1 must be a valid integer (got "abc")`,
+				InvalidParams: []InvalidParam{{Name: "int1", Reason: `must be a valid integer (got "abc")`}},
+			},
+		},
+		{
+			name:  "missing parameters",
+			query: "int1=3&limit=15&str1=fizz",
+			expected: ProblemResponse{
+				Type:   "about:blank",
+				Title:  "Bad Request",
+				Status: http.StatusBadRequest,
+				Detail: "missing required parameters: int1, int2, limit, str1, str2",
+				InvalidParams: []InvalidParam{
+					{Name: "int2", Reason: "is required"},
+					{Name: "str2", Reason: "is required"},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(statistics.NewStore(), nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/fizzbuzz?"+tc.query, nil)
+			req.Header.Set("Accept", "application/problem+json")
+			rec := httptest.NewRecorder()
+
+			h.FizzBuzz(rec, req)
+
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+			}
+			if contentType := rec.Header().Get("Content-Type"); contentType != "application/problem+json" {
+				t.Fatalf("expected Content-Type application/problem+json, got %s", contentType)
+			}
+
+			assertJSONResponse(t, rec.Body.Bytes(), tc.expected)
+		})
 	}
 }
 
-func assertErrorResponse(t *testing.T, body []byte, expectedMessage string) {
-	t.Helper()
+func TestHandler_FizzBuzz_DefaultErrorShapeWithoutProblemAccept(t *testing.T) {
+	h := NewHandler(statistics.NewStore(), nil)
 
-	var resp ErrorResponse
-	if err := json.Unmarshal(body, &resp); err != nil {
-		t.Fatalf("failed to unmarshal error response: %v", err)
+	req := httptest.NewRequest(http.MethodGet, "/fizzbuzz?int1=abc&int2=5&limit=15&str1=fizz&str2=buzz", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+
+	h.FizzBuzz(rec, req)
+
+	if contentType := rec.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Fatalf("expected Content-Type application/json, got %s", contentType)
 	}
+	assertErrorResponse(t, rec.Body.Bytes(), `int1 must be a valid integer (got "abc")`)
+}
 
-	if resp.Error != expectedMessage {
-		t.Fatalf("expected error message %q, got %q", expectedMessage, resp.Error)
+func TestHandler_FizzBuzz_MaxLimit(t *testing.T) {
+	tests := []struct {
+		name           string
+		truncate       bool
+		queryParams    string
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name:           "reject mode returns 400 above cap",
+			truncate:       false,
+			queryParams:    "int1=3&int2=5&limit=20&str1=fizz&str2=buzz",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   ErrorResponse{Error: "limit must not exceed 5"},
+		},
+		{
+			name:           "reject mode accepts limit at cap",
+			truncate:       false,
+			queryParams:    "int1=3&int2=5&limit=5&str1=fizz&str2=buzz",
+			expectedStatus: http.StatusOK,
+			expectedBody:   FizzBuzzResponse{Result: []string{"1", "2", "fizz", "4", "buzz"}},
+		},
+		{
+			name:           "truncate mode caps the result",
+			truncate:       true,
+			queryParams:    "int1=3&int2=5&limit=20&str1=fizz&str2=buzz",
+			expectedStatus: http.StatusOK,
+			expectedBody: FizzBuzzResponse{
+				Result:    []string{"1", "2", "fizz", "4", "buzz"},
+				Truncated: true,
+				Returned:  5,
+			},
+		},
+		{
+			name:           "truncate mode leaves small limits untouched",
+			truncate:       true,
+			queryParams:    "int1=3&int2=5&limit=3&str1=fizz&str2=buzz",
+			expectedStatus: http.StatusOK,
+			expectedBody:   FizzBuzzResponse{Result: []string{"1", "2", "fizz"}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(statistics.NewStore(), nil, WithMaxLimit(5, tc.truncate))
+
+			req := httptest.NewRequest(http.MethodGet, "/fizzbuzz?"+tc.queryParams, nil)
+			rec := httptest.NewRecorder()
+
+			h.FizzBuzz(rec, req)
+
+			if rec.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tc.expectedStatus, rec.Code)
+			}
+
+			switch expected := tc.expectedBody.(type) {
+			case FizzBuzzResponse:
+				assertJSONResponse(t, rec.Body.Bytes(), expected)
+			case ErrorResponse:
+				assertErrorResponse(t, rec.Body.Bytes(), expected.Error)
+			}
+		})
+	}
+}
+
+func TestHandler_FizzBuzz_ZeroMaxLimitIsUnbounded(t *testing.T) {
+	h := NewHandler(statistics.NewStore(), nil, WithMaxLimit(0, false))
+
+	req := httptest.NewRequest(http.MethodGet, "/fizzbuzz?int1=3&int2=5&limit=200000&str1=fizz&str2=buzz", nil)
+	rec := httptest.NewRecorder()
+
+	h.FizzBuzz(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	var resp FizzBuzzResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Result) != 200000 || resp.Truncated {
+		t.Fatalf("expected 200000 untruncated values, got %d (truncated %v)", len(resp.Result), resp.Truncated)
+	}
+}
+
+func TestHandler_FizzBuzz_LargeDivisors(t *testing.T) {
+	tests := []struct {
+		name           string
+		queryParams    string
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name:           "divisors beyond int32 range",
+			queryParams:    "int1=4294967296&int2=2147483648&limit=2&str1=fizz&str2=buzz&start=4294967295",
+			expectedStatus: http.StatusOK,
+			expectedBody:   FizzBuzzResponse{Result: []string{"4294967295", "fizzbuzz"}},
+		},
+		{
+			name:           "divisor beyond int64 range",
+			queryParams:    "int1=9223372036854775808&int2=5&limit=2&str1=fizz&str2=buzz",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   ErrorResponse{Error: `int1 must be a valid integer (got "9223372036854775808")`},
+		},
+		{
+			name:           "start leaving no room for limit",
+			queryParams:    "int1=3&int2=5&limit=3&str1=fizz&str2=buzz&start=9223372036854775806",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   ErrorResponse{Error: "start is too large for the requested limit"},
+		},
+		{
+			name:           "start at the last valid position",
+			queryParams:    "int1=3&int2=5&limit=2&str1=fizz&str2=buzz&start=9223372036854775806",
+			expectedStatus: http.StatusOK,
+			expectedBody:   FizzBuzzResponse{Result: []string{"fizz", "9223372036854775807"}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(statistics.NewStore(), nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/fizzbuzz?"+tc.queryParams, nil)
+			rec := httptest.NewRecorder()
+
+			h.FizzBuzz(rec, req)
+
+			if rec.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tc.expectedStatus, rec.Code)
+			}
+
+			switch expected := tc.expectedBody.(type) {
+			case FizzBuzzResponse:
+				assertJSONResponse(t, rec.Body.Bytes(), expected)
+			case ErrorResponse:
+				assertErrorResponse(t, rec.Body.Bytes(), expected.Error)
+			}
+		})
+	}
+}
+
+func TestHandler_FizzBuzz_Numeric(t *testing.T) {
+	tests := []struct {
+		name         string
+		queryParams  string
+		expectedBody string
+	}{
+		{
+			name:         "numbers unquoted and words quoted",
+			queryParams:  "int1=3&int2=5&limit=15&str1=fizz&str2=buzz&numeric=true",
+			expectedBody: `{"result":[1,2,"fizz",4,"buzz","fizz",7,8,"fizz","buzz",11,"fizz",13,14,"fizzbuzz"]}`,
+		},
+		{
+			name:         "numeric-looking words stay quoted",
+			queryParams:  "int1=2&int2=7&limit=4&str1=10&str2=20&numeric=true",
+			expectedBody: `{"result":[1,"10",3,"10"]}`,
+		},
+		{
+			name:         "negative numbers",
+			queryParams:  "int1=3&int2=5&limit=3&str1=fizz&str2=buzz&start=-2&numeric=true",
+			expectedBody: `{"result":[-2,-1,"fizzbuzz"]}`,
+		},
+		{
+			name:         "numbers only",
+			queryParams:  "int1=3&int2=5&limit=8&str1=fizz&str2=buzz&only=numbers&numeric=true",
+			expectedBody: `{"result":[1,2,4,7,8]}`,
+		},
+		{
+			name:         "default keeps strings",
+			queryParams:  "int1=3&int2=5&limit=3&str1=fizz&str2=buzz",
+			expectedBody: `{"result":["1","2","fizz"]}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(statistics.NewStore(), nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/fizzbuzz?"+tc.queryParams, nil)
+			rec := httptest.NewRecorder()
+
+			h.FizzBuzz(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+			if got := rec.Body.String(); got != tc.expectedBody {
+				t.Fatalf("expected body %s, got %s", tc.expectedBody, got)
+			}
+		})
+	}
+}
+
+func TestHandler_FizzBuzz_Shuffle(t *testing.T) {
+	h := NewHandler(statistics.NewStore(), nil)
+
+	fetch := func(t *testing.T, query string) []string {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/fizzbuzz?int1=3&int2=5&limit=30&str1=fizz&str2=buzz&"+query, nil)
+		rec := httptest.NewRecorder()
+		h.FizzBuzz(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+		var response FizzBuzzResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return response.Result
+	}
+
+	ordered := fetch(t, "")
+	first := fetch(t, "shuffle=true&seed=42")
+	second := fetch(t, "shuffle=true&seed=42")
+	other := fetch(t, "shuffle=true&seed=7")
+
+	if !reflect.DeepEqual(first, second) {
+		t.Fatalf("same seed produced different orders:\n%v\n%v", first, second)
+	}
+	if reflect.DeepEqual(first, other) {
+		t.Fatalf("different seeds produced the same order: %v", first)
+	}
+	if reflect.DeepEqual(first, ordered) {
+		t.Fatalf("shuffle left the sequence in order: %v", first)
+	}
+
+	sortedShuffle := slices.Clone(first)
+	sortedOrdered := slices.Clone(ordered)
+	slices.Sort(sortedShuffle)
+	slices.Sort(sortedOrdered)
+	if !reflect.DeepEqual(sortedShuffle, sortedOrdered) {
+		t.Fatalf("shuffle changed the values: %v", first)
+	}
+
+	if unshuffled := fetch(t, "seed=42"); !reflect.DeepEqual(unshuffled, ordered) {
+		t.Fatalf("seed without shuffle changed the order: %v", unshuffled)
+	}
+}
+
+func TestHandler_FizzBuzz_ShuffleNumeric(t *testing.T) {
+	h := NewHandler(statistics.NewStore(), nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/fizzbuzz?int1=3&int2=5&limit=15&str1=fizz&str2=buzz&shuffle=true&seed=42&numeric=true", nil)
+	rec := httptest.NewRecorder()
+	h.FizzBuzz(rec, req)
+
+	var response struct {
+		Result []interface{} `json:"result"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	for _, value := range response.Result {
+		switch v := value.(type) {
+		case float64:
+		case string:
+			if v != "fizz" && v != "buzz" && v != "fizzbuzz" {
+				t.Fatalf("number %q was quoted after shuffling", v)
+			}
+		default:
+			t.Fatalf("unexpected value %v", v)
+		}
+	}
+}
+
+func TestHandler_FizzBuzz_Templated(t *testing.T) {
+	tests := []struct {
+		name           string
+		queryParams    string
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name:           "templated interpolates the number",
+			queryParams:    "int1=3&int2=5&limit=6&str1=item-{n}&str2=buzz&templated=true",
+			expectedStatus: http.StatusOK,
+			expectedBody:   FizzBuzzResponse{Result: []string{"1", "2", "item-3", "4", "buzz", "item-6"}},
+		},
+		{
+			name:           "placeholder literal by default",
+			queryParams:    "int1=3&int2=5&limit=3&str1=item-{n}&str2=buzz",
+			expectedStatus: http.StatusOK,
+			expectedBody:   FizzBuzzResponse{Result: []string{"1", "2", "item-{n}"}},
+		},
+		{
+			name:           "placeholder literal when templated is false",
+			queryParams:    "int1=3&int2=5&limit=3&str1=item-{n}&str2=buzz&templated=false",
+			expectedStatus: http.StatusOK,
+			expectedBody:   FizzBuzzResponse{Result: []string{"1", "2", "item-{n}"}},
+		},
+		{
+			name:           "invalid templated flag",
+			queryParams:    "int1=3&int2=5&limit=3&str1=fizz&str2=buzz&templated=maybe",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   ErrorResponse{Error: "templated must be a boolean"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(statistics.NewStore(), nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/fizzbuzz?"+tc.queryParams, nil)
+			rec := httptest.NewRecorder()
+
+			h.FizzBuzz(rec, req)
+
+			if rec.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tc.expectedStatus, rec.Code)
+			}
+
+			switch expected := tc.expectedBody.(type) {
+			case FizzBuzzResponse:
+				assertJSONResponse(t, rec.Body.Bytes(), expected)
+			case ErrorResponse:
+				assertErrorResponse(t, rec.Body.Bytes(), expected.Error)
+			}
+		})
+	}
+}
+
+func TestHandler_FizzBuzz_DivisorRange(t *testing.T) {
+	tests := []struct {
+		name           string
+		int1           string
+		int2           string
+		expectedStatus int
+		expectedError  string
+	}{
+		{"both at minimum", "2", "2", http.StatusOK, ""},
+		{"both at maximum", "1000", "1000", http.StatusOK, ""},
+		{"int1 below minimum", "1", "5", http.StatusBadRequest, "int1 must be at least 2"},
+		{"int2 below minimum", "3", "1", http.StatusBadRequest, "int2 must be at least 2"},
+		{"int1 above maximum", "1001", "5", http.StatusBadRequest, "int1 must not exceed 1000"},
+		{"int2 above maximum", "3", "1001", http.StatusBadRequest, "int2 must not exceed 1000"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(statistics.NewStore(), nil, WithDivisorRange(2, 1000))
+
+			req := httptest.NewRequest(http.MethodGet, "/fizzbuzz?limit=5&str1=fizz&str2=buzz&int1="+tc.int1+"&int2="+tc.int2, nil)
+			rec := httptest.NewRecorder()
+
+			h.FizzBuzz(rec, req)
+
+			if rec.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tc.expectedStatus, rec.Code)
+			}
+			if tc.expectedError != "" {
+				assertErrorResponse(t, rec.Body.Bytes(), tc.expectedError)
+			}
+		})
+	}
+}
+
+func TestHandler_FizzBuzz_DivisorRangeDefaultUnbounded(t *testing.T) {
+	h := NewHandler(statistics.NewStore(), nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/fizzbuzz?int1=1&int2=9223372036854775807&limit=1&str1=fizz&str2=buzz", nil)
+	rec := httptest.NewRecorder()
+
+	h.FizzBuzz(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+}
+
+func TestHandler_FizzBuzz_AllowedStrings(t *testing.T) {
+	tests := []struct {
+		name           string
+		allowed        []string
+		str1           string
+		str2           string
+		expectedStatus int
+		expectedError  string
+	}{
+		{"both allowed", []string{"fizz", "buzz"}, "fizz", "buzz", http.StatusOK, ""},
+		{"str1 not allowed", []string{"fizz", "buzz"}, "rude", "buzz", http.StatusBadRequest, "str1 is not an allowed value"},
+		{"str2 not allowed", []string{"fizz", "buzz"}, "fizz", "rude", http.StatusBadRequest, "str2 is not an allowed value"},
+		{"match is exact", []string{"fizz", "buzz"}, "Fizz", "buzz", http.StatusBadRequest, "str1 is not an allowed value"},
+		{"unset allows anything", nil, "anything", "goes", http.StatusOK, ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(statistics.NewStore(), nil, WithAllowedStrings(tc.allowed))
+
+			req := httptest.NewRequest(http.MethodGet, "/fizzbuzz?int1=3&int2=5&limit=5&str1="+tc.str1+"&str2="+tc.str2, nil)
+			rec := httptest.NewRecorder()
+
+			h.FizzBuzz(rec, req)
+
+			if rec.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tc.expectedStatus, rec.Code)
+			}
+			if tc.expectedError != "" {
+				assertErrorResponse(t, rec.Body.Bytes(), tc.expectedError)
+			}
+		})
+	}
+}
+
+func TestHandler_FizzBuzz_IntegerForms(t *testing.T) {
+	tests := []struct {
+		name           string
+		strict         bool
+		queryParams    string
+		expectedStatus int
+		expectedError  string
+	}{
+		{"plain", false, "int1=3&limit=5", http.StatusOK, ""},
+		{"escaped plus", false, "int1=%2B3&limit=5", http.StatusOK, ""},
+		{"unescaped plus", false, "int1=+3&limit=5", http.StatusOK, ""},
+		{"leading zero", false, "int1=3&limit=015", http.StatusOK, ""},
+		{"strict plain", true, "int1=3&limit=5", http.StatusOK, ""},
+		{"strict plus", true, "int1=+3&limit=5", http.StatusOK, ""},
+		{"strict leading zero", true, "int1=3&limit=015", http.StatusBadRequest, "limit must not have leading zeros"},
+		{"strict signed leading zero", true, "int1=+03&limit=5", http.StatusBadRequest, "int1 must not have leading zeros"},
+		{"strict zero start", true, "int1=3&limit=5&start=0", http.StatusOK, ""},
+		{"double sign", false, "int1=+-3&limit=5", http.StatusBadRequest, `int1 must be a valid integer (got " -3")`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(statistics.NewStore(), nil, WithStrictIntegers(tc.strict))
+
+			req := httptest.NewRequest(http.MethodGet, "/fizzbuzz?int2=5&str1=fizz&str2=buzz&"+tc.queryParams, nil)
+			rec := httptest.NewRecorder()
+
+			h.FizzBuzz(rec, req)
+
+			if rec.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.expectedStatus, rec.Code, rec.Body.String())
+			}
+			if tc.expectedError != "" {
+				assertErrorResponse(t, rec.Body.Bytes(), tc.expectedError)
+			}
+		})
+	}
+}
+
+func TestHandler_FizzBuzz_NoReplacementsWarning(t *testing.T) {
+	tests := []struct {
+		name        string
+		strict      bool
+		queryParams string
+		wantWarning bool
+	}{
+		{"strict divisors above limit", true, "int1=7&int2=9&limit=5", true},
+		{"strict one divisor within limit", true, "int1=7&int2=5&limit=5", false},
+		{"strict divisor equal to limit", true, "int1=5&int2=9&limit=5", false},
+		{"strict range between multiples", true, "int1=7&int2=13&limit=5&start=15", true},
+		{"strict range reaching a multiple", true, "int1=7&int2=9&limit=5&start=10", false},
+		{"strict range through zero", true, "int1=7&int2=9&limit=5&start=-2", false},
+		{"strict digitsum rule", true, "int1=70&int2=90&limit=5&rule=digitsum", false},
+		{"lenient divisors above limit", false, "int1=7&int2=9&limit=5", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(statistics.NewStore(), nil, WithStrictIntegers(tc.strict))
+
+			req := httptest.NewRequest(http.MethodGet, "/fizzbuzz?str1=fizz&str2=buzz&"+tc.queryParams, nil)
+			rec := httptest.NewRecorder()
+
+			h.FizzBuzz(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+			}
+			want := ""
+			if tc.wantWarning {
+				want = "no replacements will occur"
+			}
+			if got := rec.Header().Get("X-FizzBuzz-Warning"); got != want {
+				t.Fatalf("X-FizzBuzz-Warning = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestHandler_FizzBuzz_ChecksumHeader(t *testing.T) {
+	tests := []struct {
+		name        string
+		queryParams string
+	}{
+		{"sequence", ""},
+		{"pretty", "&pretty=true"},
+		{"numeric", "&numeric=true"},
+		{"indices", "&only=str1"},
+		{"numbers only", "&only=numbers"},
+		{"download", "&download=true"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(statistics.NewStore(), nil)
+			target := "/fizzbuzz?int1=3&int2=5&limit=15&str1=fizz&str2=buzz" + tc.queryParams
+
+			rec := httptest.NewRecorder()
+			h.FizzBuzz(rec, httptest.NewRequest(http.MethodGet, target, nil))
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+			}
+			sum := sha256.Sum256(rec.Body.Bytes())
+			want := hex.EncodeToString(sum[:])
+			if got := rec.Header().Get("X-Content-SHA256"); got != want {
+				t.Fatalf("X-Content-SHA256 = %q, want %q", got, want)
+			}
+
+			head := httptest.NewRecorder()
+			h.FizzBuzz(head, httptest.NewRequest(http.MethodHead, target, nil))
+			if got := head.Header().Get("X-Content-SHA256"); got != want {
+				t.Fatalf("HEAD X-Content-SHA256 = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestHandler_FizzBuzz_ServerTiming(t *testing.T) {
+	parse := func(t *testing.T, header string) float64 {
+		t.Helper()
+		raw, ok := strings.CutPrefix(header, "gen;dur=")
+		if !ok {
+			t.Fatalf("Server-Timing = %q, want gen;dur=<ms>", header)
+		}
+		ms, err := strconv.ParseFloat(raw, 64)
+		if err != nil || ms < 0 {
+			t.Fatalf("Server-Timing duration %q is not a non-negative number", raw)
+		}
+		return ms
+	}
+	target := "/fizzbuzz?int1=3&int2=5&limit=15&str1=fizz&str2=buzz"
+
+	rec := httptest.NewRecorder()
+	NewHandler(statistics.NewStore(), nil).FizzBuzz(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	parse(t, rec.Header().Get("Server-Timing"))
+
+	synctest.Test(t, func(t *testing.T) {
+		h := NewHandler(statistics.NewStore(), nil)
+		h.generator = GeneratorFunc(func(params GenerationParams) []string {
+			time.Sleep(12 * time.Millisecond)
+			return DefaultGenerator.Generate(params)
+		})
+
+		rec := httptest.NewRecorder()
+		h.FizzBuzz(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if ms := parse(t, rec.Header().Get("Server-Timing")); ms != 12 {
+			t.Fatalf("generation took %vms, want 12ms", ms)
+		}
+	})
+}
+
+func TestHandler_FizzBuzz_NumericQuotesNonIntegers(t *testing.T) {
+	h := NewHandler(statistics.NewStore(), nil)
+	h.generator = GeneratorFunc(func(GenerationParams) []string {
+		return []string{"one", "007", "+3", "4"}
+	})
+
+	rec := httptest.NewRecorder()
+	h.FizzBuzz(rec, httptest.NewRequest(http.MethodGet, "/fizzbuzz?int1=3&int2=5&limit=4&str1=fizz&str2=buzz&numeric=true", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if !json.Valid(rec.Body.Bytes()) {
+		t.Fatalf("expected valid JSON, got %s", rec.Body.String())
+	}
+	if got, want := rec.Body.String(), `{"result":["one","007","+3",4]}`; got != want {
+		t.Fatalf("expected body %s, got %s", want, got)
+	}
+}
+
+func TestHandler_FizzBuzz_EmptyGenerationIsAnArray(t *testing.T) {
+	tests := []struct {
+		name        string
+		queryParams string
+		want        string
+	}{
+		{"strings", "", `{"result":[]}`},
+		{"shuffled", "&shuffle=true&seed=1", `{"result":[]}`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(statistics.NewStore(), nil)
+			h.generator = GeneratorFunc(func(GenerationParams) []string { return nil })
+
+			rec := httptest.NewRecorder()
+			h.FizzBuzz(rec, httptest.NewRequest(http.MethodGet, "/fizzbuzz?int1=3&int2=5&limit=15&str1=fizz&str2=buzz"+tc.queryParams, nil))
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+			}
+			if got := rec.Body.String(); got != tc.want {
+				t.Fatalf("expected body %s, got %s", tc.want, got)
+			}
+		})
+	}
+
+	numeric, err := json.Marshal(NumericFizzBuzzResponse{})
+	if err != nil {
+		t.Fatalf("failed to marshal numeric response: %v", err)
+	}
+	if got, want := string(numeric), `{"result":[]}`; got != want {
+		t.Fatalf("expected numeric body %s, got %s", want, got)
+	}
+}
+
+func TestHandler_FizzBuzz_MaxResponseBytes(t *testing.T) {
+	tests := []struct {
+		name           string
+		queryParams    string
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "short words pass a high limit",
+			queryParams:    "int1=3&int2=5&limit=1000&str1=a&str2=b",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "estimate at the bound passes",
+			queryParams:    "int1=3&int2=5&limit=250&str1=fizz&str2=buzz",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "long words trip the guard at a lower limit",
+			queryParams:    "int1=3&int2=5&limit=100&str1=fizz&str2=" + strings.Repeat("x", 20),
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "limit produces an estimated 2000 bytes, exceeding the 1000 byte response limit",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(statistics.NewStore(), nil, WithMaxResponseBytes(1000))
+
+			req := httptest.NewRequest(http.MethodGet, "/fizzbuzz?"+tc.queryParams, nil)
+			rec := httptest.NewRecorder()
+
+			h.FizzBuzz(rec, req)
+
+			if rec.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tc.expectedStatus, rec.Code)
+			}
+			if tc.expectedError != "" {
+				assertErrorResponse(t, rec.Body.Bytes(), tc.expectedError)
+			}
+		})
+	}
+}
+
+func TestHandler_FizzBuzz_ConcurrencyLimit(t *testing.T) {
+	h := NewHandler(statistics.NewStore(), nil, WithConcurrencyLimit(1, 10))
+
+	serve := func(limit int) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/fizzbuzz?int1=3&int2=5&limit=%d&str1=fizz&str2=buzz", limit), nil)
+		rec := httptest.NewRecorder()
+		h.FizzBuzz(rec, req)
+		return rec
+	}
+
+	// Occupy the only slot as an in-flight heavy generation would.
+	h.generations <- struct{}{}
+
+	rec := serve(10)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("heavy request while saturated: expected status %d, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "1" {
+		t.Fatalf("expected Retry-After 1, got %q", got)
+	}
+
+	if rec := serve(9); rec.Code != http.StatusOK {
+		t.Fatalf("small request while saturated: expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	<-h.generations
+
+	if rec := serve(10); rec.Code != http.StatusOK {
+		t.Fatalf("heavy request after release: expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if len(h.generations) != 0 {
+		t.Fatalf("expected slot to be released, %d still held", len(h.generations))
+	}
+}
+
+func TestHandler_FizzBuzz_TruncatedFieldsOmittedByDefault(t *testing.T) {
+	h := NewHandler(statistics.NewStore(), nil, WithMaxLimit(100, true))
+
+	req := httptest.NewRequest(http.MethodGet, "/fizzbuzz?int1=3&int2=5&limit=15&str1=fizz&str2=buzz", nil)
+	rec := httptest.NewRecorder()
+
+	h.FizzBuzz(rec, req)
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	if _, ok := payload["truncated"]; ok {
+		t.Fatalf("expected truncated to be omitted, got %v", payload["truncated"])
+	}
+	if _, ok := payload["returned"]; ok {
+		t.Fatalf("expected returned to be omitted, got %v", payload["returned"])
+	}
+}
+
+func assertJSONResponse(t *testing.T, body []byte, expected interface{}) {
+	t.Helper()
+
+	value := reflect.New(reflect.TypeOf(expected))
+	if err := json.Unmarshal(body, value.Interface()); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	got := value.Elem().Interface()
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected body %+v, got %+v", expected, got)
+	}
+}
+
+func assertErrorResponse(t *testing.T, body []byte, expectedMessage string) {
+	t.Helper()
+
+	var resp ErrorResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatalf("failed to unmarshal error response: %v", err)
+	}
+
+	if resp.Error != expectedMessage {
+		t.Fatalf("expected error message %q, got %q", expectedMessage, resp.Error)
+	}
+}
+
+func TestHandler_FizzBuzz_EchoParams(t *testing.T) {
+	tests := []struct {
+		name           string
+		queryParams    string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "default omits params",
+			queryParams:    "int1=3&int2=5&limit=3&str1=fizz&str2=buzz",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"result":["1","2","fizz"]}`,
+		},
+		{
+			name:           "echo false omits params",
+			queryParams:    "int1=3&int2=5&limit=3&str1=fizz&str2=buzz&echo_params=false",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"result":["1","2","fizz"]}`,
+		},
+		{
+			name:           "echo",
+			queryParams:    "int1=%2B3&int2=5&limit=3&str1=fizz&str2=buzz&echo_params=true",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"params":{"int1":3,"int2":5,"limit":3,"str1":"fizz","str2":"buzz"},"result":["1","2","fizz"]}`,
+		},
+		{
+			name:           "echo numeric",
+			queryParams:    "int1=3&int2=5&limit=3&str1=fizz&str2=buzz&echo_params=true&numeric=true",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"params":{"int1":3,"int2":5,"limit":3,"str1":"fizz","str2":"buzz"},"result":[1,2,"fizz"]}`,
+		},
+		{
+			name:           "echo indices",
+			queryParams:    "int1=3&int2=5&limit=6&str1=fizz&str2=buzz&echo_params=true&only=str1",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"params":{"int1":3,"int2":5,"limit":6,"str1":"fizz","str2":"buzz"},"indices":[3,6]}`,
+		},
+		{
+			name:           "invalid echo",
+			queryParams:    "int1=3&int2=5&limit=3&str1=fizz&str2=buzz&echo_params=maybe",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"echo_params must be a boolean"}`,
+		},
+		{
+			name:           "echo with stream",
+			queryParams:    "int1=3&int2=5&limit=3&str1=fizz&str2=buzz&echo_params=true&stream=true",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"echo_params cannot be combined with stream"}`,
+		},
+		{
+			name:           "echo with download",
+			queryParams:    "int1=3&int2=5&limit=3&str1=fizz&str2=buzz&echo_params=true&download=true",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"echo_params cannot be combined with download"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(statistics.NewStore(), nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/fizzbuzz?"+tc.queryParams, nil)
+			rec := httptest.NewRecorder()
+			h.FizzBuzz(rec, req)
+
+			if rec.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tc.expectedStatus, rec.Code)
+			}
+			if body := strings.TrimSpace(rec.Body.String()); body != tc.expectedBody {
+				t.Fatalf("expected body %s, got %s", tc.expectedBody, body)
+			}
+		})
+	}
+}
+
+func TestHandler_FizzBuzz_FormatMap(t *testing.T) {
+	tests := []struct {
+		name           string
+		queryParams    string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "keyed by number",
+			queryParams:    "int1=3&int2=5&limit=5&str1=fizz&str2=buzz&format=map",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"result":{"1":"1","2":"2","3":"fizz","4":"4","5":"buzz"}}`,
+		},
+		{
+			name:           "keys follow start",
+			queryParams:    "int1=3&int2=5&start=-1&limit=3&str1=fizz&str2=buzz&format=map",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"result":{"-1":"-1","0":"fizzbuzz","1":"1"}}`,
+		},
+		{
+			name:           "list is the default",
+			queryParams:    "int1=3&int2=5&limit=3&str1=fizz&str2=buzz&format=list",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"result":["1","2","fizz"]}`,
+		},
+		{
+			name:           "echo params",
+			queryParams:    "int1=3&int2=5&limit=1&str1=fizz&str2=buzz&format=map&echo_params=true",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"params":{"int1":3,"int2":5,"limit":1,"str1":"fizz","str2":"buzz"},"result":{"1":"1"}}`,
+		},
+		{
+			name:           "unknown format",
+			queryParams:    "int1=3&int2=5&limit=3&str1=fizz&str2=buzz&format=csv",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"format must be one of: list, map"}`,
+		},
+		{
+			name:           "map with numeric",
+			queryParams:    "int1=3&int2=5&limit=3&str1=fizz&str2=buzz&format=map&numeric=true",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"format map cannot be combined with numeric"}`,
+		},
+		{
+			name:           "map with stream",
+			queryParams:    "int1=3&int2=5&limit=3&str1=fizz&str2=buzz&format=map&stream=true",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"format map cannot be combined with stream"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(statistics.NewStore(), nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/fizzbuzz?"+tc.queryParams, nil)
+			rec := httptest.NewRecorder()
+			h.FizzBuzz(rec, req)
+
+			if rec.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tc.expectedStatus, rec.Code)
+			}
+			if body := strings.TrimSpace(rec.Body.String()); body != tc.expectedBody {
+				t.Fatalf("expected body %s, got %s", tc.expectedBody, body)
+			}
+		})
+	}
+}
+
+func TestHandler_FizzBuzz_FormatMapMatchesList(t *testing.T) {
+	h := NewHandler(statistics.NewStore(), nil)
+	target := "/fizzbuzz?int1=3&int2=5&limit=100&str1=fizz&str2=buzz"
+
+	listed := httptest.NewRecorder()
+	h.FizzBuzz(listed, httptest.NewRequest(http.MethodGet, target, nil))
+	keyed := httptest.NewRecorder()
+	h.FizzBuzz(keyed, httptest.NewRequest(http.MethodGet, target+"&format=map", nil))
+
+	var list FizzBuzzResponse
+	if err := json.Unmarshal(listed.Body.Bytes(), &list); err != nil {
+		t.Fatalf("failed to decode list response: %v", err)
+	}
+	var keyedBody MapFizzBuzzResponse
+	if err := json.Unmarshal(keyed.Body.Bytes(), &keyedBody); err != nil {
+		t.Fatalf("failed to decode map response: %v", err)
+	}
+	if len(keyedBody.Result) != len(list.Result) {
+		t.Fatalf("expected %d entries, got %d", len(list.Result), len(keyedBody.Result))
+	}
+	for i, value := range list.Result {
+		if got := keyedBody.Result[int64(i+1)]; got != value {
+			t.Fatalf("entry %d = %q, want %q", i+1, got, value)
+		}
+	}
+}
+
+func TestHandler_FizzBuzz_Base(t *testing.T) {
+	tests := []struct {
+		name           string
+		queryParams    string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "base 2",
+			queryParams:    "int1=3&int2=5&limit=8&str1=fizz&str2=buzz&base=2",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"result":["1","10","fizz","100","buzz","fizz","111","1000"]}`,
+		},
+		{
+			name:           "base 16",
+			queryParams:    "int1=3&int2=5&start=10&limit=5&str1=fizz&str2=buzz&base=16",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"result":["buzz","b","fizz","d","e"]}`,
+		},
+		{
+			name:           "base 16 numbers only",
+			queryParams:    "int1=3&int2=5&start=10&limit=5&str1=fizz&str2=buzz&base=16&only=numbers",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"result":["b","d","e"]}`,
+		},
+		{
+			name:           "base 10 numeric",
+			queryParams:    "int1=3&int2=5&limit=3&str1=fizz&str2=buzz&base=10&numeric=true",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"result":[1,2,"fizz"]}`,
+		},
+		{
+			name:           "base too small",
+			queryParams:    "int1=3&int2=5&limit=3&str1=fizz&str2=buzz&base=1",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"base must be between 2 and 36"}`,
+		},
+		{
+			name:           "base too large",
+			queryParams:    "int1=3&int2=5&limit=3&str1=fizz&str2=buzz&base=37",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"base must be between 2 and 36"}`,
+		},
+		{
+			name:           "base not an integer",
+			queryParams:    "int1=3&int2=5&limit=3&str1=fizz&str2=buzz&base=hex",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"base must be a valid integer (got \"hex\")"}`,
+		},
+		{
+			name:           "base 16 numeric",
+			queryParams:    "int1=3&int2=5&limit=3&str1=fizz&str2=buzz&base=16&numeric=true",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"base cannot be combined with numeric"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(statistics.NewStore(), nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/fizzbuzz?"+tc.queryParams, nil)
+			rec := httptest.NewRecorder()
+			h.FizzBuzz(rec, req)
+
+			if rec.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tc.expectedStatus, rec.Code)
+			}
+			if body := strings.TrimSpace(rec.Body.String()); body != tc.expectedBody {
+				t.Fatalf("expected body %s, got %s", tc.expectedBody, body)
+			}
+		})
+	}
+}
+
+func TestHandler_FizzBuzz_Rule(t *testing.T) {
+	tests := []struct {
+		name           string
+		queryParams    string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "default divisibility",
+			queryParams:    "int1=3&int2=5&start=10&limit=6&str1=fizz&str2=buzz",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"result":["buzz","11","fizz","13","14","fizzbuzz"]}`,
+		},
+		{
+			name:           "explicit divisibility",
+			queryParams:    "int1=3&int2=5&start=10&limit=6&str1=fizz&str2=buzz&rule=divisible",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"result":["buzz","11","fizz","13","14","fizzbuzz"]}`,
+		},
+		{
+			name:           "digit sum",
+			queryParams:    "int1=3&int2=5&start=10&limit=6&str1=fizz&str2=buzz&rule=digitsum",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"result":["10","11","fizz","13","buzz","fizz"]}`,
+		},
+		{
+			name:           "digit sum indices",
+			queryParams:    "int1=3&int2=5&start=10&limit=6&str1=fizz&str2=buzz&rule=digitsum&only=str2",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"indices":[5]}`,
+		},
+		{
+			name:           "digit sum numeric",
+			queryParams:    "int1=3&int2=5&start=10&limit=3&str1=fizz&str2=buzz&rule=digitsum&numeric=true",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"result":[10,11,"fizz"]}`,
+		},
+		{
+			name:           "unknown rule",
+			queryParams:    "int1=3&int2=5&limit=3&str1=fizz&str2=buzz&rule=prime",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"rule must be one of: divisible, digitsum"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(statistics.NewStore(), nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/fizzbuzz?"+tc.queryParams, nil)
+			rec := httptest.NewRecorder()
+			h.FizzBuzz(rec, req)
+
+			if rec.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tc.expectedStatus, rec.Code)
+			}
+			if body := strings.TrimSpace(rec.Body.String()); body != tc.expectedBody {
+				t.Fatalf("expected body %s, got %s", tc.expectedBody, body)
+			}
+		})
+	}
+}
+
+func TestHandler_FizzBuzz_CollapseEqual(t *testing.T) {
+	tests := []struct {
+		name           string
+		queryParams    string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "equal words concatenated by default",
+			queryParams:    "int1=3&int2=5&start=14&limit=2&str1=foo&str2=foo",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"result":["14","foofoo"]}`,
+		},
+		{
+			name:           "equal words collapsed",
+			queryParams:    "int1=3&int2=5&start=14&limit=2&str1=foo&str2=foo&collapse_equal=true",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"result":["14","foo"]}`,
+		},
+		{
+			name:           "different words unaffected",
+			queryParams:    "int1=3&int2=5&start=14&limit=2&str1=fizz&str2=buzz&collapse_equal=true",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"result":["14","fizzbuzz"]}`,
+		},
+		{
+			name:           "invalid flag",
+			queryParams:    "int1=3&int2=5&limit=2&str1=foo&str2=foo&collapse_equal=yes",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"collapse_equal must be a boolean"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(statistics.NewStore(), nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/fizzbuzz?"+tc.queryParams, nil)
+			rec := httptest.NewRecorder()
+			h.FizzBuzz(rec, req)
+
+			if rec.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tc.expectedStatus, rec.Code)
+			}
+			if body := strings.TrimSpace(rec.Body.String()); body != tc.expectedBody {
+				t.Fatalf("expected body %s, got %s", tc.expectedBody, body)
+			}
+		})
+	}
+}
+
+func TestHandler_FizzBuzz_Str3(t *testing.T) {
+	tests := []struct {
+		name           string
+		queryParams    string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "str3 replaces values divisible by both",
+			queryParams:    "int1=3&int2=5&start=14&limit=2&str1=fizz&str2=buzz&str3=bang",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"result":["14","bang"]}`,
+		},
+		{
+			name:           "absent str3 falls back to concatenation",
+			queryParams:    "int1=3&int2=5&start=14&limit=2&str1=fizz&str2=buzz",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"result":["14","fizzbuzz"]}`,
+		},
+		{
+			name:           "str3 leaves single matches alone",
+			queryParams:    "int1=3&int2=5&start=9&limit=2&str1=fizz&str2=buzz&str3=bang",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"result":["fizz","buzz"]}`,
+		},
+		{
+			name:           "str3 wins over collapse_equal",
+			queryParams:    "int1=3&int2=5&start=15&limit=1&str1=foo&str2=foo&str3=bang&collapse_equal=true",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"result":["bang"]}`,
+		},
+		{
+			name:           "empty str3",
+			queryParams:    "int1=3&int2=5&limit=2&str1=fizz&str2=buzz&str3=",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"str3 cannot be empty"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(statistics.NewStore(), nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/fizzbuzz?"+tc.queryParams, nil)
+			rec := httptest.NewRecorder()
+			h.FizzBuzz(rec, req)
+
+			if rec.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tc.expectedStatus, rec.Code)
+			}
+			if body := strings.TrimSpace(rec.Body.String()); body != tc.expectedBody {
+				t.Fatalf("expected body %s, got %s", tc.expectedBody, body)
+			}
+		})
 	}
 }
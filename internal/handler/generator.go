@@ -0,0 +1,44 @@
+package handler
+
+import "github.com/Cerebrovinny/fizz-buzz-rest/internal/fizzbuzz"
+
+// GenerationParams describes one FizzBuzz generation: Count values from
+// Start, rendered with the divisors, words and options given. It is
+// comparable, so identical generations can be recognised.
+type GenerationParams struct {
+	Int1, Int2 int64
+	Start      int64
+	Count      int
+	Str1, Str2 string
+	Options    fizzbuzz.Options
+}
+
+// Generator produces the FizzBuzz sequences /fizzbuzz serves, whole or one
+// chunk at a time when streaming. A result may be shared by concurrent
+// identical requests, so neither the generator nor its callers may modify
+// it afterwards.
+type Generator interface {
+	Generate(params GenerationParams) []string
+}
+
+// GeneratorFunc adapts an ordinary function to Generator.
+type GeneratorFunc func(params GenerationParams) []string
+
+// Generate calls f(params).
+func (f GeneratorFunc) Generate(params GenerationParams) []string {
+	return f(params)
+}
+
+// DefaultGenerator generates with fizzbuzz.GenerateWith.
+var DefaultGenerator Generator = GeneratorFunc(func(p GenerationParams) []string {
+	return fizzbuzz.GenerateWith(p.Int1, p.Int2, p.Start, p.Count, p.Str1, p.Str2, p.Options)
+})
+
+// WithGenerator replaces the generator behind /fizzbuzz sequences, for
+// instrumentation or alternative implementations. nil keeps
+// DefaultGenerator.
+func WithGenerator(g Generator) Option {
+	return func(h *Handler) {
+		h.generator = g
+	}
+}
@@ -1,13 +1,135 @@
 package handler
 
-import "net/http"
+import (
+	"context"
+	"net/http"
+)
 
 type HealthResponse struct {
 	Status  string `json:"status"`
 	Service string `json:"service"`
 }
 
+// HealthChecker reports whether a dependency is ready to serve traffic.
+type HealthChecker interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+// CheckResult describes the outcome of a single readiness check.
+type CheckResult struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ReadyResponse represents the payload returned by the readiness endpoint.
+type ReadyResponse struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks"`
+}
+
+// ComponentStatus is the state of one dependency in the detailed health
+// report.
+type ComponentStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// DetailedHealthResponse extends HealthResponse with the state of the
+// statistics store and of every registered HealthChecker, keyed by name.
+type DetailedHealthResponse struct {
+	Status     string                     `json:"status"`
+	Service    string                     `json:"service"`
+	Components map[string]ComponentStatus `json:"components"`
+}
+
+// RegisterHealthChecker adds a checker that is run on every readiness probe.
+func (h *Handler) RegisterHealthChecker(checker HealthChecker) {
+	h.checkers = append(h.checkers, checker)
+}
+
 func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Cache-Control", "no-store")
-	respondJSON(h.logger, w, http.StatusOK, HealthResponse{Status: "ok", Service: "fizzbuzz-api"})
+	h.respondHealth(w, r)
+}
+
+// ToggleHealth flips the forced-unhealthy flag and reports the resulting
+// health status. It is intended for chaos testing behind admin auth.
+func (h *Handler) ToggleHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	for {
+		current := h.unhealthy.Load()
+		if h.unhealthy.CompareAndSwap(current, !current) {
+			break
+		}
+	}
+	h.respondHealth(w, r)
+}
+
+func (h *Handler) respondHealth(w http.ResponseWriter, r *http.Request) {
+	if h.unhealthy.Load() {
+		h.respondJSON(w, r, http.StatusServiceUnavailable, HealthResponse{Status: "unhealthy", Service: "fizzbuzz-api"})
+		return
+	}
+	h.respondJSON(w, r, http.StatusOK, HealthResponse{Status: "ok", Service: "fizzbuzz-api"})
+}
+
+// DetailedHealth reports the health of each component. It names internals,
+// so it is meant to be mounted behind admin auth; /health keeps its minimal
+// shape for public probes.
+func (h *Handler) DetailedHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+
+	response := DetailedHealthResponse{Status: "ok", Service: "fizzbuzz-api", Components: make(map[string]ComponentStatus, len(h.checkers)+1)}
+	status := http.StatusOK
+	if h.unhealthy.Load() {
+		response.Status = "unhealthy"
+		status = http.StatusServiceUnavailable
+	}
+
+	response.Components["store"] = ComponentStatus{Status: "ok"}
+	if h.store == nil {
+		response.Components["store"] = ComponentStatus{Status: "error", Error: "statistics store not configured"}
+		response.Status = "unhealthy"
+		status = http.StatusServiceUnavailable
+	}
+	for _, checker := range h.checkers {
+		component := ComponentStatus{Status: "ok"}
+		if err := checker.Check(r.Context()); err != nil {
+			component = ComponentStatus{Status: "error", Error: err.Error()}
+			response.Status = "unhealthy"
+			status = http.StatusServiceUnavailable
+		}
+		response.Components[checker.Name()] = component
+	}
+
+	h.respondJSON(w, r, status, response)
+}
+
+// Ready runs every registered checker and returns 503 if any of them fail.
+func (h *Handler) Ready(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+
+	response := ReadyResponse{Status: "ok", Checks: make([]CheckResult, 0, len(h.checkers))}
+	status := http.StatusOK
+
+	if h.unhealthy.Load() {
+		response.Status = "unavailable"
+		status = http.StatusServiceUnavailable
+		response.Checks = append(response.Checks, CheckResult{Name: "forced", Status: "error", Error: "service forced unhealthy"})
+	}
+
+	for _, checker := range h.checkers {
+		result := CheckResult{Name: checker.Name(), Status: "ok"}
+		if err := checker.Check(r.Context()); err != nil {
+			result.Status = "error"
+			result.Error = err.Error()
+			response.Status = "unavailable"
+			status = http.StatusServiceUnavailable
+		}
+		response.Checks = append(response.Checks, result)
+	}
+
+	h.respondJSON(w, r, status, response)
 }
@@ -1,7 +1,9 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -112,6 +114,169 @@ func TestHandler_Health_MultipleRequests(t *testing.T) {
 	}
 }
 
+type stubChecker struct {
+	name string
+	err  error
+}
+
+func (c stubChecker) Name() string { return c.name }
+
+func (c stubChecker) Check(context.Context) error { return c.err }
+
+func TestHandler_Ready(t *testing.T) {
+	tests := []struct {
+		name           string
+		checkers       []HealthChecker
+		expectedStatus int
+		expected       ReadyResponse
+	}{
+		{
+			name:           "no checkers",
+			expectedStatus: http.StatusOK,
+			expected:       ReadyResponse{Status: "ok", Checks: []CheckResult{}},
+		},
+		{
+			name:           "passing checker",
+			checkers:       []HealthChecker{stubChecker{name: "store"}},
+			expectedStatus: http.StatusOK,
+			expected: ReadyResponse{Status: "ok", Checks: []CheckResult{
+				{Name: "store", Status: "ok"},
+			}},
+		},
+		{
+			name: "failing checker marks service unavailable",
+			checkers: []HealthChecker{
+				stubChecker{name: "store"},
+				stubChecker{name: "cache", err: errors.New("connection refused")},
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+			expected: ReadyResponse{Status: "unavailable", Checks: []CheckResult{
+				{Name: "store", Status: "ok"},
+				{Name: "cache", Status: "error", Error: "connection refused"},
+			}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(statistics.NewStore(), nil)
+			for _, checker := range tc.checkers {
+				h.RegisterHealthChecker(checker)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/ready", nil)
+			rec := httptest.NewRecorder()
+			h.Ready(rec, req)
+
+			if rec.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tc.expectedStatus, rec.Code)
+			}
+			if cacheControl := rec.Header().Get("Cache-Control"); cacheControl != "no-store" {
+				t.Fatalf("expected Cache-Control no-store, got %q", cacheControl)
+			}
+
+			assertJSONResponse(t, rec.Body.Bytes(), tc.expected)
+		})
+	}
+}
+
+func TestHandler_DetailedHealth(t *testing.T) {
+	tests := []struct {
+		name           string
+		store          *statistics.Store
+		checkers       []HealthChecker
+		expectedStatus int
+		expected       DetailedHealthResponse
+	}{
+		{
+			name:           "store only",
+			store:          statistics.NewStore(),
+			expectedStatus: http.StatusOK,
+			expected: DetailedHealthResponse{Status: "ok", Service: "fizzbuzz-api", Components: map[string]ComponentStatus{
+				"store": {Status: "ok"},
+			}},
+		},
+		{
+			name:           "failing persistence",
+			store:          statistics.NewStore(),
+			checkers:       []HealthChecker{stubChecker{name: "statistics_persistence", err: errors.New("read-only file system")}},
+			expectedStatus: http.StatusServiceUnavailable,
+			expected: DetailedHealthResponse{Status: "unhealthy", Service: "fizzbuzz-api", Components: map[string]ComponentStatus{
+				"store":                  {Status: "ok"},
+				"statistics_persistence": {Status: "error", Error: "read-only file system"},
+			}},
+		},
+		{
+			name:           "missing store",
+			expectedStatus: http.StatusServiceUnavailable,
+			expected: DetailedHealthResponse{Status: "unhealthy", Service: "fizzbuzz-api", Components: map[string]ComponentStatus{
+				"store": {Status: "error", Error: "statistics store not configured"},
+			}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(tc.store, nil)
+			for _, checker := range tc.checkers {
+				h.RegisterHealthChecker(checker)
+			}
+
+			rec := httptest.NewRecorder()
+			h.DetailedHealth(rec, httptest.NewRequest(http.MethodGet, "/health/detailed", nil))
+
+			if rec.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tc.expectedStatus, rec.Code)
+			}
+			if cacheControl := rec.Header().Get("Cache-Control"); cacheControl != "no-store" {
+				t.Fatalf("expected Cache-Control no-store, got %q", cacheControl)
+			}
+
+			assertJSONResponse(t, rec.Body.Bytes(), tc.expected)
+		})
+	}
+}
+
+func TestHandler_ForceUnhealthy(t *testing.T) {
+	h := NewHandler(statistics.NewStore(), nil, WithForceUnhealthy(true))
+
+	rec := callHealthHandler(t, h)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status %d, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+	assertJSONResponse(t, rec.Body.Bytes(), HealthResponse{Status: "unhealthy", Service: "fizzbuzz-api"})
+
+	ready := httptest.NewRecorder()
+	h.Ready(ready, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	if ready.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected ready status %d, got %d", http.StatusServiceUnavailable, ready.Code)
+	}
+}
+
+func TestHandler_ToggleHealth(t *testing.T) {
+	h := NewHandler(statistics.NewStore(), nil)
+
+	toggle := func() int {
+		rec := httptest.NewRecorder()
+		h.ToggleHealth(rec, httptest.NewRequest(http.MethodPost, "/health/toggle", nil))
+		return rec.Code
+	}
+
+	if code := toggle(); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected first toggle to report %d, got %d", http.StatusServiceUnavailable, code)
+	}
+	if rec := callHealthHandler(t, h); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected health status %d after toggle, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+
+	if code := toggle(); code != http.StatusOK {
+		t.Fatalf("expected second toggle to report %d, got %d", http.StatusOK, code)
+	}
+	if rec := callHealthHandler(t, h); rec.Code != http.StatusOK {
+		t.Fatalf("expected health status %d after second toggle, got %d", http.StatusOK, rec.Code)
+	}
+}
+
 func callHealthHandler(t *testing.T, h *Handler) *httptest.ResponseRecorder {
 	t.Helper()
 	req := httptest.NewRequest(http.MethodGet, "/health", nil)
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+)
+
+// codeGenerationPanic is the ErrorResponse code of a generation that
+// panicked.
+const codeGenerationPanic = "generation_panic"
+
+var errGenerationPanic = errors.New("generation failed")
+
+// recoverGeneration runs generate, turning a panic into errGenerationPanic
+// logged with its stack, so the client gets a JSON 500 rather than the plain
+// text of chi's Recoverer. http.ErrAbortHandler is re-raised, since it is
+// how a handler deliberately aborts a response.
+func (h *Handler) recoverGeneration(r *http.Request, generate func() []string) (result []string, err error) {
+	defer func() {
+		p := recover()
+		if p == nil {
+			return
+		}
+		if p == http.ErrAbortHandler {
+			panic(p)
+		}
+		h.logger.Error("generation panicked",
+			slog.Any("panic", p),
+			slog.String("path", r.URL.Path),
+			slog.String("query", r.URL.RawQuery),
+			slog.String("stack", string(debug.Stack())),
+		)
+		err = errGenerationPanic
+	}()
+	return generate(), nil
+}
+
+// respondGenerationPanic answers a request whose generation panicked.
+func (h *Handler) respondGenerationPanic(w http.ResponseWriter, r *http.Request) {
+	if wantsProblem(r) {
+		h.respondProblem(w, r, http.StatusInternalServerError, errGenerationPanic.Error(), nil)
+		return
+	}
+	h.respondJSON(w, r, http.StatusInternalServerError, ErrorResponse{Error: errGenerationPanic.Error(), Code: codeGenerationPanic})
+}
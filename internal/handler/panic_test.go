@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/statistics"
+)
+
+func TestHandler_FizzBuzz_GenerationPanic(t *testing.T) {
+	var logs bytes.Buffer
+	h := NewHandler(statistics.NewStore(), slog.New(slog.NewTextHandler(&logs, nil)))
+	h.generator = GeneratorFunc(func(GenerationParams) []string {
+		panic("exotic input")
+	})
+
+	target := "/fizzbuzz?int1=3&int2=5&limit=15&str1=fizz&str2=buzz"
+	rec := httptest.NewRecorder()
+	h.FizzBuzz(rec, httptest.NewRequest(http.MethodGet, target, nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status %d, got %d", http.StatusInternalServerError, rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("expected JSON content type, got %q", ct)
+	}
+	if want, got := `{"error":"generation failed","code":"generation_panic"}`, rec.Body.String(); got != want {
+		t.Fatalf("expected body %s, got %s", want, got)
+	}
+	for _, want := range []string{"generation panicked", "exotic input", "stack="} {
+		if !strings.Contains(logs.String(), want) {
+			t.Fatalf("expected log to contain %q, got %s", want, logs.String())
+		}
+	}
+
+	// The panicking call must not stay registered as in flight.
+	h.generator = DefaultGenerator
+	rec = httptest.NewRecorder()
+	h.FizzBuzz(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d after recovery, got %d", http.StatusOK, rec.Code)
+	}
+}
+
+func TestHandler_FizzBuzz_StreamGenerationPanic(t *testing.T) {
+	var logs bytes.Buffer
+	h := NewHandler(statistics.NewStore(), slog.New(slog.NewTextHandler(&logs, nil)))
+	h.generator = GeneratorFunc(func(params GenerationParams) []string {
+		if params.Start > 1 {
+			panic("exotic input")
+		}
+		return DefaultGenerator.Generate(params)
+	})
+
+	rec := httptest.NewRecorder()
+	h.FizzBuzz(rec, httptest.NewRequest(http.MethodGet, "/fizzbuzz?int1=3&int2=5&limit=2500&str1=fizz&str2=buzz&stream=true", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	var body FizzBuzzResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("expected valid JSON, got %v: %.200s", err, rec.Body.String())
+	}
+	if !body.Truncated || body.Returned != streamChunkSize || len(body.Result) != streamChunkSize {
+		t.Fatalf("expected the first chunk marked truncated, got truncated=%t returned=%d with %d results",
+			body.Truncated, body.Returned, len(body.Result))
+	}
+	if !strings.Contains(logs.String(), "generation panicked") {
+		t.Fatalf("expected the panic to be logged, got %s", logs.String())
+	}
+}
+
+func TestHandler_FizzBuzz_GenerationPanicProblem(t *testing.T) {
+	h := NewHandler(statistics.NewStore(), nil)
+	h.generator = GeneratorFunc(func(GenerationParams) []string {
+		panic("exotic input")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/fizzbuzz?int1=3&int2=5&limit=15&str1=fizz&str2=buzz", nil)
+	req.Header.Set("Accept", problemContentType)
+	rec := httptest.NewRecorder()
+	h.FizzBuzz(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status %d, got %d", http.StatusInternalServerError, rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != problemContentType {
+		t.Fatalf("expected %s, got %q", problemContentType, ct)
+	}
+}
+
+func TestHandler_FizzBuzz_AbortHandlerIsNotRecovered(t *testing.T) {
+	h := NewHandler(statistics.NewStore(), nil)
+	h.generator = GeneratorFunc(func(GenerationParams) []string {
+		panic(http.ErrAbortHandler)
+	})
+
+	defer func() {
+		if p := recover(); p != http.ErrAbortHandler {
+			t.Fatalf("expected http.ErrAbortHandler to propagate, got %v", p)
+		}
+	}()
+	h.FizzBuzz(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fizzbuzz?int1=3&int2=5&limit=15&str1=fizz&str2=buzz", nil))
+}
@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var bufferPool = sync.Pool{
+	New: func() any {
+		return new(bytes.Buffer)
+	},
+}
+
+// maxPooledBufferSize is the largest buffer returned to bufferPool, so one
+// huge response does not keep megabytes alive for the life of the pool.
+const maxPooledBufferSize = 64 << 10
+
+// putBuffer returns buf to bufferPool unless it grew past
+// maxPooledBufferSize.
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	bufferPool.Put(buf)
+}
+
+const problemContentType = "application/problem+json"
+
+// ProblemResponse is an RFC 7807 problem details payload.
+type ProblemResponse struct {
+	Type          string         `json:"type"`
+	Title         string         `json:"title"`
+	Status        int            `json:"status"`
+	Detail        string         `json:"detail,omitempty"`
+	InvalidParams []InvalidParam `json:"invalid-params,omitempty"`
+}
+
+// respondJSON writes data as JSON. It tolerates a nil Handler so that
+// misconfigured routes still produce a well-formed response.
+func (h *Handler) respondJSON(w http.ResponseWriter, r *http.Request, status int, data interface{}) {
+	h.writeJSON(w, r, status, "application/json", data)
+}
+
+// respondChecksummedJSON is respondJSON with an X-Content-SHA256 header
+// holding the hex SHA-256 of the body, so clients can verify the transfer.
+func (h *Handler) respondChecksummedJSON(w http.ResponseWriter, r *http.Request, status int, data interface{}) {
+	h.encodeJSON(w, r, status, "application/json", data, true)
+}
+
+func (h *Handler) writeJSON(w http.ResponseWriter, r *http.Request, status int, contentType string, data interface{}) {
+	h.encodeJSON(w, r, status, contentType, data, false)
+}
+
+func (h *Handler) encodeJSON(w http.ResponseWriter, r *http.Request, status int, contentType string, data interface{}, checksum bool) {
+	logger := discardLogger
+	if h != nil {
+		logger = h.logger
+	}
+
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer putBuffer(buf)
+
+	encoder := json.NewEncoder(buf)
+	if wantsPretty(r) {
+		encoder.SetIndent("", "  ")
+	}
+
+	if err := encoder.Encode(data); err != nil {
+		logger.Error("json marshal error", slog.String("error", err.Error()))
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	// Encode appends a newline that json.Marshal does not; drop it so the
+	// payload stays byte-for-byte identical to the marshaled form.
+	payload := bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+
+	w.Header().Set("Content-Type", contentType)
+	if checksum {
+		setChecksum(w, payload)
+	}
+	if r != nil && r.Method == http.MethodHead {
+		// HEAD gets the headers a GET would, including the body length
+		// already known from encoding, but no body.
+		w.Header().Set("Content-Length", strconv.Itoa(len(payload)))
+		w.WriteHeader(status)
+		return
+	}
+	w.WriteHeader(status)
+	if _, err := w.Write(payload); err != nil {
+		logger.Error("json response write error", slog.String("error", err.Error()))
+		if h != nil {
+			h.writeErrors.Inc()
+		}
+	}
+}
+
+// discardLogger stands in for a missing logger.
+var discardLogger = slog.New(slog.DiscardHandler)
+
+// checksumHeader carries the hex SHA-256 of a /fizzbuzz response body.
+const checksumHeader = "X-Content-SHA256"
+
+func setChecksum(w http.ResponseWriter, body []byte) {
+	sum := sha256.Sum256(body)
+	w.Header().Set(checksumHeader, hex.EncodeToString(sum[:]))
+}
+
+// respondError writes an error in the default {"error": ...} shape, or as
+// problem+json when the client asks for it.
+func (h *Handler) respondError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	if wantsProblem(r) {
+		h.respondProblem(w, r, status, message, nil)
+		return
+	}
+	h.respondJSON(w, r, status, ErrorResponse{Error: message})
+}
+
+// respondValidationError reports a request parsing failure with the status
+// validationStatus maps it to, including the offending parameters in problem+json
+// responses.
+func (h *Handler) respondValidationError(w http.ResponseWriter, r *http.Request, err error) {
+	var invalid []InvalidParam
+	var verr *validationError
+	if errors.As(err, &verr) {
+		invalid = verr.params
+	}
+
+	status := h.validationStatus(err)
+	if wantsProblem(r) {
+		h.respondProblem(w, r, status, err.Error(), invalid)
+		return
+	}
+	h.respondJSON(w, r, status, ErrorResponse{Error: err.Error()})
+}
+
+func (h *Handler) respondProblem(w http.ResponseWriter, r *http.Request, status int, detail string, invalid []InvalidParam) {
+	h.writeJSON(w, r, status, problemContentType, ProblemResponse{
+		Type:          "about:blank",
+		Title:         http.StatusText(status),
+		Status:        status,
+		Detail:        detail,
+		InvalidParams: invalid,
+	})
+}
+
+func wantsProblem(r *http.Request) bool {
+	if r == nil {
+		return false
+	}
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err == nil && mediaType == problemContentType {
+			return true
+		}
+	}
+	return false
+}
+
+// wantsPretty reports whether the client asked for indented JSON, either via
+// ?pretty=true or an Accept header carrying an "indent" parameter.
+func wantsPretty(r *http.Request) bool {
+	if r == nil {
+		return false
+	}
+	if pretty, err := strconv.ParseBool(r.URL.Query().Get("pretty")); err == nil && pretty {
+		return true
+	}
+	_, ok := acceptParam(r, "indent")
+	return ok
+}
+
+// acceptParam returns the value of a media type parameter, such as
+// "profile" in `Accept: application/json; profile=flat`, from the first
+// Accept entry that carries it.
+func acceptParam(r *http.Request, name string) (string, bool) {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		_, params, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil {
+			continue
+		}
+		if value, ok := params[name]; ok {
+			return value, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/fizzbuzz"
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/metrics"
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/statistics"
+)
+
+func TestRespondJSON_MatchesMarshal(t *testing.T) {
+	h := NewHandler(nil, nil)
+
+	tests := []struct {
+		name string
+		data interface{}
+	}{
+		{"fizzbuzz response", FizzBuzzResponse{Result: fizzbuzz.Generate(3, 5, 15, "fizz", "buzz")}},
+		{"error response", ErrorResponse{Error: "limit must be greater than 0"}},
+		{"html characters", ErrorResponse{Error: "<script>&</script>"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			want, err := json.Marshal(tc.data)
+			if err != nil {
+				t.Fatalf("failed to marshal: %v", err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := httptest.NewRecorder()
+			h.respondJSON(rec, req, http.StatusOK, tc.data)
+
+			if !bytes.Equal(rec.Body.Bytes(), want) {
+				t.Fatalf("expected body %q, got %q", want, rec.Body.Bytes())
+			}
+		})
+	}
+}
+
+func TestRespondJSON_MarshalErrorReturns500(t *testing.T) {
+	h := NewHandler(nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	h.respondJSON(rec, req, http.StatusOK, math.Inf(1))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status %d, got %d", http.StatusInternalServerError, rec.Code)
+	}
+}
+
+func TestRespondJSON_Pretty(t *testing.T) {
+	h := NewHandler(nil, nil)
+
+	data := ErrorResponse{Error: "boom"}
+	indented, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+	compact, err := json.Marshal(data)
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		accept string
+		want   []byte
+	}{
+		{"default is compact", "/", "", compact},
+		{"pretty query parameter", "/?pretty=true", "", indented},
+		{"pretty false stays compact", "/?pretty=false", "", compact},
+		{"accept indent hint", "/", "application/json; indent=2", indented},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.target, nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			rec := httptest.NewRecorder()
+
+			h.respondJSON(rec, req, http.StatusOK, data)
+
+			if !bytes.Equal(rec.Body.Bytes(), tc.want) {
+				t.Fatalf("expected body %q, got %q", tc.want, rec.Body.Bytes())
+			}
+		})
+	}
+}
+
+type failingWriter struct {
+	header http.Header
+	status int
+}
+
+func (w *failingWriter) Header() http.Header {
+	if w.header == nil {
+		w.header = http.Header{}
+	}
+	return w.header
+}
+
+func (w *failingWriter) WriteHeader(status int) { w.status = status }
+
+func (w *failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func TestPutBuffer_DropsLargeBuffers(t *testing.T) {
+	large := bytes.NewBuffer(make([]byte, 0, maxPooledBufferSize+1))
+	putBuffer(large)
+
+	if got := bufferPool.Get().(*bytes.Buffer); got == large {
+		t.Fatalf("expected a buffer of capacity %d to stay out of the pool", large.Cap())
+	}
+}
+
+func TestRespondJSON_WriteErrorLoggedAndCounted(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+	registry := metrics.NewRegistry()
+	h := NewHandler(nil, logger, WithMetrics(registry))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	h.respondJSON(&failingWriter{}, req, http.StatusOK, ErrorResponse{Error: "boom"})
+
+	var entry map[string]any
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("failed to parse log entry %q: %v", logs.String(), err)
+	}
+	if entry["msg"] != "json response write error" {
+		t.Fatalf("expected write error log, got %v", entry["msg"])
+	}
+	if entry["error"] != "broken pipe" {
+		t.Fatalf("expected error attribute %q, got %v", "broken pipe", entry["error"])
+	}
+
+	counter := registry.Counter("response_write_errors_total", "")
+	if got := counter.Value(); got != 1 {
+		t.Fatalf("expected response_write_errors_total 1, got %d", got)
+	}
+}
+
+func TestNewHandler_NilLoggerErrorResponses(t *testing.T) {
+	h := NewHandler(statistics.NewStore(), nil)
+
+	rec := httptest.NewRecorder()
+	h.FizzBuzz(rec, httptest.NewRequest(http.MethodGet, "/fizzbuzz?int1=0", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/fizzbuzz?int1=3&int2=5&limit=15&str1=fizz&str2=buzz", nil)
+	h.FizzBuzz(&failingWriter{}, req)
+	h.respondJSON(&failingWriter{}, req, http.StatusInternalServerError, ErrorResponse{Error: "boom"})
+	h.respondJSON(httptest.NewRecorder(), req, http.StatusOK, func() {})
+}
+
+func BenchmarkRespondJSON(b *testing.B) {
+	h := NewHandler(nil, nil)
+	data := FizzBuzzResponse{Result: fizzbuzz.Generate(3, 5, 100, "fizz", "buzz")}
+	req := httptest.NewRequest(http.MethodGet, "/fizzbuzz", nil)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		h.respondJSON(httptest.NewRecorder(), req, http.StatusOK, data)
+	}
+}
+
+func BenchmarkRespondJSON_MarshalBaseline(b *testing.B) {
+	data := FizzBuzzResponse{Result: fizzbuzz.Generate(3, 5, 100, "fizz", "buzz")}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			b.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+		rec.Header().Set("Content-Type", "application/json")
+		rec.WriteHeader(http.StatusOK)
+		_, _ = rec.Write(payload)
+	}
+}
@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/fizzbuzz"
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/query"
+)
+
+// defaultMaxRules caps the pairs per request when WithMaxRules is not given,
+// since every value may concatenate every word.
+const defaultMaxRules = 16
+
+// WithMaxRules caps the rule=divisor:word pairs one request may give.
+// Requests with more get 400. Zero or less keeps the default of 16.
+func WithMaxRules(n int) Option {
+	return func(h *Handler) {
+		h.maxRules = n
+	}
+}
+
+// rulePairsOnly are the /fizzbuzz parameters that belong to the two-divisor
+// form and cannot be combined with rule=divisor:word pairs.
+var rulePairsOnly = []string{
+	"int1", "int2", "str1", "str2", "str3", "only", "numeric", "collapse_equal",
+	"shuffle", "seed", "stream", "download", "echo_params", "format",
+}
+
+// ruleParams is a /fizzbuzz request that lists its divisors and words as
+// repeated rule=divisor:word parameters instead of int1/str1 and int2/str2.
+type ruleParams struct {
+	pairs []fizzbuzz.Pair
+	start int64
+	limit int
+	opts  fizzbuzz.Options
+}
+
+// hasRulePairs reports whether any rule value is a divisor:word pair rather
+// than a match mode.
+func hasRulePairs(values url.Values) bool {
+	return slices.ContainsFunc(values["rule"], func(v string) bool {
+		return strings.Contains(v, ":")
+	})
+}
+
+// fizzBuzzRules serves /fizzbuzz for rule=divisor:word pairs, applying the
+// same limit, size and concurrency policies as the two-divisor form.
+func (h *Handler) fizzBuzzRules(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+	params, err := parseRuleParams(values)
+	if err == nil {
+		err = h.checkRulePairs(params.pairs)
+	}
+	if err == nil {
+		err = h.checkIntegers(values)
+	}
+	if err != nil {
+		h.logger.Debug("validation error",
+			slog.String("error", err.Error()),
+			slog.String("path", r.URL.Path),
+		)
+		h.respondValidationError(w, r, err)
+		return
+	}
+
+	width := 0
+	for _, pair := range params.pairs {
+		width += len(pair.Word)
+	}
+	limit, truncated, err := h.capLimit(params.limit, width)
+	if err != nil {
+		h.respondValidationError(w, r, err)
+		return
+	}
+
+	release, ok := h.acquireGeneration(w, r, limit)
+	if !ok {
+		return
+	}
+	defer release()
+
+	generationStart := time.Now()
+	result, err := h.recoverGeneration(r, func() []string {
+		return fizzbuzz.GenerateRules(params.pairs, params.start, limit, params.opts)
+	})
+	if err != nil {
+		h.respondGenerationPanic(w, r)
+		return
+	}
+	setServerTiming(w, time.Since(generationStart))
+
+	response := FizzBuzzResponse{Result: result}
+	if truncated {
+		response.Truncated = true
+		response.Returned = len(result)
+	}
+	h.respondChecksummedJSON(w, r, http.StatusOK, response)
+}
+
+// checkRulePairs applies the pair cap, then the divisor bounds and allowed
+// strings to every pair.
+func (h *Handler) checkRulePairs(pairs []fizzbuzz.Pair) error {
+	maxRules := defaultMaxRules
+	if h.maxRules > 0 {
+		maxRules = h.maxRules
+	}
+	if len(pairs) > maxRules {
+		return newKindError(ErrOutOfRange, "rule", fmt.Sprintf("must not be given more than %d times", maxRules))
+	}
+	for _, pair := range pairs {
+		if err := h.checkDivisor("rule", pair.Divisor); err != nil {
+			return err
+		}
+		if err := h.checkString("rule", pair.Word); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseRuleParams(values url.Values) (ruleParams, error) {
+	for _, name := range rulePairsOnly {
+		if values.Has(name) {
+			return ruleParams{}, newParamError(name, "cannot be combined with rule=divisor:word")
+		}
+	}
+
+	if !values.Has("limit") {
+		return ruleParams{}, &validationError{
+			kind:    ErrMissingParams,
+			message: "missing required parameters: limit",
+			params:  []InvalidParam{{Name: "limit", Reason: "is required"}},
+		}
+	}
+
+	opts := fizzbuzz.Options{Rule: fizzbuzz.RuleDivisible, Base: 10}
+	var pairs []fizzbuzz.Pair
+	modes := 0
+	for _, raw := range values["rule"] {
+		rawDivisor, word, ok := strings.Cut(raw, ":")
+		if !ok {
+			// A value without a colon still picks the match mode.
+			modes++
+			if opts.Rule, ok = rules[raw]; !ok || modes > 1 {
+				return ruleParams{}, newParamError("rule", "must be divisor:word, or one of: divisible, digitsum")
+			}
+			continue
+		}
+		divisor, err := parsePositiveInt64(rawDivisor, "rule")
+		if err != nil {
+			return ruleParams{}, err
+		}
+		if word == "" {
+			return ruleParams{}, newKindError(ErrEmptyStr, "rule", "word cannot be empty")
+		}
+		pairs = append(pairs, fizzbuzz.Pair{Divisor: divisor, Word: word})
+	}
+
+	if query.IsScientific(values.Get("limit")) {
+		return ruleParams{}, newKindError(ErrInvalidInt, "limit", "must be a plain integer (scientific notation not allowed)")
+	}
+	limit, err := parsePositiveInt(values.Get("limit"), "limit")
+	if err != nil {
+		return ruleParams{}, err
+	}
+
+	start := int64(1)
+	if raw := values.Get("start"); raw != "" {
+		if start, err = query.ParseInt(raw, 64); err != nil {
+			return ruleParams{}, newKindError(ErrInvalidInt, "start", err.Error())
+		}
+		if start > math.MaxInt64-int64(limit)+1 {
+			return ruleParams{}, newKindError(ErrOutOfRange, "start", "is too large for the requested limit")
+		}
+	}
+
+	if raw := values.Get("templated"); raw != "" {
+		if opts.Templated, err = strconv.ParseBool(raw); err != nil {
+			return ruleParams{}, newParamError("templated", "must be a boolean")
+		}
+	}
+
+	if raw := values.Get("base"); raw != "" {
+		if opts.Base, err = query.Atoi(raw); err != nil {
+			return ruleParams{}, newKindError(ErrInvalidInt, "base", err.Error())
+		}
+		if opts.Base < 2 || opts.Base > 36 {
+			return ruleParams{}, newKindError(ErrOutOfRange, "base", "must be between 2 and 36")
+		}
+	}
+
+	if raw := values.Get("preview"); raw != "" {
+		if _, err := strconv.ParseBool(raw); err != nil {
+			return ruleParams{}, newParamError("preview", "must be a boolean")
+		}
+	}
+
+	return ruleParams{pairs: pairs, start: start, limit: limit, opts: opts}, nil
+}
@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/statistics"
+)
+
+func TestHandler_FizzBuzz_RulePairs(t *testing.T) {
+	tests := []struct {
+		name           string
+		queryParams    string
+		opts           []Option
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "three pairs",
+			queryParams:    "rule=3:fizz&rule=5:buzz&rule=7:bazz&limit=7",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"result":["1","2","fizz","4","buzz","fizz","bazz"]}`,
+		},
+		{
+			name:           "matching words concatenate in order",
+			queryParams:    "rule=3:fizz&rule=5:buzz&rule=7:bazz&start=105&limit=1",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"result":["fizzbuzzbazz"]}`,
+		},
+		{
+			name:           "single pair",
+			queryParams:    "rule=2:even&limit=3",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"result":["1","even","3"]}`,
+		},
+		{
+			name:           "match mode alongside pairs",
+			queryParams:    "rule=3:fizz&rule=digitsum&start=12&limit=2",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"result":["fizz","13"]}`,
+		},
+		{
+			name:           "templated and base",
+			queryParams:    "rule=3:x{n}&templated=true&base=16&start=11&limit=2",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"result":["b","xc"]}`,
+		},
+		{
+			name:           "truncated at the max limit",
+			queryParams:    "rule=3:fizz&limit=5",
+			opts:           []Option{WithMaxLimit(3, true)},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"result":["1","2","fizz"],"truncated":true,"returned":3}`,
+		},
+		{
+			name:           "missing limit",
+			queryParams:    "rule=3:fizz",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"missing required parameters: limit"}`,
+		},
+		{
+			name:           "malformed divisor",
+			queryParams:    "rule=three:fizz&limit=3",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"rule must be a valid integer (got \"three\")"}`,
+		},
+		{
+			name:           "zero divisor",
+			queryParams:    "rule=0:fizz&limit=3",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"rule must be greater than 0"}`,
+		},
+		{
+			name:           "empty word",
+			queryParams:    "rule=3:&limit=3",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"rule word cannot be empty"}`,
+		},
+		{
+			name:           "unknown match mode",
+			queryParams:    "rule=3:fizz&rule=odd&limit=3",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"rule must be divisor:word, or one of: divisible, digitsum"}`,
+		},
+		{
+			name:           "combined with int1",
+			queryParams:    "rule=3:fizz&int1=3&limit=3",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"int1 cannot be combined with rule=divisor:word"}`,
+		},
+		{
+			name:           "combined with seed",
+			queryParams:    "rule=3:fizz&seed=42&limit=3",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"seed cannot be combined with rule=divisor:word"}`,
+		},
+		{
+			name:           "divisor above the configured maximum",
+			queryParams:    "rule=300:fizz&limit=3",
+			opts:           []Option{WithDivisorRange(1, 100)},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"rule must not exceed 100"}`,
+		},
+		{
+			name:           "word outside the allowed strings",
+			queryParams:    "rule=3:fizz&rule=5:bang&limit=3",
+			opts:           []Option{WithAllowedStrings([]string{"fizz", "buzz"})},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"rule is not an allowed value"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(statistics.NewStore(), nil, tc.opts...)
+
+			req := httptest.NewRequest(http.MethodGet, "/fizzbuzz?"+tc.queryParams, nil)
+			rec := httptest.NewRecorder()
+			h.FizzBuzz(rec, req)
+
+			if rec.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.expectedStatus, rec.Code, rec.Body.String())
+			}
+			if body := strings.TrimSpace(rec.Body.String()); body != tc.expectedBody {
+				t.Fatalf("expected body %s, got %s", tc.expectedBody, body)
+			}
+		})
+	}
+}
+
+func TestHandler_FizzBuzz_MaxRules(t *testing.T) {
+	tests := []struct {
+		name           string
+		pairs          int
+		opts           []Option
+		expectedStatus int
+	}{
+		{name: "default cap", pairs: defaultMaxRules, expectedStatus: http.StatusOK},
+		{name: "one over the default cap", pairs: defaultMaxRules + 1, expectedStatus: http.StatusBadRequest},
+		{name: "configured cap", pairs: 3, opts: []Option{WithMaxRules(3)}, expectedStatus: http.StatusOK},
+		{name: "one over the configured cap", pairs: 4, opts: []Option{WithMaxRules(3)}, expectedStatus: http.StatusBadRequest},
+		{name: "zero keeps the default", pairs: defaultMaxRules, opts: []Option{WithMaxRules(0)}, expectedStatus: http.StatusOK},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(statistics.NewStore(), nil, tc.opts...)
+
+			values := url.Values{"limit": {"10"}, "rule": {"digitsum"}}
+			for i := range tc.pairs {
+				values.Add("rule", fmt.Sprintf("%d:w", i+1))
+			}
+			req := httptest.NewRequest(http.MethodGet, "/fizzbuzz?"+values.Encode(), nil)
+			rec := httptest.NewRecorder()
+			h.FizzBuzz(rec, req)
+
+			if rec.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.expectedStatus, rec.Code, rec.Body.String())
+			}
+			if tc.expectedStatus == http.StatusBadRequest && !strings.Contains(rec.Body.String(), "rule must not be given more than") {
+				t.Fatalf("unexpected error body %s", rec.Body.String())
+			}
+		})
+	}
+}
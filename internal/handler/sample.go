@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/query"
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/statistics"
+)
+
+// SampleResponse represents the payload returned by the sample endpoint.
+// Total is the number of parameter sets in the store, so the sample size
+// can be compared against it.
+type SampleResponse struct {
+	Rate    float64 `json:"rate"`
+	Seed    int64   `json:"seed"`
+	Total   int     `json:"total"`
+	Entries []any   `json:"entries"`
+}
+
+// SampleStatistics returns a deterministic sample of roughly rate of the
+// recorded parameter sets, in the order of statistics.Store.Entries. Whether
+// a set is included depends only on its parameters and seed, so the same
+// rate and seed return the same sets, and raising the rate only adds sets.
+func (h *Handler) SampleStatistics(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+
+	raw := values.Get("rate")
+	if raw == "" {
+		h.respondValidationError(w, r, newParamError("rate", "is required"))
+		return
+	}
+	rate, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(rate) {
+		h.respondValidationError(w, r, newParamError("rate", "must be a number greater than 0 and at most 1"))
+		return
+	}
+	if rate <= 0 || rate > 1 {
+		h.respondValidationError(w, r, newKindError(ErrOutOfRange, "rate", "must be a number greater than 0 and at most 1"))
+		return
+	}
+
+	var seed int64
+	if raw := values.Get("seed"); raw != "" {
+		if seed, err = query.ParseInt(raw, 64); err != nil {
+			h.respondValidationError(w, r, newKindError(ErrInvalidInt, "seed", err.Error()))
+			return
+		}
+	}
+
+	response := SampleResponse{Rate: rate, Seed: seed, Entries: []any{}}
+	if h.store != nil {
+		entries := h.store.Entries()
+		response.Total = len(entries)
+		for i := range entries {
+			if sampled(entries[i].Params, seed, rate) {
+				response.Entries = append(response.Entries, h.statisticsPayload(r, &entries[i]))
+			}
+		}
+	}
+
+	h.respondJSON(w, r, http.StatusOK, response)
+}
+
+// sampled reports whether params falls in the sample for seed and rate. It
+// hashes the seed and every parameter, lengths included so "ab"+"c" and
+// "a"+"bc" differ, and keeps params when the hash, scaled to [0, 1), is
+// below rate.
+func sampled(params statistics.RequestParams, seed int64, rate float64) bool {
+	hash := fnv.New64a()
+	var buf [8]byte
+	for _, n := range []int64{seed, params.Int1, params.Int2, int64(params.Limit), int64(len(params.Str1))} {
+		binary.BigEndian.PutUint64(buf[:], uint64(n))
+		hash.Write(buf[:])
+	}
+	hash.Write([]byte(params.Str1))
+	hash.Write([]byte(params.Str2))
+	return float64(hash.Sum64())/(1<<64) < rate
+}
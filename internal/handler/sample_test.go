@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/statistics"
+)
+
+// sampleStore records limits 1 through n once each.
+func sampleStore(n int) *statistics.Store {
+	store := statistics.NewStore()
+	for limit := 1; limit <= n; limit++ {
+		store.Record(statistics.RequestParams{Int1: 3, Int2: 5, Limit: limit, Str1: "fizz", Str2: "buzz"})
+	}
+	return store
+}
+
+// callSample returns the limits of the sampled entries.
+func callSample(t *testing.T, h *Handler, query string) []int {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	h.SampleStatistics(rec, httptest.NewRequest(http.MethodGet, "/statistics/sample?"+query, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	var body struct {
+		Total   int                  `json:"total"`
+		Entries []StatisticsResponse `json:"entries"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	limits := []int{}
+	for _, entry := range body.Entries {
+		limits = append(limits, entry.Params.Limit)
+	}
+	return limits
+}
+
+func TestHandler_SampleStatistics_Deterministic(t *testing.T) {
+	h := NewHandler(sampleStore(200), nil)
+
+	first := callSample(t, h, "rate=0.1&seed=7")
+	if again := callSample(t, h, "rate=0.1&seed=7"); !reflect.DeepEqual(first, again) {
+		t.Fatalf("same seed and rate sampled %v, then %v", first, again)
+	}
+	if len(first) == 0 || len(first) > 60 {
+		t.Fatalf("expected roughly 20 of 200 entries at rate 0.1, got %d", len(first))
+	}
+	if other := callSample(t, h, "rate=0.1&seed=8"); reflect.DeepEqual(first, other) {
+		t.Fatalf("expected a different seed to sample a different subset, got %v for both", first)
+	}
+
+	wider := callSample(t, h, "rate=0.5&seed=7")
+	for _, limit := range first {
+		if !slices.Contains(wider, limit) {
+			t.Fatalf("expected limit %d sampled at rate 0.1 to stay in the rate 0.5 sample", limit)
+		}
+	}
+
+	if all := callSample(t, h, "rate=1"); len(all) != 200 {
+		t.Fatalf("expected rate 1 to return every entry, got %d", len(all))
+	}
+}
+
+func TestHandler_SampleStatistics_InvalidParams(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", "rate is required"},
+		{"rate=0", "rate must be a number greater than 0 and at most 1"},
+		{"rate=1.5", "rate must be a number greater than 0 and at most 1"},
+		{"rate=NaN", "rate must be a number greater than 0 and at most 1"},
+		{"rate=often", "rate must be a number greater than 0 and at most 1"},
+		{"rate=0.1&seed=x", `seed must be a valid integer (got "x")`},
+	}
+
+	h := NewHandler(sampleStore(3), nil)
+	for _, tc := range tests {
+		t.Run(tc.query, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.SampleStatistics(rec, httptest.NewRequest(http.MethodGet, "/statistics/sample?"+tc.query, nil))
+
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+			}
+			assertErrorResponse(t, rec.Body.Bytes(), tc.want)
+		})
+	}
+}
@@ -1,14 +1,24 @@
 package handler
 
 import (
+	"cmp"
+	"encoding/csv"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/query"
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/statistics"
 )
 
 // StatisticsParams describes the request parameters in the statistics response.
 type StatisticsParams struct {
-	Int1  int    `json:"int1"`
-	Int2  int    `json:"int2"`
+	Int1  int64  `json:"int1"`
+	Int2  int64  `json:"int2"`
 	Limit int    `json:"limit"`
 	Str1  string `json:"str1"`
 	Str2  string `json:"str2"`
@@ -20,25 +30,96 @@ type StatisticsResponse struct {
 	Hits   int              `json:"hits"`
 }
 
-// Statistics returns the most frequent FizzBuzz request observed so far.
-func (h *Handler) Statistics(w http.ResponseWriter, r *http.Request) {
-	var logger *slog.Logger
-	if h != nil {
-		logger = h.logger
+// FlatStatisticsResponse is the alternate "flat" statistics shape, with the
+// parameters inlined next to the hit count.
+type FlatStatisticsResponse struct {
+	Int1  int64  `json:"int1"`
+	Int2  int64  `json:"int2"`
+	Limit int    `json:"limit"`
+	Str1  string `json:"str1"`
+	Str2  string `json:"str2"`
+	Hits  int    `json:"hits"`
+}
+
+const (
+	// ShapeNested is the default response shape.
+	ShapeNested = "nested"
+	// ShapeFlat inlines nested objects into their parent.
+	ShapeFlat = "flat"
+)
+
+// responseShape resolves the requested shape from an Accept profile
+// parameter, falling back to the handler default.
+func (h *Handler) responseShape(r *http.Request) string {
+	if profile, ok := acceptParam(r, "profile"); ok && (profile == ShapeNested || profile == ShapeFlat) {
+		return profile
+	}
+	if h.shape != "" {
+		return h.shape
 	}
+	return ShapeNested
+}
 
+// Statistics returns the most frequent FizzBuzz request observed so far.
+// Optional str1 and str2 parameters restrict it to requests that used those
+// exact words.
+func (h *Handler) Statistics(w http.ResponseWriter, r *http.Request) {
 	if h == nil || h.store == nil {
-		respondError(logger, w, http.StatusNotFound, "no statistics available")
+		h.respondError(w, r, http.StatusNotFound, "no statistics available")
 		return
 	}
 
-	stats, ok := h.store.GetMostFrequent()
+	values := r.URL.Query()
+	minHits := 1
+	if raw := values.Get("min_hits"); raw != "" {
+		var err error
+		if minHits, err = parsePositiveInt(raw, "min_hits"); err != nil {
+			h.respondValidationError(w, r, err)
+			return
+		}
+	}
+
+	var match func(statistics.RequestParams) bool
+	for _, name := range []string{"str1", "str2"} {
+		if values.Has(name) && values.Get(name) == "" {
+			h.respondValidationError(w, r, newKindError(ErrEmptyStr, name, "cannot be empty"))
+			return
+		}
+	}
+	str1, str2 := values.Get("str1"), values.Get("str2")
+	if str1 != "" || str2 != "" {
+		match = func(params statistics.RequestParams) bool {
+			return (str1 == "" || params.Str1 == str1) && (str2 == "" || params.Str2 == str2)
+		}
+	}
+
+	stats, ok := h.store.GetMostFrequentMatching(minHits, match)
 	if !ok {
-		respondError(h.logger, w, http.StatusNotFound, "no statistics available")
+		message := "no statistics available"
+		if match != nil {
+			message = "no statistics match the requested str1/str2"
+		}
+		h.respondError(w, r, http.StatusNotFound, message)
 		return
 	}
 
-	response := StatisticsResponse{
+	h.respondJSON(w, r, http.StatusOK, h.statisticsPayload(r, stats))
+}
+
+// statisticsPayload renders stats in the response shape r asks for.
+func (h *Handler) statisticsPayload(r *http.Request, stats *statistics.Stats) any {
+	if h.responseShape(r) == ShapeFlat {
+		return FlatStatisticsResponse{
+			Int1:  stats.Params.Int1,
+			Int2:  stats.Params.Int2,
+			Limit: stats.Params.Limit,
+			Str1:  stats.Params.Str1,
+			Str2:  stats.Params.Str2,
+			Hits:  stats.Hits,
+		}
+	}
+
+	return StatisticsResponse{
 		Params: StatisticsParams{
 			Int1:  stats.Params.Int1,
 			Int2:  stats.Params.Int2,
@@ -48,6 +129,315 @@ func (h *Handler) Statistics(w http.ResponseWriter, r *http.Request) {
 		},
 		Hits: stats.Hits,
 	}
+}
+
+// CountResponse represents the payload returned by the count endpoint.
+type CountResponse struct {
+	Hits int `json:"hits"`
+}
+
+// CountStatistics returns how many times one parameter set was requested,
+// zero if never. The parameters are validated exactly as /fizzbuzz does.
+func (h *Handler) CountStatistics(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+	params, err := parseFizzBuzzParams(values)
+	if err == nil {
+		err = h.checkParams(params, values)
+	}
+	if err != nil {
+		h.respondValidationError(w, r, err)
+		return
+	}
 
-	respondJSON(h.logger, w, http.StatusOK, response)
+	response := CountResponse{}
+	if h.store != nil {
+		response.Hits = h.store.Get(statistics.RequestParams{
+			Int1:  params.int1,
+			Int2:  params.int2,
+			Limit: params.limit,
+			Str1:  params.str1,
+			Str2:  params.str2,
+		})
+	}
+	h.respondJSON(w, r, http.StatusOK, response)
+}
+
+// LimitHits is the number of requests recorded for a single limit value.
+type LimitHits struct {
+	Limit int `json:"limit"`
+	Hits  int `json:"hits"`
+}
+
+// LimitHistogramResponse represents the payload returned by the limits endpoint.
+type LimitHistogramResponse struct {
+	Limits []LimitHits `json:"limits"`
+}
+
+// LimitStatistics returns hits per requested limit, sorted by limit.
+func (h *Handler) LimitStatistics(w http.ResponseWriter, r *http.Request) {
+	response := LimitHistogramResponse{Limits: []LimitHits{}}
+	if h == nil || h.store == nil {
+		h.respondJSON(w, r, http.StatusOK, response)
+		return
+	}
+
+	for limit, hits := range h.store.LimitHistogram() {
+		response.Limits = append(response.Limits, LimitHits{Limit: limit, Hits: hits})
+	}
+	sort.Slice(response.Limits, func(i, j int) bool {
+		return response.Limits[i].Limit < response.Limits[j].Limit
+	})
+
+	h.respondJSON(w, r, http.StatusOK, response)
+}
+
+// FailureStatisticsResponse represents the payload returned by the errors endpoint.
+type FailureStatisticsResponse struct {
+	Errors map[string]int `json:"errors"`
+	Total  int            `json:"total"`
+}
+
+// FailureStatistics returns how many FizzBuzz requests were rejected, by reason.
+func (h *Handler) FailureStatistics(w http.ResponseWriter, r *http.Request) {
+	response := FailureStatisticsResponse{Errors: map[string]int{}}
+	if h != nil && h.store != nil {
+		response.Errors = h.store.Failures()
+	}
+	for _, count := range response.Errors {
+		response.Total += count
+	}
+
+	h.respondJSON(w, r, http.StatusOK, response)
+}
+
+var exportHeader = []string{"int1", "int2", "limit", "str1", "str2", "hits"}
+
+// ExportStatistics writes every recorded parameter set and its hit count as
+// CSV, in the deterministic order of statistics.Store.Entries.
+func (h *Handler) ExportStatistics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="statistics.csv"`)
+	w.WriteHeader(http.StatusOK)
+
+	cw := csv.NewWriter(w)
+	_ = cw.Write(exportHeader)
+	if h != nil && h.store != nil {
+		for _, entry := range h.store.Entries() {
+			_ = cw.Write([]string{
+				strconv.FormatInt(entry.Params.Int1, 10),
+				strconv.FormatInt(entry.Params.Int2, 10),
+				strconv.Itoa(entry.Params.Limit),
+				entry.Params.Str1,
+				entry.Params.Str2,
+				strconv.Itoa(entry.Hits),
+			})
+		}
+	}
+	cw.Flush()
+
+	if err := cw.Error(); err != nil && h != nil {
+		h.logger.Error("csv export write error", slog.String("error", err.Error()))
+		h.writeErrors.Inc()
+	}
+}
+
+// TopValue is the most requested value of a single parameter.
+type TopValue[T any] struct {
+	Value T   `json:"value"`
+	Hits  int `json:"hits"`
+}
+
+// BreakdownResponse represents the payload returned by the breakdown
+// endpoint: the most requested value of each parameter, counted
+// independently of the others.
+type BreakdownResponse struct {
+	Int1  TopValue[int64]  `json:"int1"`
+	Int2  TopValue[int64]  `json:"int2"`
+	Limit TopValue[int]    `json:"limit"`
+	Str1  TopValue[string] `json:"str1"`
+	Str2  TopValue[string] `json:"str2"`
+}
+
+// BreakdownStatistics returns the most popular value of each FizzBuzz
+// parameter. Ties go to the smallest value.
+func (h *Handler) BreakdownStatistics(w http.ResponseWriter, r *http.Request) {
+	if h == nil || h.store == nil {
+		h.respondError(w, r, http.StatusNotFound, "no statistics available")
+		return
+	}
+
+	breakdown := h.store.Breakdown()
+	if len(breakdown.LimitCounts) == 0 {
+		h.respondError(w, r, http.StatusNotFound, "no statistics available")
+		return
+	}
+
+	h.respondJSON(w, r, http.StatusOK, BreakdownResponse{
+		Int1:  topValue(breakdown.Int1Counts),
+		Int2:  topValue(breakdown.Int2Counts),
+		Limit: topValue(breakdown.LimitCounts),
+		Str1:  topValue(breakdown.Str1Counts),
+		Str2:  topValue(breakdown.Str2Counts),
+	})
+}
+
+const (
+	defaultTopLimit = 10
+	defaultMaxTopN  = 100
+)
+
+// WithMaxTopN caps the page size of /statistics/top. Larger requested
+// limits are served at the cap and flagged as truncated. Zero or less keeps
+// the default of 100.
+func WithMaxTopN(n int) Option {
+	return func(h *Handler) {
+		h.maxTopN = n
+	}
+}
+
+// TopStatisticsResponse is one page of parameter sets ranked by frequency.
+// Entries use the same shape as /statistics. When the requested limit was
+// above the cap, Truncated is set and Limit reports the page size served.
+type TopStatisticsResponse struct {
+	Top       []any `json:"top"`
+	Total     int   `json:"total"`
+	Truncated bool  `json:"truncated,omitempty"`
+	Limit     int   `json:"limit,omitempty"`
+}
+
+// TopStatistics returns the most frequent parameter sets, paged with limit
+// and offset. Link headers point to the next and previous pages when they
+// exist.
+func (h *Handler) TopStatistics(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+
+	maxTopN := defaultMaxTopN
+	if h != nil && h.maxTopN > 0 {
+		maxTopN = h.maxTopN
+	}
+
+	limit := min(defaultTopLimit, maxTopN)
+	truncated := false
+	if raw := values.Get("limit"); raw != "" {
+		var err error
+		if limit, err = parsePositiveInt(raw, "limit"); err != nil {
+			h.respondValidationError(w, r, err)
+			return
+		}
+		if limit > maxTopN {
+			limit = maxTopN
+			truncated = true
+		}
+	}
+
+	offset := 0
+	if raw := values.Get("offset"); raw != "" {
+		var err error
+		if offset, err = query.Atoi(raw); err != nil || offset < 0 {
+			h.respondValidationError(w, r, newParamError("offset", "must be a non-negative integer"))
+			return
+		}
+	}
+
+	var ranked []statistics.Stats
+	if h != nil && h.store != nil {
+		ranked = h.store.Ranked()
+	}
+
+	response := TopStatisticsResponse{Top: []any{}, Total: len(ranked)}
+	if truncated {
+		response.Truncated = true
+		response.Limit = limit
+	}
+	for i := offset; i < len(ranked) && i < offset+limit; i++ {
+		response.Top = append(response.Top, h.statisticsPayload(r, &ranked[i]))
+	}
+
+	var links []string
+	if offset+limit < len(ranked) {
+		links = append(links, pageLink(r, limit, offset+limit, "next"))
+	}
+	if offset > 0 {
+		links = append(links, pageLink(r, limit, max(offset-limit, 0), "prev"))
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+
+	h.respondJSON(w, r, http.StatusOK, response)
+}
+
+// pageLink renders an RFC 8288 Link header value for the same request with a
+// different page.
+func pageLink(r *http.Request, limit, offset int, rel string) string {
+	query := r.URL.Query()
+	query.Set("limit", strconv.Itoa(limit))
+	query.Set("offset", strconv.Itoa(offset))
+	target := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+	return fmt.Sprintf("<%s>; rel=%q", target.String(), rel)
+}
+
+// ReplayStatistics regenerates the FizzBuzz sequence for the most frequent
+// request, in the same shape as /fizzbuzz and through the same generator,
+// size limit and concurrency limit. A recorded limit above the current cap
+// is truncated to it, since the client did not choose those parameters.
+func (h *Handler) ReplayStatistics(w http.ResponseWriter, r *http.Request) {
+	if h == nil || h.store == nil {
+		h.respondError(w, r, http.StatusNotFound, "no statistics available")
+		return
+	}
+
+	stats, ok := h.store.GetMostFrequent()
+	if !ok {
+		h.respondError(w, r, http.StatusNotFound, "no statistics available")
+		return
+	}
+
+	params := fizzBuzzParams{
+		int1: stats.Params.Int1, int2: stats.Params.Int2, start: 1, limit: stats.Params.Limit,
+		str1: stats.Params.Str1, str2: stats.Params.Str2, base: 10,
+	}
+	limit := params.limit
+	if caps := h.limits.Load(); caps != nil && caps.maxLimit > 0 && limit > caps.maxLimit {
+		limit = caps.maxLimit
+	}
+	limit, _, err := h.capLimit(limit, max(len(params.str1), len(params.str2)))
+	if err != nil {
+		h.respondValidationError(w, r, err)
+		return
+	}
+
+	release, ok := h.acquireGeneration(w, r, limit)
+	if !ok {
+		return
+	}
+	defer release()
+
+	result, err := h.recoverGeneration(r, func() []string { return h.generateSequence(params, limit) })
+	if err != nil {
+		h.respondGenerationPanic(w, r)
+		return
+	}
+	if result == nil {
+		result = []string{}
+	}
+
+	response := FizzBuzzResponse{Result: result}
+	if limit < params.limit {
+		response.Truncated = true
+		response.Returned = len(result)
+	}
+	h.respondJSON(w, r, http.StatusOK, response)
+}
+
+func topValue[T cmp.Ordered](counts map[T]int) TopValue[T] {
+	var top TopValue[T]
+	found := false
+	for value, hits := range counts {
+		if !found || hits > top.Hits || (hits == top.Hits && value < top.Value) {
+			top = TopValue[T]{Value: value, Hits: hits}
+			found = true
+		}
+	}
+	return top
 }
@@ -5,12 +5,14 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"sync"
 	"testing"
 	"testing/synctest"
 
 	"github.com/go-chi/chi/v5"
 
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/fizzbuzz"
 	"github.com/Cerebrovinny/fizz-buzz-rest/internal/statistics"
 )
 
@@ -118,6 +120,615 @@ func TestHandler_Statistics_JSONFormat(t *testing.T) {
 	}
 }
 
+func TestHandler_Statistics_MinHits(t *testing.T) {
+	store := statistics.NewStore()
+	top := statistics.RequestParams{Int1: 3, Int2: 5, Limit: 15, Str1: "fizz", Str2: "buzz"}
+	lower := statistics.RequestParams{Int1: 2, Int2: 3, Limit: 10, Str1: "foo", Str2: "bar"}
+	recordRequest(store, top, 6)
+	recordRequest(store, lower, 2)
+
+	h := NewHandler(store, nil)
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedParams statistics.RequestParams
+		expectedHits   int
+		expectedError  string
+	}{
+		{name: "default threshold", query: "", expectedStatus: http.StatusOK, expectedParams: top, expectedHits: 6},
+		{name: "threshold met by top only", query: "?min_hits=5", expectedStatus: http.StatusOK, expectedParams: top, expectedHits: 6},
+		{name: "threshold above every entry", query: "?min_hits=7", expectedStatus: http.StatusNotFound, expectedError: "no statistics available"},
+		{name: "invalid threshold", query: "?min_hits=many", expectedStatus: http.StatusBadRequest, expectedError: `min_hits must be a valid integer (got "many")`},
+		{name: "zero threshold", query: "?min_hits=0", expectedStatus: http.StatusBadRequest, expectedError: "min_hits must be greater than 0"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/statistics"+tc.query, nil)
+			rec := httptest.NewRecorder()
+			h.Statistics(rec, req)
+
+			if rec.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tc.expectedStatus, rec.Code)
+			}
+			if tc.expectedError != "" {
+				assertErrorResponse(t, rec.Body.Bytes(), tc.expectedError)
+				return
+			}
+			assertStatisticsResponse(t, rec.Body.Bytes(), tc.expectedParams, tc.expectedHits)
+		})
+	}
+}
+
+func TestHandler_Statistics_FilterByWords(t *testing.T) {
+	store := statistics.NewStore()
+	top := statistics.RequestParams{Int1: 2, Int2: 7, Limit: 10, Str1: "foo", Str2: "bar"}
+	fizz := statistics.RequestParams{Int1: 3, Int2: 5, Limit: 15, Str1: "fizz", Str2: "buzz"}
+	fizzBang := statistics.RequestParams{Int1: 3, Int2: 5, Limit: 30, Str1: "fizz", Str2: "bang"}
+	recordRequest(store, top, 9)
+	recordRequest(store, fizz, 4)
+	recordRequest(store, fizzBang, 2)
+
+	h := NewHandler(store, nil)
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedParams statistics.RequestParams
+		expectedHits   int
+		expectedError  string
+	}{
+		{name: "unfiltered", query: "", expectedStatus: http.StatusOK, expectedParams: top, expectedHits: 9},
+		{name: "str1", query: "?str1=fizz", expectedStatus: http.StatusOK, expectedParams: fizz, expectedHits: 4},
+		{name: "str2", query: "?str2=bang", expectedStatus: http.StatusOK, expectedParams: fizzBang, expectedHits: 2},
+		{name: "str1 and str2", query: "?str1=fizz&str2=bang", expectedStatus: http.StatusOK, expectedParams: fizzBang, expectedHits: 2},
+		{name: "combined with min_hits", query: "?str1=fizz&min_hits=5", expectedStatus: http.StatusNotFound, expectedError: "no statistics match the requested str1/str2"},
+		{name: "no match", query: "?str1=buzz", expectedStatus: http.StatusNotFound, expectedError: "no statistics match the requested str1/str2"},
+		{name: "empty str1", query: "?str1=", expectedStatus: http.StatusBadRequest, expectedError: "str1 cannot be empty"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/statistics"+tc.query, nil)
+			rec := httptest.NewRecorder()
+			h.Statistics(rec, req)
+
+			if rec.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tc.expectedStatus, rec.Code)
+			}
+			if tc.expectedError != "" {
+				assertErrorResponse(t, rec.Body.Bytes(), tc.expectedError)
+				return
+			}
+			assertStatisticsResponse(t, rec.Body.Bytes(), tc.expectedParams, tc.expectedHits)
+		})
+	}
+}
+
+func TestHandler_CountStatistics(t *testing.T) {
+	store := statistics.NewStore()
+	recordRequest(store, statistics.RequestParams{Int1: 3, Int2: 5, Limit: 15, Str1: "fizz", Str2: "buzz"}, 4)
+
+	h := NewHandler(store, nil, WithStrictIntegers(true))
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedHits   int
+		expectedError  string
+	}{
+		{name: "recorded", query: "int1=3&int2=5&limit=15&str1=fizz&str2=buzz", expectedStatus: http.StatusOK, expectedHits: 4},
+		{name: "recorded with plus sign", query: "int1=%2B3&int2=5&limit=15&str1=fizz&str2=buzz", expectedStatus: http.StatusOK, expectedHits: 4},
+		{name: "unrecorded", query: "int1=3&int2=5&limit=16&str1=fizz&str2=buzz", expectedStatus: http.StatusOK, expectedHits: 0},
+		{name: "missing params", query: "int1=3&int2=5", expectedStatus: http.StatusBadRequest, expectedError: "missing required parameters: int1, int2, limit, str1, str2"},
+		{name: "invalid integer", query: "int1=x&int2=5&limit=15&str1=fizz&str2=buzz", expectedStatus: http.StatusBadRequest, expectedError: `int1 must be a valid integer (got "x")`},
+		{name: "strict integers", query: "int1=03&int2=5&limit=15&str1=fizz&str2=buzz", expectedStatus: http.StatusBadRequest, expectedError: "int1 must not have leading zeros"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/statistics/count?"+tc.query, nil)
+			rec := httptest.NewRecorder()
+			h.CountStatistics(rec, req)
+
+			if rec.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tc.expectedStatus, rec.Code)
+			}
+			if tc.expectedError != "" {
+				assertErrorResponse(t, rec.Body.Bytes(), tc.expectedError)
+				return
+			}
+
+			var response CountResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if response.Hits != tc.expectedHits {
+				t.Fatalf("expected %d hits, got %d", tc.expectedHits, response.Hits)
+			}
+		})
+	}
+}
+
+func TestHandler_LimitStatistics(t *testing.T) {
+	store := statistics.NewStore()
+	recordRequest(store, statistics.RequestParams{Int1: 3, Int2: 5, Limit: 100, Str1: "fizz", Str2: "buzz"}, 2)
+	recordRequest(store, statistics.RequestParams{Int1: 3, Int2: 5, Limit: 15, Str1: "fizz", Str2: "buzz"}, 4)
+	recordRequest(store, statistics.RequestParams{Int1: 2, Int2: 7, Limit: 15, Str1: "foo", Str2: "bar"}, 1)
+
+	h := NewHandler(store, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/statistics/limits", nil)
+	rec := httptest.NewRecorder()
+	h.LimitStatistics(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	assertJSONResponse(t, rec.Body.Bytes(), LimitHistogramResponse{Limits: []LimitHits{
+		{Limit: 15, Hits: 5},
+		{Limit: 100, Hits: 2},
+	}})
+}
+
+func TestHandler_LimitStatistics_NoData(t *testing.T) {
+	h := NewHandler(statistics.NewStore(), nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/statistics/limits", nil)
+	rec := httptest.NewRecorder()
+	h.LimitStatistics(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	assertJSONResponse(t, rec.Body.Bytes(), LimitHistogramResponse{Limits: []LimitHits{}})
+}
+
+func TestHandler_Statistics_ResponseShape(t *testing.T) {
+	store := statistics.NewStore()
+	params := statistics.RequestParams{Int1: 3, Int2: 5, Limit: 15, Str1: "fizz", Str2: "buzz"}
+	recordRequest(store, params, 2)
+
+	flat := FlatStatisticsResponse{Int1: 3, Int2: 5, Limit: 15, Str1: "fizz", Str2: "buzz", Hits: 2}
+	nested := StatisticsResponse{
+		Params: StatisticsParams{Int1: 3, Int2: 5, Limit: 15, Str1: "fizz", Str2: "buzz"},
+		Hits:   2,
+	}
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		accept   string
+		expected interface{}
+	}{
+		{name: "default is nested", expected: nested},
+		{name: "accept profile flat", accept: `application/json; profile="flat"`, expected: flat},
+		{name: "config flat", opts: []Option{WithResponseShape(ShapeFlat)}, expected: flat},
+		{name: "accept profile overrides config", opts: []Option{WithResponseShape(ShapeFlat)}, accept: "application/json; profile=nested", expected: nested},
+		{name: "unknown profile falls back", accept: "application/json; profile=camel", expected: nested},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(store, nil, tc.opts...)
+
+			req := httptest.NewRequest(http.MethodGet, "/statistics", nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			rec := httptest.NewRecorder()
+			h.Statistics(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+
+			var payload map[string]interface{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			_, nestedShape := payload["params"]
+			if _, wantNested := tc.expected.(StatisticsResponse); nestedShape != wantNested {
+				t.Fatalf("expected nested=%t, got body %s", wantNested, rec.Body.String())
+			}
+
+			assertJSONResponse(t, rec.Body.Bytes(), tc.expected)
+		})
+	}
+}
+
+func TestHandler_FailureStatistics(t *testing.T) {
+	store := statistics.NewStore()
+	store.RecordFailure("missing_parameter")
+	store.RecordFailure("missing_parameter")
+	store.RecordFailure("invalid_integer")
+
+	h := NewHandler(store, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/statistics/errors", nil)
+	rec := httptest.NewRecorder()
+	h.FailureStatistics(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	assertJSONResponse(t, rec.Body.Bytes(), FailureStatisticsResponse{
+		Errors: map[string]int{"missing_parameter": 2, "invalid_integer": 1},
+		Total:  3,
+	})
+}
+
+func TestHandler_ExportStatistics(t *testing.T) {
+	store := statistics.NewStore()
+	recordRequest(store, statistics.RequestParams{Int1: 3, Int2: 5, Limit: 15, Str1: "fizz", Str2: "buzz"}, 3)
+	recordRequest(store, statistics.RequestParams{Int1: 2, Int2: 7, Limit: 20, Str1: "foo", Str2: "bar,baz"}, 1)
+
+	h := NewHandler(store, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/statistics/export", nil)
+	rec := httptest.NewRecorder()
+	h.ExportStatistics(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if contentType := rec.Header().Get("Content-Type"); contentType != "text/csv; charset=utf-8" {
+		t.Fatalf("expected Content-Type text/csv; charset=utf-8, got %s", contentType)
+	}
+
+	want := "int1,int2,limit,str1,str2,hits\n" +
+		"2,7,20,foo,\"bar,baz\",1\n" +
+		"3,5,15,fizz,buzz,3\n"
+	if got := rec.Body.String(); got != want {
+		t.Fatalf("unexpected CSV body:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestHandler_ExportStatistics_NoData(t *testing.T) {
+	h := NewHandler(statistics.NewStore(), nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/statistics/export", nil)
+	rec := httptest.NewRecorder()
+	h.ExportStatistics(rec, req)
+
+	if got, want := rec.Body.String(), "int1,int2,limit,str1,str2,hits\n"; got != want {
+		t.Fatalf("expected header row only, got %q", got)
+	}
+}
+
+func TestHandler_BreakdownStatistics(t *testing.T) {
+	store := statistics.NewStore()
+	recordRequest(store, statistics.RequestParams{Int1: 3, Int2: 5, Limit: 15, Str1: "fizz", Str2: "buzz"}, 2)
+	recordRequest(store, statistics.RequestParams{Int1: 2, Int2: 7, Limit: 100, Str1: "fizz", Str2: "bar"}, 1)
+	recordRequest(store, statistics.RequestParams{Int1: 2, Int2: 5, Limit: 100, Str1: "foo", Str2: "bar"}, 2)
+
+	h := NewHandler(store, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/statistics/breakdown", nil)
+	rec := httptest.NewRecorder()
+	h.BreakdownStatistics(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	assertJSONResponse(t, rec.Body.Bytes(), BreakdownResponse{
+		Int1:  TopValue[int64]{Value: 2, Hits: 3},
+		Int2:  TopValue[int64]{Value: 5, Hits: 4},
+		Limit: TopValue[int]{Value: 100, Hits: 3},
+		Str1:  TopValue[string]{Value: "fizz", Hits: 3},
+		Str2:  TopValue[string]{Value: "bar", Hits: 3},
+	})
+}
+
+func TestHandler_BreakdownStatistics_TieGoesToSmallestValue(t *testing.T) {
+	store := statistics.NewStore()
+	recordRequest(store, statistics.RequestParams{Int1: 5, Int2: 7, Limit: 20, Str1: "zed", Str2: "b"}, 1)
+	recordRequest(store, statistics.RequestParams{Int1: 3, Int2: 7, Limit: 10, Str1: "abc", Str2: "b"}, 1)
+
+	h := NewHandler(store, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/statistics/breakdown", nil)
+	rec := httptest.NewRecorder()
+	h.BreakdownStatistics(rec, req)
+
+	assertJSONResponse(t, rec.Body.Bytes(), BreakdownResponse{
+		Int1:  TopValue[int64]{Value: 3, Hits: 1},
+		Int2:  TopValue[int64]{Value: 7, Hits: 2},
+		Limit: TopValue[int]{Value: 10, Hits: 1},
+		Str1:  TopValue[string]{Value: "abc", Hits: 1},
+		Str2:  TopValue[string]{Value: "b", Hits: 2},
+	})
+}
+
+func TestHandler_BreakdownStatistics_NoData(t *testing.T) {
+	h := NewHandler(statistics.NewStore(), nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/statistics/breakdown", nil)
+	rec := httptest.NewRecorder()
+	h.BreakdownStatistics(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+	assertErrorResponse(t, rec.Body.Bytes(), "no statistics available")
+}
+
+func TestHandler_ReplayStatistics(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []Option
+		expected FizzBuzzResponse
+	}{
+		{
+			name: "regenerates top params",
+			expected: FizzBuzzResponse{Result: []string{
+				"1", "2", "fizz", "4", "buzz", "fizz", "7", "8", "fizz", "buzz", "11", "fizz", "13", "14", "fizzbuzz",
+			}},
+		},
+		{
+			name:     "truncates to current max limit",
+			opts:     []Option{WithMaxLimit(5, false)},
+			expected: FizzBuzzResponse{Result: []string{"1", "2", "fizz", "4", "buzz"}, Truncated: true, Returned: 5},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store := statistics.NewStore()
+			recordRequest(store, statistics.RequestParams{Int1: 3, Int2: 5, Limit: 15, Str1: "fizz", Str2: "buzz"}, 3)
+			recordRequest(store, statistics.RequestParams{Int1: 2, Int2: 7, Limit: 4, Str1: "foo", Str2: "bar"}, 1)
+
+			h := NewHandler(store, nil, tc.opts...)
+
+			req := httptest.NewRequest(http.MethodGet, "/statistics/replay", nil)
+			rec := httptest.NewRecorder()
+			h.ReplayStatistics(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+			assertJSONResponse(t, rec.Body.Bytes(), tc.expected)
+		})
+	}
+}
+
+func TestHandler_ReplayStatistics_UsesTheGenerationPath(t *testing.T) {
+	store := statistics.NewStore()
+	recordRequest(store, statistics.RequestParams{Int1: 3, Int2: 5, Limit: 15, Str1: "fizz", Str2: "buzz"}, 1)
+
+	var got []GenerationParams
+	spy := GeneratorFunc(func(params GenerationParams) []string {
+		got = append(got, params)
+		return []string{"spied"}
+	})
+	h := NewHandler(store, nil, WithGenerator(spy))
+
+	rec := httptest.NewRecorder()
+	h.ReplayStatistics(rec, httptest.NewRequest(http.MethodGet, "/statistics/replay", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	assertJSONResponse(t, rec.Body.Bytes(), FizzBuzzResponse{Result: []string{"spied"}})
+	want := GenerationParams{Int1: 3, Int2: 5, Start: 1, Count: 15, Str1: "fizz", Str2: "buzz", Options: fizzbuzz.Options{Base: 10}}
+	if len(got) != 1 || got[0] != want {
+		t.Fatalf("expected one generation %+v, got %+v", want, got)
+	}
+}
+
+func TestHandler_ReplayStatistics_Guards(t *testing.T) {
+	tests := []struct {
+		name           string
+		opts           []Option
+		expectedStatus int
+	}{
+		{"response size limit", []Option{WithMaxResponseBytes(10)}, http.StatusBadRequest},
+		{"generation panic", []Option{WithGenerator(GeneratorFunc(func(GenerationParams) []string { panic("boom") }))}, http.StatusInternalServerError},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store := statistics.NewStore()
+			recordRequest(store, statistics.RequestParams{Int1: 3, Int2: 5, Limit: 15, Str1: "fizz", Str2: "buzz"}, 1)
+			h := NewHandler(store, nil, tc.opts...)
+
+			rec := httptest.NewRecorder()
+			h.ReplayStatistics(rec, httptest.NewRequest(http.MethodGet, "/statistics/replay", nil))
+
+			if rec.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.expectedStatus, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_ReplayStatistics_NoData(t *testing.T) {
+	h := NewHandler(statistics.NewStore(), nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/statistics/replay", nil)
+	rec := httptest.NewRecorder()
+	h.ReplayStatistics(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+	assertErrorResponse(t, rec.Body.Bytes(), "no statistics available")
+}
+
+func TestHandler_TopStatistics_Links(t *testing.T) {
+	store := statistics.NewStore()
+	for i := range 5 {
+		recordRequest(store, statistics.RequestParams{Int1: 3, Int2: 5, Limit: i + 1, Str1: "fizz", Str2: "buzz"}, 10-i)
+	}
+	h := NewHandler(store, nil)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantLimits []int
+		wantLink   string
+	}{
+		{
+			name:       "first page has only next",
+			query:      "limit=2",
+			wantLimits: []int{1, 2},
+			wantLink:   `</statistics/top?limit=2&offset=2>; rel="next"`,
+		},
+		{
+			name:       "middle page has next and prev",
+			query:      "limit=2&offset=2",
+			wantLimits: []int{3, 4},
+			wantLink:   `</statistics/top?limit=2&offset=4>; rel="next", </statistics/top?limit=2&offset=0>; rel="prev"`,
+		},
+		{
+			name:       "last page has only prev",
+			query:      "limit=2&offset=4",
+			wantLimits: []int{5},
+			wantLink:   `</statistics/top?limit=2&offset=2>; rel="prev"`,
+		},
+		{
+			name:       "single page has no links",
+			query:      "",
+			wantLimits: []int{1, 2, 3, 4, 5},
+		},
+		{
+			name:       "unaligned offset clamps prev to zero",
+			query:      "limit=2&offset=1",
+			wantLimits: []int{2, 3},
+			wantLink:   `</statistics/top?limit=2&offset=3>; rel="next", </statistics/top?limit=2&offset=0>; rel="prev"`,
+		},
+		{
+			name:       "offset past the end",
+			query:      "limit=2&offset=9",
+			wantLimits: []int{},
+			wantLink:   `</statistics/top?limit=2&offset=7>; rel="prev"`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/statistics/top?"+tc.query, nil)
+			rec := httptest.NewRecorder()
+			h.TopStatistics(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+			if got := rec.Header().Get("Link"); got != tc.wantLink {
+				t.Fatalf("expected Link %q, got %q", tc.wantLink, got)
+			}
+
+			var body struct {
+				Top   []StatisticsResponse `json:"top"`
+				Total int                  `json:"total"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("failed to decode body: %v", err)
+			}
+			if body.Total != 5 {
+				t.Fatalf("expected total 5, got %d", body.Total)
+			}
+			limits := []int{}
+			for _, entry := range body.Top {
+				limits = append(limits, entry.Params.Limit)
+			}
+			if !slices.Equal(limits, tc.wantLimits) {
+				t.Fatalf("expected limits %v, got %v", tc.wantLimits, limits)
+			}
+		})
+	}
+}
+
+func TestHandler_TopStatistics_MaxTopN(t *testing.T) {
+	store := statistics.NewStore()
+	for i := range 5 {
+		recordRequest(store, statistics.RequestParams{Int1: 3, Int2: 5, Limit: i + 1, Str1: "fizz", Str2: "buzz"}, 10-i)
+	}
+
+	tests := []struct {
+		name          string
+		maxTopN       int
+		query         string
+		wantEntries   int
+		wantTruncated bool
+		wantLimit     int
+		wantLink      string
+	}{
+		{name: "under the cap", maxTopN: 3, query: "limit=2", wantEntries: 2, wantLink: `</statistics/top?limit=2&offset=2>; rel="next"`},
+		{name: "at the cap", maxTopN: 3, query: "limit=3", wantEntries: 3, wantLink: `</statistics/top?limit=3&offset=3>; rel="next"`},
+		{name: "over the cap", maxTopN: 3, query: "limit=50", wantEntries: 3, wantTruncated: true, wantLimit: 3, wantLink: `</statistics/top?limit=3&offset=3>; rel="next"`},
+		{name: "cap below the default page size", maxTopN: 3, query: "", wantEntries: 3, wantLink: `</statistics/top?limit=3&offset=3>; rel="next"`},
+		{name: "default cap", query: "limit=101", wantEntries: 5, wantTruncated: true, wantLimit: 100},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(store, nil, WithMaxTopN(tc.maxTopN))
+
+			req := httptest.NewRequest(http.MethodGet, "/statistics/top?"+tc.query, nil)
+			rec := httptest.NewRecorder()
+			h.TopStatistics(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+			if got := rec.Header().Get("Link"); got != tc.wantLink {
+				t.Fatalf("expected Link %q, got %q", tc.wantLink, got)
+			}
+
+			var body struct {
+				Top       []StatisticsResponse `json:"top"`
+				Truncated bool                 `json:"truncated"`
+				Limit     int                  `json:"limit"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("failed to decode body: %v", err)
+			}
+			if len(body.Top) != tc.wantEntries {
+				t.Fatalf("expected %d entries, got %d", tc.wantEntries, len(body.Top))
+			}
+			if body.Truncated != tc.wantTruncated || body.Limit != tc.wantLimit {
+				t.Fatalf("expected truncated=%v limit=%d, got truncated=%v limit=%d",
+					tc.wantTruncated, tc.wantLimit, body.Truncated, body.Limit)
+			}
+		})
+	}
+}
+
+func TestHandler_TopStatistics_InvalidParams(t *testing.T) {
+	tests := []struct {
+		query         string
+		expectedError string
+	}{
+		{"limit=0", "limit must be greater than 0"},
+		{"offset=-1", "offset must be a non-negative integer"},
+		{"offset=abc", "offset must be a non-negative integer"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.query, func(t *testing.T) {
+			h := NewHandler(statistics.NewStore(), nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/statistics/top?"+tc.query, nil)
+			rec := httptest.NewRecorder()
+			h.TopStatistics(rec, req)
+
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+			}
+			assertErrorResponse(t, rec.Body.Bytes(), tc.expectedError)
+		})
+	}
+}
+
 func TestHandler_Statistics_ThroughRouter(t *testing.T) {
 	store := statistics.NewStore()
 	params := statistics.RequestParams{Int1: 3, Int2: 5, Limit: 15, Str1: "fizz", Str2: "buzz"}
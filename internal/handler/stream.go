@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/fizzbuzz"
+)
+
+// streamChunkSize is the number of items generated, written and flushed at a
+// time in stream mode.
+const streamChunkSize = 1000
+
+// WithStreamWriteTimeout gives each stream=true chunk its own write deadline
+// of d, so a long response is not cut off by the server's WriteTimeout, which
+// otherwise applies to the response as a whole. Zero leaves the server
+// deadline in place.
+func WithStreamWriteTimeout(d time.Duration) Option {
+	return func(h *Handler) {
+		h.streamWriteTimeout = d
+	}
+}
+
+// defaultStatisticsInterval is used when WithStatisticsInterval is not given.
+const defaultStatisticsInterval = 5 * time.Second
+
+// WithStatisticsInterval sets how often /statistics/stream pushes an event.
+func WithStatisticsInterval(d time.Duration) Option {
+	return func(h *Handler) {
+		h.statisticsInterval = d
+	}
+}
+
+// streamFizzBuzz writes the FizzBuzz response in chunks instead of building
+// it in memory first. Before each chunk the write deadline is pushed forward
+// by streamWriteTimeout. If the request context ends or a chunk's generation
+// panics part way through, the array is closed and the response marked
+// truncated, so the client always receives valid JSON with "returned"
+// telling it how much arrived.
+func (h *Handler) streamFizzBuzz(w http.ResponseWriter, r *http.Request, params fizzBuzzParams, limit int, truncated bool) {
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	buf := []byte(`{"result":[`)
+	returned := 0
+	for returned < limit {
+		if r.Context().Err() != nil {
+			truncated = true
+			break
+		}
+		if h.streamWriteTimeout > 0 {
+			err := rc.SetWriteDeadline(time.Now().Add(h.streamWriteTimeout))
+			if err != nil && !errors.Is(err, http.ErrNotSupported) {
+				h.logger.Debug("stream write deadline not extended", slog.String("error", err.Error()))
+			}
+		}
+
+		start := params.start + int64(returned)
+		count := min(streamChunkSize, limit-returned)
+		values, err := h.recoverGeneration(r, func() []string {
+			return h.generator.Generate(params.generation(start, count))
+		})
+		if err != nil {
+			// The status line is already sent; close what was written.
+			truncated = true
+			break
+		}
+		chunk := NumericResult{Values: values}
+		if params.numeric {
+			chunk.Numbers = make([]bool, count)
+			for _, index := range fizzbuzz.IndicesWith(params.int1, params.int2, start, count, fizzbuzz.CategoryNumber, params.rule) {
+				chunk.Numbers[index-1] = true
+			}
+		}
+
+		encoded, err := chunk.MarshalJSON()
+		if err != nil {
+			// The status line is already sent; close what was written.
+			truncated = true
+			break
+		}
+		if returned > 0 {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, encoded[1:len(encoded)-1]...)
+		if !h.writeStream(w, buf) {
+			return
+		}
+		_ = rc.Flush()
+		buf = buf[:0]
+		returned += count
+	}
+
+	buf = append(buf, ']')
+	if truncated {
+		buf = append(buf, `,"truncated":true,"returned":`...)
+		buf = strconv.AppendInt(buf, int64(returned), 10)
+	}
+	buf = append(buf, '}')
+	h.writeStream(w, buf)
+}
+
+// writeStream writes one stream chunk and reports whether the stream can
+// continue. A failed write means the connection is gone, so there is no one
+// left to send a closing bracket to.
+func (h *Handler) writeStream(w http.ResponseWriter, b []byte) bool {
+	if _, err := w.Write(b); err != nil {
+		h.logger.Error("stream write error", slog.String("error", err.Error()))
+		h.writeErrors.Inc()
+		return false
+	}
+	return true
+}
+
+// StreamStatistics is a Server-Sent Events feed of the most frequent request.
+// It sends a "statistics" event on connect and then every statistics
+// interval, in the same shape as /statistics, until the client disconnects.
+// While nothing has been recorded it sends a comment instead, which keeps the
+// connection alive without an event for clients to handle. The periodic
+// events come from statisticsFeed, so all clients share one store read per
+// interval.
+func (h *Handler) StreamStatistics(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	interval := h.statisticsInterval
+	if interval <= 0 {
+		interval = defaultStatisticsInterval
+	}
+	ticks, unsubscribe := h.statisticsFeed.subscribe(interval, h.readStatistics)
+	defer unsubscribe()
+
+	tick := h.readStatistics()
+	for {
+		if !h.sendStatisticsEvent(w, r, rc, tick) {
+			return
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case tick = <-ticks:
+		}
+	}
+}
+
+// readStatistics reads the most frequent request for statisticsFeed.
+func (h *Handler) readStatistics() statisticsTick {
+	if h.store == nil {
+		return statisticsTick{}
+	}
+	stats, ok := h.store.GetMostFrequent()
+	if !ok {
+		return statisticsTick{}
+	}
+	return statisticsTick{stats: *stats, ok: true}
+}
+
+// sendStatisticsEvent writes and flushes the event for tick, reporting
+// whether the feed can continue.
+func (h *Handler) sendStatisticsEvent(w http.ResponseWriter, r *http.Request, rc *http.ResponseController, tick statisticsTick) bool {
+	if h.streamWriteTimeout > 0 {
+		_ = rc.SetWriteDeadline(time.Now().Add(h.streamWriteTimeout))
+	}
+
+	event := []byte(": no statistics yet\n\n")
+	if tick.ok {
+		data, err := json.Marshal(h.statisticsPayload(r, &tick.stats))
+		if err != nil {
+			h.logger.Error("json marshal error", slog.String("error", err.Error()))
+			return false
+		}
+		event = append([]byte("event: statistics\ndata: "), data...)
+		event = append(event, "\n\n"...)
+	}
+
+	if !h.writeStream(w, event) {
+		return false
+	}
+	if err := rc.Flush(); err != nil {
+		h.logger.Error("statistics stream flush error", slog.String("error", err.Error()))
+		return false
+	}
+	return true
+}
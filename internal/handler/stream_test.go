@@ -0,0 +1,253 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/statistics"
+)
+
+func TestHandler_FizzBuzz_StreamMatchesBuffered(t *testing.T) {
+	tests := []struct {
+		name        string
+		queryParams string
+		opts        []Option
+	}{
+		{
+			name:        "classic",
+			queryParams: "int1=3&int2=5&limit=15&str1=fizz&str2=buzz",
+		},
+		{
+			name:        "spans several chunks",
+			queryParams: "int1=3&int2=5&limit=2500&str1=fizz&str2=buzz&start=-100",
+		},
+		{
+			name:        "numeric",
+			queryParams: "int1=3&int2=5&limit=1001&str1=fizz&str2=buzz&numeric=true",
+		},
+		{
+			name:        "templated",
+			queryParams: "int1=3&int2=5&limit=30&str1=fizz-{n}&str2=buzz&templated=true",
+		},
+		{
+			name:        "truncated by max limit",
+			queryParams: "int1=3&int2=5&limit=50&str1=fizz&str2=buzz",
+			opts:        []Option{WithMaxLimit(20, true)},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(statistics.NewStore(), nil, tc.opts...)
+
+			buffered := httptest.NewRecorder()
+			h.FizzBuzz(buffered, httptest.NewRequest(http.MethodGet, "/fizzbuzz?"+tc.queryParams, nil))
+
+			streamed := httptest.NewRecorder()
+			h.FizzBuzz(streamed, httptest.NewRequest(http.MethodGet, "/fizzbuzz?"+tc.queryParams+"&stream=true", nil))
+
+			if streamed.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, streamed.Code)
+			}
+			if contentType := streamed.Header().Get("Content-Type"); contentType != "application/json" {
+				t.Errorf("expected Content-Type application/json, got %s", contentType)
+			}
+			if !streamed.Flushed {
+				t.Error("expected the stream to be flushed")
+			}
+			if streamed.Body.String() != buffered.Body.String() {
+				t.Fatalf("streamed body differs from buffered body:\nstreamed: %.200s\nbuffered: %.200s",
+					streamed.Body.String(), buffered.Body.String())
+			}
+		})
+	}
+}
+
+// deadlineRecorder records write deadline extensions and ends the request
+// context after a set number of writes, standing in for a response that runs
+// into its deadline part way through.
+type deadlineRecorder struct {
+	*httptest.ResponseRecorder
+	cancel      context.CancelFunc
+	writesLeft  int
+	deadlineSet int
+}
+
+func (d *deadlineRecorder) Write(b []byte) (int, error) {
+	n, err := d.ResponseRecorder.Write(b)
+	d.writesLeft--
+	if d.writesLeft == 0 {
+		d.cancel()
+	}
+	return n, err
+}
+
+func (d *deadlineRecorder) SetWriteDeadline(time.Time) error {
+	d.deadlineSet++
+	return nil
+}
+
+func TestHandler_FizzBuzz_StreamUnderDeadline(t *testing.T) {
+	tests := []struct {
+		name             string
+		writesBeforeDone int
+		expectedReturned int
+	}{
+		{name: "deadline before first chunk", writesBeforeDone: 0, expectedReturned: 0},
+		{name: "deadline after first chunk", writesBeforeDone: 1, expectedReturned: streamChunkSize},
+		{name: "deadline after two chunks", writesBeforeDone: 2, expectedReturned: 2 * streamChunkSize},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(statistics.NewStore(), nil, WithStreamWriteTimeout(time.Second))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tc.writesBeforeDone == 0 {
+				cancel()
+			}
+			rec := &deadlineRecorder{ResponseRecorder: httptest.NewRecorder(), cancel: cancel, writesLeft: tc.writesBeforeDone}
+			req := httptest.NewRequestWithContext(ctx, http.MethodGet,
+				"/fizzbuzz?int1=3&int2=5&limit=10000&str1=fizz&str2=buzz&stream=true", nil)
+
+			h.FizzBuzz(rec, req)
+
+			var body FizzBuzzResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("expected valid JSON, got %v: %.200s", err, rec.Body.String())
+			}
+			if !body.Truncated {
+				t.Error("expected truncated to be true")
+			}
+			if body.Returned != tc.expectedReturned || len(body.Result) != tc.expectedReturned {
+				t.Errorf("expected %d items, got returned=%d with %d results", tc.expectedReturned, body.Returned, len(body.Result))
+			}
+			if rec.deadlineSet != tc.writesBeforeDone {
+				t.Errorf("expected %d write deadline extensions, got %d", tc.writesBeforeDone, rec.deadlineSet)
+			}
+		})
+	}
+}
+
+func TestHandler_FizzBuzz_StreamValidation(t *testing.T) {
+	tests := []struct {
+		name          string
+		queryParams   string
+		expectedError string
+	}{
+		{
+			name:          "invalid stream",
+			queryParams:   "int1=3&int2=5&limit=15&str1=fizz&str2=buzz&stream=maybe",
+			expectedError: "stream must be a boolean",
+		},
+		{
+			name:          "stream with shuffle",
+			queryParams:   "int1=3&int2=5&limit=15&str1=fizz&str2=buzz&stream=true&shuffle=true",
+			expectedError: "stream cannot be combined with shuffle",
+		},
+		{
+			name:          "stream with only",
+			queryParams:   "int1=3&int2=5&limit=15&str1=fizz&str2=buzz&stream=true&only=str1",
+			expectedError: "stream cannot be combined with only",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(statistics.NewStore(), nil)
+
+			rec := httptest.NewRecorder()
+			h.FizzBuzz(rec, httptest.NewRequest(http.MethodGet, "/fizzbuzz?"+tc.queryParams, nil))
+
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+			}
+			var body ErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("failed to decode body: %v", err)
+			}
+			if body.Error != tc.expectedError {
+				t.Errorf("expected error %q, got %q", tc.expectedError, body.Error)
+			}
+		})
+	}
+}
+
+func TestHandler_StreamStatistics(t *testing.T) {
+	store := statistics.NewStore()
+	store.Record(statistics.RequestParams{Int1: 3, Int2: 5, Limit: 15, Str1: "fizz", Str2: "buzz"})
+	h := NewHandler(store, nil, WithStatisticsInterval(10*time.Millisecond))
+
+	server := httptest.NewServer(http.HandlerFunc(h.StreamStatistics))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer res.Body.Close()
+
+	if contentType := res.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Fatalf("expected Content-Type text/event-stream, got %s", contentType)
+	}
+
+	scanner := bufio.NewScanner(res.Body)
+	for events := 0; events < 2; {
+		if !scanner.Scan() {
+			t.Fatalf("stream ended after %d events: %v", events, scanner.Err())
+		}
+		line := scanner.Text()
+		if line == "" || line == "event: statistics" {
+			continue
+		}
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok {
+			t.Fatalf("unexpected line %q", line)
+		}
+		var body StatisticsResponse
+		if err := json.Unmarshal([]byte(data), &body); err != nil {
+			t.Fatalf("failed to decode event data %q: %v", data, err)
+		}
+		if body.Hits != 1 || body.Params.Str1 != "fizz" {
+			t.Fatalf("unexpected event %+v", body)
+		}
+		events++
+	}
+
+	cancel()
+}
+
+func TestHandler_StreamStatistics_EmptyAndDisconnect(t *testing.T) {
+	h := NewHandler(statistics.NewStore(), nil, WithStatisticsInterval(time.Hour))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rec := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.StreamStatistics(rec, httptest.NewRequestWithContext(ctx, http.MethodGet, "/statistics/stream", nil))
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("stream did not stop after the client disconnected")
+	}
+	if got := rec.Body.String(); got != ": no statistics yet\n\n" {
+		t.Fatalf("expected a keep-alive comment, got %q", got)
+	}
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// UsageErrorResponse is the error returned for a bare /fizzbuzz request
+// when WithUsageHint is enabled.
+type UsageErrorResponse struct {
+	Error string    `json:"error"`
+	Usage UsageHint `json:"usage"`
+}
+
+// UsageHint shows a complete /fizzbuzz request, both as a URL and as the
+// parameters it carries.
+type UsageHint struct {
+	Example string           `json:"example"`
+	Params  StatisticsParams `json:"params"`
+}
+
+// usageExample is the classic sequence, which every deployment accepts
+// unless ALLOWED_STRINGS or the divisor range excludes it.
+var usageExample = StatisticsParams{Int1: 3, Int2: 5, Limit: 15, Str1: "fizz", Str2: "buzz"}
+
+// respondUsage answers err, the validation error of a bare request, with
+// usageExample attached. The example URL keeps the request path, so it stays
+// correct under ROUTE_PREFIX.
+func (h *Handler) respondUsage(w http.ResponseWriter, r *http.Request, err error) {
+	example := url.Values{
+		"int1":  {strconv.FormatInt(usageExample.Int1, 10)},
+		"int2":  {strconv.FormatInt(usageExample.Int2, 10)},
+		"limit": {strconv.Itoa(usageExample.Limit)},
+		"str1":  {usageExample.Str1},
+		"str2":  {usageExample.Str2},
+	}
+	h.respondJSON(w, r, h.validationStatus(err), UsageErrorResponse{
+		Error: err.Error(),
+		Usage: UsageHint{Example: r.URL.Path + "?" + example.Encode(), Params: usageExample},
+	})
+}
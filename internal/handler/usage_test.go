@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/statistics"
+)
+
+func TestHandler_FizzBuzz_UsageHint(t *testing.T) {
+	h := NewHandler(statistics.NewStore(), nil, WithUsageHint(true))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/fizzbuzz", nil)
+	rec := httptest.NewRecorder()
+	h.FizzBuzz(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+	var got UsageErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	want := UsageErrorResponse{
+		Error: "missing required parameters: int1, int2, limit, str1, str2",
+		Usage: UsageHint{
+			Example: "/api/v1/fizzbuzz?int1=3&int2=5&limit=15&str1=fizz&str2=buzz",
+			Params:  StatisticsParams{Int1: 3, Int2: 5, Limit: 15, Str1: "fizz", Str2: "buzz"},
+		},
+	}
+	if got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestHandler_FizzBuzz_UsageHintOnlyForBareRequests(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		opts   []Option
+		accept string
+	}{
+		{name: "disabled by default", target: "/fizzbuzz"},
+		{name: "some parameters given", target: "/fizzbuzz?int1=3", opts: []Option{WithUsageHint(true)}},
+		{name: "problem details requested", target: "/fizzbuzz", opts: []Option{WithUsageHint(true)}, accept: problemContentType},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(statistics.NewStore(), nil, tc.opts...)
+
+			req := httptest.NewRequest(http.MethodGet, tc.target, nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			rec := httptest.NewRecorder()
+			h.FizzBuzz(rec, req)
+
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+			}
+			if strings.Contains(rec.Body.String(), `"usage"`) {
+				t.Fatalf("expected no usage hint, got %s", rec.Body.String())
+			}
+		})
+	}
+}
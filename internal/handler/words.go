@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/statistics"
+)
+
+// WordHits is how often a word was requested in one position.
+type WordHits struct {
+	Word string `json:"word"`
+	Hits int    `json:"hits"`
+}
+
+// WordsResponse lists the distinct str1 and str2 values requested, most
+// requested first. Each list holds at most MAX_TOP_N words; Truncated is set
+// when either was cut.
+type WordsResponse struct {
+	Str1      []WordHits `json:"str1"`
+	Str2      []WordHits `json:"str2"`
+	Truncated bool       `json:"truncated,omitempty"`
+}
+
+// WordsStatistics returns the words requested as str1 and str2 with their hit
+// counts. Ties are ordered by word.
+func (h *Handler) WordsStatistics(w http.ResponseWriter, r *http.Request) {
+	response := WordsResponse{Str1: []WordHits{}, Str2: []WordHits{}}
+	if h == nil || h.store == nil {
+		h.respondJSON(w, r, http.StatusOK, response)
+		return
+	}
+
+	maxWords := defaultMaxTopN
+	if h.maxTopN > 0 {
+		maxWords = h.maxTopN
+	}
+	str1, str2, truncated := h.store.TopWords(maxWords)
+	response.Str1 = appendWordHits(response.Str1, str1)
+	response.Str2 = appendWordHits(response.Str2, str2)
+	response.Truncated = truncated
+
+	h.respondJSON(w, r, http.StatusOK, response)
+}
+
+func appendWordHits(dst []WordHits, counts []statistics.WordCount) []WordHits {
+	for _, count := range counts {
+		dst = append(dst, WordHits{Word: count.Word, Hits: count.Hits})
+	}
+	return dst
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/statistics"
+)
+
+func callWords(t *testing.T, h *Handler) WordsResponse {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	h.WordsStatistics(rec, httptest.NewRequest(http.MethodGet, "/statistics/words", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	var body WordsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	return body
+}
+
+func TestHandler_WordsStatistics(t *testing.T) {
+	store := statistics.NewStore()
+	recordRequest(store, statistics.RequestParams{Int1: 3, Int2: 5, Limit: 15, Str1: "fizz", Str2: "buzz"}, 3)
+	recordRequest(store, statistics.RequestParams{Int1: 2, Int2: 7, Limit: 15, Str1: "foo", Str2: "buzz"}, 2)
+	recordRequest(store, statistics.RequestParams{Int1: 2, Int2: 7, Limit: 30, Str1: "bar", Str2: "baz"}, 2)
+
+	got := callWords(t, NewHandler(store, nil))
+	want := WordsResponse{
+		Str1: []WordHits{{Word: "fizz", Hits: 3}, {Word: "bar", Hits: 2}, {Word: "foo", Hits: 2}},
+		Str2: []WordHits{{Word: "buzz", Hits: 5}, {Word: "baz", Hits: 2}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestHandler_WordsStatistics_CappedAtMaxTopN(t *testing.T) {
+	store := statistics.NewStore()
+	for i, word := range []string{"a", "b", "c"} {
+		recordRequest(store, statistics.RequestParams{Int1: 3, Int2: 5, Limit: 15, Str1: word, Str2: "buzz"}, 3-i)
+	}
+
+	got := callWords(t, NewHandler(store, nil, WithMaxTopN(2)))
+	want := WordsResponse{
+		Str1:      []WordHits{{Word: "a", Hits: 3}, {Word: "b", Hits: 2}},
+		Str2:      []WordHits{{Word: "buzz", Hits: 6}},
+		Truncated: true,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestHandler_WordsStatistics_Empty(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewHandler(statistics.NewStore(), nil).WordsStatistics(rec, httptest.NewRequest(http.MethodGet, "/statistics/words", nil))
+
+	if got, want := rec.Body.String(), `{"str1":[],"str2":[]}`; got != want {
+		t.Fatalf("expected body %s, got %s", want, got)
+	}
+}
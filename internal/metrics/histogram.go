@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// HistogramVec is a family of histograms that share buckets and are told
+// apart by label values. A nil HistogramVec is a no-op.
+type HistogramVec struct {
+	help       string
+	buckets    []float64
+	labelNames []string
+
+	mu     sync.Mutex
+	series map[string]*histogram
+}
+
+type histogram struct {
+	labelValues []string
+	// counts[i] is the number of observations in bucket i alone; the
+	// cumulative form is computed when rendering. The last slot is +Inf.
+	counts []int64
+	sum    float64
+	count  int64
+}
+
+// Observe records value in the histogram for labelValues, which must match
+// the label names the family was registered with.
+func (v *HistogramVec) Observe(value float64, labelValues ...string) {
+	if v == nil {
+		return
+	}
+	if len(labelValues) != len(v.labelNames) {
+		panic(fmt.Sprintf("metrics: %d label values for %d labels", len(labelValues), len(v.labelNames)))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	h, ok := v.series[key]
+	if !ok {
+		h = &histogram{labelValues: labelValues, counts: make([]int64, len(v.buckets)+1)}
+		v.series[key] = h
+	}
+	h.counts[sort.SearchFloat64s(v.buckets, value)]++
+	h.sum += value
+	h.count++
+}
+
+// HistogramVec returns the histogram family registered under name, creating
+// it with the given upper bucket bounds and label names if needed. It returns
+// nil when called on a nil Registry.
+func (r *Registry) HistogramVec(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if v, ok := r.histograms[name]; ok {
+		return v
+	}
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	v := &HistogramVec{help: help, buckets: sorted, labelNames: labelNames, series: make(map[string]*histogram)}
+	r.histograms[name] = v
+	return v
+}
+
+// writeText renders every series of the family, ordered by label values.
+func (v *HistogramVec) writeText(w io.Writer, name string) error {
+	v.mu.Lock()
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s histogram\n", name, v.help, name)
+	for _, key := range keys {
+		h := v.series[key]
+		labels := v.labels(h.labelValues)
+		var cumulative int64
+		for i, upper := range v.buckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(&b, "%s_bucket{%sle=%q} %d\n", name, labels, strconv.FormatFloat(upper, 'g', -1, 64), cumulative)
+		}
+		fmt.Fprintf(&b, "%s_bucket{%sle=\"+Inf\"} %d\n", name, labels, h.count)
+		series := ""
+		if labels != "" {
+			series = "{" + strings.TrimSuffix(labels, ",") + "}"
+		}
+		fmt.Fprintf(&b, "%s_sum%s %s\n", name, series, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(&b, "%s_count%s %d\n", name, series, h.count)
+	}
+	v.mu.Unlock()
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// labels renders name="value" pairs, each followed by a comma so an le label
+// can be appended.
+func (v *HistogramVec) labels(values []string) string {
+	var b strings.Builder
+	for i, name := range v.labelNames {
+		fmt.Fprintf(&b, "%s=%q,", name, values[i])
+	}
+	return b.String()
+}
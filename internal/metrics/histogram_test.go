@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHistogramVec_NilIsNoop(t *testing.T) {
+	var v *HistogramVec
+	v.Observe(42, "a")
+
+	var r *Registry
+	if got := r.HistogramVec("sizes", "help", []float64{1}, "route"); got != nil {
+		t.Fatalf("HistogramVec() on nil registry = %v, want nil", got)
+	}
+}
+
+func TestHistogramVec_WriteText(t *testing.T) {
+	r := NewRegistry()
+	v := r.HistogramVec("response_size_bytes", "Response sizes.", []float64{100, 10}, "route", "status")
+	if again := r.HistogramVec("response_size_bytes", "Response sizes.", nil, "route", "status"); again != v {
+		t.Fatalf("expected the same histogram for the same name")
+	}
+
+	v.Observe(5, "/a", "200")
+	v.Observe(10, "/a", "200")
+	v.Observe(50, "/a", "200")
+	v.Observe(500, "/a", "200")
+	v.Observe(20, "/a", "400")
+
+	var b strings.Builder
+	if err := r.WriteText(&b); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+
+	want := `# HELP response_size_bytes Response sizes.
+# TYPE response_size_bytes histogram
+response_size_bytes_bucket{route="/a",status="200",le="10"} 2
+response_size_bytes_bucket{route="/a",status="200",le="100"} 3
+response_size_bytes_bucket{route="/a",status="200",le="+Inf"} 4
+response_size_bytes_sum{route="/a",status="200"} 565
+response_size_bytes_count{route="/a",status="200"} 4
+response_size_bytes_bucket{route="/a",status="400",le="10"} 0
+response_size_bytes_bucket{route="/a",status="400",le="100"} 1
+response_size_bytes_bucket{route="/a",status="400",le="+Inf"} 1
+response_size_bytes_sum{route="/a",status="400"} 20
+response_size_bytes_count{route="/a",status="400"} 1
+`
+	if got := b.String(); got != want {
+		t.Fatalf("WriteText() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestHistogramVec_LabelMismatchPanics(t *testing.T) {
+	v := NewRegistry().HistogramVec("sizes", "help", []float64{1}, "route", "status")
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic for a missing label value")
+		}
+	}()
+	v.Observe(1, "/a")
+}
@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Counter is a monotonically increasing value. A nil Counter is a no-op, so
+// callers can increment unconditionally when metrics are disabled.
+type Counter struct {
+	value atomic.Int64
+}
+
+// Inc increments the counter by one.
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add increments the counter by n.
+func (c *Counter) Add(n int64) {
+	if c == nil {
+		return
+	}
+	c.value.Add(n)
+}
+
+// Value returns the current counter value.
+func (c *Counter) Value() int64 {
+	if c == nil {
+		return 0
+	}
+	return c.value.Load()
+}
+
+type counterEntry struct {
+	help    string
+	counter *Counter
+}
+
+// Registry holds named metrics and renders them in the Prometheus text format.
+type Registry struct {
+	mu         sync.Mutex
+	counters   map[string]*counterEntry
+	histograms map[string]*HistogramVec
+}
+
+// NewRegistry returns an initialized Registry instance.
+func NewRegistry() *Registry {
+	return &Registry{
+		counters:   make(map[string]*counterEntry),
+		histograms: make(map[string]*HistogramVec),
+	}
+}
+
+// Counter returns the counter registered under name, creating it if needed.
+// It returns nil when called on a nil Registry.
+func (r *Registry) Counter(name, help string) *Counter {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if entry, ok := r.counters[name]; ok {
+		return entry.counter
+	}
+
+	entry := &counterEntry{help: help, counter: &Counter{}}
+	r.counters[name] = entry
+	return entry.counter
+}
+
+// WriteText writes every registered metric in the Prometheus text format,
+// sorted by name.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.counters)+len(r.histograms))
+	for name := range r.counters {
+		names = append(names, name)
+	}
+	for name := range r.histograms {
+		names = append(names, name)
+	}
+	entries := make(map[string]*counterEntry, len(r.counters))
+	for name, entry := range r.counters {
+		entries[name] = entry
+	}
+	histograms := make(map[string]*HistogramVec, len(r.histograms))
+	for name, v := range r.histograms {
+		histograms[name] = v
+	}
+	r.mu.Unlock()
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		if v, ok := histograms[name]; ok {
+			if err := v.writeText(w, name); err != nil {
+				return err
+			}
+			continue
+		}
+		entry := entries[name]
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %d\n",
+			name, entry.help, name, name, entry.counter.Value()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ServeHTTP exposes the registry for scraping.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	_ = r.WriteText(w)
+}
@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCounter_NilIsNoop(t *testing.T) {
+	var c *Counter
+	c.Inc()
+	c.Add(5)
+
+	if got := c.Value(); got != 0 {
+		t.Fatalf("Value() = %d, want 0", got)
+	}
+
+	var r *Registry
+	if got := r.Counter("anything_total", "help"); got != nil {
+		t.Fatalf("Counter() on nil registry = %v, want nil", got)
+	}
+}
+
+func TestRegistry_CounterReturnsSameInstance(t *testing.T) {
+	r := NewRegistry()
+
+	first := r.Counter("requests_total", "Total requests.")
+	second := r.Counter("requests_total", "Total requests.")
+	if first != second {
+		t.Fatalf("expected the same counter for the same name")
+	}
+}
+
+func TestRegistry_ConcurrentIncrements(t *testing.T) {
+	r := NewRegistry()
+	c := r.Counter("requests_total", "Total requests.")
+
+	var wg sync.WaitGroup
+	for range 100 {
+		wg.Go(func() {
+			c.Inc()
+		})
+	}
+	wg.Wait()
+
+	if got := c.Value(); got != 100 {
+		t.Fatalf("Value() = %d, want 100", got)
+	}
+}
+
+func TestRegistry_ServeHTTP(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("b_total", "Second metric.").Add(2)
+	r.Counter("a_total", "First metric.").Inc()
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	want := strings.Join([]string{
+		"# HELP a_total First metric.",
+		"# TYPE a_total counter",
+		"a_total 1",
+		"# HELP b_total Second metric.",
+		"# TYPE b_total counter",
+		"b_total 2",
+		"",
+	}, "\n")
+
+	if got := rec.Body.String(); got != want {
+		t.Fatalf("expected body:\n%s\ngot:\n%s", want, got)
+	}
+	if contentType := rec.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain") {
+		t.Fatalf("expected text/plain content type, got %q", contentType)
+	}
+}
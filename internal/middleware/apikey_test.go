@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireAPIKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		headers map[string]string
+		want    int
+	}{
+		{"missing credentials", "secret", nil, http.StatusUnauthorized},
+		{"wrong bearer token", "secret", map[string]string{"Authorization": "Bearer nope"}, http.StatusUnauthorized},
+		{"valid bearer token", "secret", map[string]string{"Authorization": "Bearer secret"}, http.StatusOK},
+		{"valid X-API-Key", "secret", map[string]string{"X-API-Key": "secret"}, http.StatusOK},
+		{"empty configured key rejects everything", "", map[string]string{"X-API-Key": ""}, http.StatusUnauthorized},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := RequireAPIKey(tc.key)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/health/toggle", nil)
+			for key, value := range tc.headers {
+				req.Header.Set(key, value)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tc.want {
+				t.Fatalf("expected status %d, got %d", tc.want, rec.Code)
+			}
+		})
+	}
+}
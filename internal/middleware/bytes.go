@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/metrics"
+)
+
+// CountBytes returns middleware that adds the number of response body bytes
+// written to counter. A nil counter disables counting.
+func CountBytes(counter *metrics.Counter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if counter == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			wrapped := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+			defer func() { counter.Add(int64(wrapped.bytes)) }()
+
+			next.ServeHTTP(wrapped, r)
+		})
+	}
+}
+
+// ResponseSizeBuckets are the upper bounds, in bytes, of the response size
+// histogram: powers of four from 64 B to 4 MiB.
+var ResponseSizeBuckets = []float64{64, 256, 1024, 4096, 16384, 65536, 262144, 1048576, 4194304}
+
+// routeLabel is the matched route pattern, or "unmatched" so arbitrary paths
+// cannot grow a metric's series count.
+func routeLabel(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+		return rctx.RoutePattern()
+	}
+	return "unmatched"
+}
+
+// observeDuration records d in seconds in durations, labeled like
+// ResponseSizes. A nil histogram is a no-op.
+func observeDuration(durations *metrics.HistogramVec, r *http.Request, status int, d time.Duration) {
+	durations.Observe(d.Seconds(), routeLabel(r), strconv.Itoa(status))
+}
+
+// ResponseSizes returns middleware that observes each response body size in
+// sizes, labeled by route pattern and status code. Requests that match no
+// route are labeled "unmatched" so arbitrary paths cannot grow the series
+// count. A nil histogram disables it.
+func ResponseSizes(sizes *metrics.HistogramVec) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if sizes == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			wrapped := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+			defer func() {
+				sizes.Observe(float64(wrapped.bytes), routeLabel(r), strconv.Itoa(wrapped.status))
+			}()
+
+			next.ServeHTTP(wrapped, r)
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"testing/synctest"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/metrics"
+)
+
+func TestCountBytes_SumsResponseBodies(t *testing.T) {
+	counter := metrics.NewRegistry().Counter("bytes_total", "Bytes written.")
+	wrapped := CountBytes(counter)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(strings.Repeat("x", len(r.URL.Query().Get("body")))))
+	}))
+
+	for _, body := range []string{"a", "abcd", "abcdefghij"} {
+		makeRequest(t, wrapped, "/fizzbuzz?body="+body)
+	}
+
+	if got := counter.Value(); got != 15 {
+		t.Fatalf("counter = %d, want 15", got)
+	}
+}
+
+func TestCountBytes_Concurrent(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		counter := metrics.NewRegistry().Counter("bytes_total", "Bytes written.")
+		wrapped := CountBytes(counter)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("hello"))
+			_, _ = w.Write([]byte("!"))
+		}))
+
+		var wg sync.WaitGroup
+		for range 50 {
+			wg.Go(func() {
+				makeRequest(t, wrapped, "/fizzbuzz")
+			})
+		}
+		wg.Wait()
+
+		if got := counter.Value(); got != 300 {
+			t.Fatalf("counter = %d, want 300", got)
+		}
+	})
+}
+
+func TestCountBytes_NilCounter(t *testing.T) {
+	wrapped := CountBytes(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+
+	if rec := makeRequest(t, wrapped, "/fizzbuzz"); rec.Body.String() != "ok" {
+		t.Fatalf("body = %q, want ok", rec.Body.String())
+	}
+}
+
+func TestResponseSizes_ObservesByRouteAndStatus(t *testing.T) {
+	registry := metrics.NewRegistry()
+	sizes := registry.HistogramVec("response_size_bytes", "Response sizes.", []float64{4, 64}, "route", "status")
+
+	router := chi.NewRouter()
+	router.Use(ResponseSizes(sizes))
+	router.Get("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if chi.URLParam(r, "id") == "missing" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(strings.Repeat("x", len(chi.URLParam(r, "id")))))
+	})
+
+	for _, target := range []string{"/items/a", "/items/abcdefgh", "/items/missing", "/nowhere"} {
+		makeRequest(t, router, target)
+	}
+
+	var b strings.Builder
+	if err := registry.WriteText(&b); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+	for _, want := range []string{
+		`response_size_bytes_bucket{route="/items/{id}",status="200",le="4"} 1`,
+		`response_size_bytes_sum{route="/items/{id}",status="200"} 9`,
+		`response_size_bytes_count{route="/items/{id}",status="200"} 2`,
+		`response_size_bytes_sum{route="/items/{id}",status="404"} 10`,
+		`response_size_bytes_count{route="unmatched",status="404"} 1`,
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("missing %q in:\n%s", want, b.String())
+		}
+	}
+}
+
+func TestResponseSizes_NilHistogram(t *testing.T) {
+	wrapped := ResponseSizes(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+
+	if rec := makeRequest(t, wrapped, "/fizzbuzz"); rec.Body.String() != "ok" {
+		t.Fatalf("body = %q, want ok", rec.Body.String())
+	}
+}
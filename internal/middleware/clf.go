@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/metrics"
+)
+
+const clfTimeLayout = "02/Jan/2006:15:04:05 -0700"
+
+// CommonLogFormat returns middleware that writes one Apache/NGINX-style
+// Common Log Format line per request to out:
+//
+//	host ident authuser [date] "method uri proto" status bytes
+//
+// Request durations are observed in durations as RequestLogger does.
+func CommonLogFormat(out io.Writer, durations *metrics.HistogramVec) func(http.Handler) http.Handler {
+	var mu sync.Mutex
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			wrapped := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+
+			defer func() {
+				observeDuration(durations, r, wrapped.status, time.Since(start))
+				if out == nil {
+					return
+				}
+				line := formatCLF(r, wrapped.status, wrapped.bytes, start)
+				mu.Lock()
+				defer mu.Unlock()
+				_, _ = io.WriteString(out, line)
+			}()
+
+			next.ServeHTTP(wrapped, r)
+		})
+	}
+}
+
+func formatCLF(r *http.Request, status, bytes int, at time.Time) string {
+	host := r.RemoteAddr
+	if h, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		host = h
+	}
+	if host == "" {
+		host = "-"
+	}
+
+	user := "-"
+	if u, _, ok := r.BasicAuth(); ok && u != "" {
+		user = u
+	}
+
+	size := "-"
+	if bytes > 0 {
+		size = strconv.Itoa(bytes)
+	}
+
+	return fmt.Sprintf("%s - %s [%s] \"%s %s %s\" %d %s\n",
+		host, user, at.Format(clfTimeLayout), r.Method, r.URL.RequestURI(), r.Proto, status, size)
+}
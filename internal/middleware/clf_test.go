@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestCommonLogFormat_WritesLine(t *testing.T) {
+	var buf bytes.Buffer
+	handler := CommonLogFormat(&buf, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		_, _ = w.Write([]byte("short and stout"))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/fizzbuzz?int1=3&limit=15", nil)
+	req.RemoteAddr = "203.0.113.9:52311"
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	line := buf.String()
+	pattern := regexp.MustCompile(`^(\S+) - (\S+) \[([^\]]+)\] "(\S+) (\S+) (\S+)" (\d{3}) (\S+)\n$`)
+	match := pattern.FindStringSubmatch(line)
+	if match == nil {
+		t.Fatalf("line %q does not match Common Log Format", line)
+	}
+
+	checks := []struct {
+		field string
+		got   string
+		want  string
+	}{
+		{"host", match[1], "203.0.113.9"},
+		{"user", match[2], "-"},
+		{"method", match[4], http.MethodGet},
+		{"path", match[5], "/fizzbuzz?int1=3&limit=15"},
+		{"proto", match[6], "HTTP/1.1"},
+		{"status", match[7], "418"},
+		{"bytes", match[8], "15"},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %q, want %q", c.field, c.got, c.want)
+		}
+	}
+}
+
+func TestCommonLogFormat_EmptyBodyUsesDash(t *testing.T) {
+	var buf bytes.Buffer
+	handler := CommonLogFormat(&buf, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/statistics", nil))
+
+	if line := buf.String(); !strings.HasSuffix(line, `"DELETE /statistics HTTP/1.1" 204 -`+"\n") {
+		t.Fatalf("unexpected line %q", line)
+	}
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// twoDivisorParams are the defaults that do not apply to requests listing
+// rule=divisor:word pairs, which reject them.
+var twoDivisorParams = []string{"int1", "int2", "str1", "str2"}
+
+// DefaultQueryParams returns middleware that fills query parameters absent
+// from the request with the configured defaults, so downstream handlers and
+// statistics see the effective values. Parameters present with an empty
+// value are left alone, and requests with rule=divisor:word pairs only get
+// the defaults outside twoDivisorParams. A nil or empty map is a no-op.
+func DefaultQueryParams(defaults map[string]string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if len(defaults) == 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			query := r.URL.Query()
+			rulePairs := slices.ContainsFunc(query["rule"], func(v string) bool {
+				return strings.Contains(v, ":")
+			})
+			changed := false
+			for name, value := range defaults {
+				if rulePairs && slices.Contains(twoDivisorParams, name) {
+					continue
+				}
+				if _, ok := query[name]; !ok {
+					query.Set(name, value)
+					changed = true
+				}
+			}
+			if !changed {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			r2 := r.Clone(r.Context())
+			r2.URL.RawQuery = query.Encode()
+			r2.RequestURI = r2.URL.RequestURI()
+			next.ServeHTTP(w, r2)
+		})
+	}
+}
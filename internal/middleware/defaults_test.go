@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestDefaultQueryParams(t *testing.T) {
+	defaults := map[string]string{"int1": "3", "str1": "fizz", "limit": "15"}
+
+	tests := []struct {
+		name   string
+		target string
+		want   url.Values
+	}{
+		{
+			name:   "fills absent params",
+			target: "/fizzbuzz?int2=5&str2=buzz",
+			want:   url.Values{"int1": {"3"}, "int2": {"5"}, "limit": {"15"}, "str1": {"fizz"}, "str2": {"buzz"}},
+		},
+		{
+			name:   "client values win",
+			target: "/fizzbuzz?int1=2&str1=foo&limit=5",
+			want:   url.Values{"int1": {"2"}, "str1": {"foo"}, "limit": {"5"}},
+		},
+		{
+			name:   "empty values are not replaced",
+			target: "/fizzbuzz?int1=&str1=&limit=",
+			want:   url.Values{"int1": {""}, "str1": {""}, "limit": {""}},
+		},
+		{
+			name:   "rule pairs only get limit",
+			target: "/fizzbuzz?rule=3:fizz",
+			want:   url.Values{"rule": {"3:fizz"}, "limit": {"15"}},
+		},
+		{
+			name:   "a match mode alone is not a rule pair",
+			target: "/fizzbuzz?rule=digitsum",
+			want:   url.Values{"rule": {"digitsum"}, "int1": {"3"}, "str1": {"fizz"}, "limit": {"15"}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var got url.Values
+			wrapped := DefaultQueryParams(defaults)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.URL.Query()
+			}))
+
+			makeRequest(t, wrapped, tc.target)
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("query = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDefaultQueryParams_Empty(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	if got := DefaultQueryParams(nil)(next); reflect.ValueOf(got).Pointer() != reflect.ValueOf(next).Pointer() {
+		t.Fatal("expected nil defaults to return the next handler unchanged")
+	}
+}
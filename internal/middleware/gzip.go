@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Gzip returns middleware that gzip-compresses response bodies for clients
+// that accept it. Once a compressed response is complete, its uncompressed
+// and compressed sizes and their ratio are logged at DEBUG, to tell which
+// responses are worth compressing. A nil logger disables the log.
+// HEAD requests, bodiless statuses and responses that already set a
+// Content-Encoding pass through unchanged.
+func Gzip(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			if r.Method == http.MethodHead || !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			gw := &gzipWriter{ResponseWriter: w}
+			defer func() {
+				if gw.gz == nil {
+					return
+				}
+				_ = gw.gz.Close()
+				if logger != nil && gw.compressed.n > 0 {
+					logger.LogAttrs(r.Context(), slog.LevelDebug, "response compressed",
+						slog.String("path", r.URL.Path),
+						slog.Int64("uncompressed_bytes", gw.uncompressed),
+						slog.Int64("compressed_bytes", gw.compressed.n),
+						slog.Float64("ratio", float64(gw.uncompressed)/float64(gw.compressed.n)),
+					)
+				}
+			}()
+
+			next.ServeHTTP(gw, r)
+		})
+	}
+}
+
+// acceptsGzip reports whether an Accept-Encoding header lists gzip without
+// refusing it with q=0.
+func acceptsGzip(header string) bool {
+	for part := range strings.SplitSeq(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+		if raw, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			q, err := strconv.ParseFloat(raw, 64)
+			return err == nil && q > 0
+		}
+		return true
+	}
+	return false
+}
+
+// byteCounter counts the bytes written through it.
+type byteCounter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *byteCounter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.n += int64(n)
+	return n, err
+}
+
+// gzipWriter decides at WriteHeader whether to compress, then routes the
+// body through a gzip.Writer, counting bytes on both sides of it.
+type gzipWriter struct {
+	http.ResponseWriter
+	gz           *gzip.Writer
+	compressed   byteCounter
+	uncompressed int64
+	wroteHeader  bool
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, so
+// streaming handlers can extend write deadlines through it.
+func (w *gzipWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *gzipWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	bodiless := code < http.StatusOK || code == http.StatusNoContent || code == http.StatusNotModified
+	if !bodiless && w.Header().Get("Content-Encoding") == "" {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Del("Content-Length")
+		w.compressed = byteCounter{w: w.ResponseWriter}
+		w.gz = gzip.NewWriter(&w.compressed)
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *gzipWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.gz == nil {
+		return w.ResponseWriter.Write(b)
+	}
+	n, err := w.gz.Write(b)
+	w.uncompressed += int64(n)
+	return n, err
+}
+
+// FlushError pushes compressed data buffered so far to the client, so
+// streamed responses keep arriving as they are written.
+func (w *gzipWriter) FlushError() error {
+	if w.gz != nil {
+		if err := w.gz.Flush(); err != nil {
+			return err
+		}
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGzip_LogsCompressionRatio(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	body := strings.Repeat("fizz,buzz,fizzbuzz,", 500)
+
+	wrapped := Gzip(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "9500")
+		_, _ = io.WriteString(w, body)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/fizzbuzz", nil)
+	req.Header.Set("Accept-Encoding", "br, gzip;q=0.8")
+	rec := httptest.NewRecorder()
+	wrapped.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", got)
+	}
+	if got := rec.Header().Get("Content-Length"); got != "" {
+		t.Fatalf("expected Content-Length to be dropped, got %q", got)
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("gzip.NewReader() error = %v", err)
+	}
+	if plain, _ := io.ReadAll(zr); string(plain) != body {
+		t.Fatalf("decompressed body differs from the original")
+	}
+
+	var entry map[string]any
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("failed to parse log entry %q: %v", logs.String(), err)
+	}
+	if entry["msg"] != "response compressed" || entry["level"] != "DEBUG" {
+		t.Fatalf("unexpected log entry %v", entry)
+	}
+	if entry["uncompressed_bytes"] != float64(len(body)) {
+		t.Fatalf("uncompressed_bytes = %v, want %d", entry["uncompressed_bytes"], len(body))
+	}
+	compressed, _ := entry["compressed_bytes"].(float64)
+	ratio, _ := entry["ratio"].(float64)
+	if compressed <= 0 || ratio < 10 || ratio != float64(len(body))/compressed {
+		t.Fatalf("expected a repetitive body to compress well, got compressed_bytes=%v ratio=%v", compressed, ratio)
+	}
+}
+
+func TestGzip_PassesThrough(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		acceptEncoding string
+		status         int
+	}{
+		{"no Accept-Encoding", http.MethodGet, "", http.StatusOK},
+		{"gzip refused", http.MethodGet, "gzip;q=0", http.StatusOK},
+		{"other encoding only", http.MethodGet, "br", http.StatusOK},
+		{"HEAD", http.MethodHead, "gzip", http.StatusOK},
+		{"no content", http.MethodGet, "gzip", http.StatusNoContent},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+			wrapped := Gzip(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				if tc.status != http.StatusNoContent {
+					_, _ = io.WriteString(w, "plain")
+				}
+			}))
+
+			req := httptest.NewRequest(tc.method, "/fizzbuzz", nil)
+			if tc.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tc.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+			wrapped.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Content-Encoding"); got != "" {
+				t.Fatalf("Content-Encoding = %q, want none", got)
+			}
+			if got := rec.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Fatalf("Vary = %q, want Accept-Encoding", got)
+			}
+			if logs.Len() != 0 {
+				t.Fatalf("expected no compression log, got %s", logs.String())
+			}
+		})
+	}
+}
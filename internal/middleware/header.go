@@ -0,0 +1,23 @@
+package middleware
+
+import "net/http"
+
+// RequireHeader returns middleware that rejects requests with 400 unless they
+// carry a non-empty name header, for deployments where a gateway must inject
+// one such as X-Gateway-Auth. Only presence is checked, not the value. An
+// empty name disables the check.
+func RequireHeader(name string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if name == "" {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get(name) == "" {
+				writeJSONError(w, http.StatusBadRequest, "missing required header: "+http.CanonicalHeaderKey(name))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireHeader(t *testing.T) {
+	tests := []struct {
+		name       string
+		required   string
+		header     http.Header
+		wantStatus int
+		wantError  string
+	}{
+		{name: "present", required: "X-Gateway-Auth", header: http.Header{"X-Gateway-Auth": {"token"}}, wantStatus: http.StatusOK},
+		{name: "name is case-insensitive", required: "x-gateway-auth", header: http.Header{"X-Gateway-Auth": {"token"}}, wantStatus: http.StatusOK},
+		{
+			name:       "absent",
+			required:   "X-Gateway-Auth",
+			wantStatus: http.StatusBadRequest,
+			wantError:  "missing required header: X-Gateway-Auth",
+		},
+		{
+			name:       "empty value",
+			required:   "X-Gateway-Auth",
+			header:     http.Header{"X-Gateway-Auth": {""}},
+			wantStatus: http.StatusBadRequest,
+			wantError:  "missing required header: X-Gateway-Auth",
+		},
+		{name: "unset requires nothing", wantStatus: http.StatusOK},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			called := false
+			wrapped := RequireHeader(tc.required)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/fizzbuzz", nil)
+			for name, values := range tc.header {
+				req.Header[name] = values
+			}
+			rec := httptest.NewRecorder()
+			wrapped.ServeHTTP(rec, req)
+
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("expected status %d, got %d", tc.wantStatus, rec.Code)
+			}
+			if called != (tc.wantStatus == http.StatusOK) {
+				t.Fatalf("next called = %t, want %t", called, tc.wantStatus == http.StatusOK)
+			}
+			if tc.wantError != "" {
+				var body struct {
+					Error string `json:"error"`
+				}
+				if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+					t.Fatalf("failed to decode error body: %v", err)
+				}
+				if body.Error != tc.wantError {
+					t.Fatalf("expected error %q, got %q", tc.wantError, body.Error)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package middleware
+
+import "net/http"
+
+// ResponseHeaders returns middleware that sets each of headers on every
+// response, for downstream systems that route or tag traffic by a static
+// header such as X-Env. Handlers that set the same header overwrite it. With
+// no headers the request passes through unchanged.
+func ResponseHeaders(headers map[string]string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if len(headers) == 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for name, value := range headers {
+				w.Header().Set(name, value)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
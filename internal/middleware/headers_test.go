@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResponseHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    http.Header
+	}{
+		{
+			name:    "sets every header",
+			headers: map[string]string{"X-Env": "prod", "X-Team": "platform"},
+			want:    http.Header{"X-Env": {"prod"}, "X-Team": {"platform"}, "Content-Type": {"application/json"}},
+		},
+		{
+			name:    "handler wins",
+			headers: map[string]string{"Content-Type": "text/plain"},
+			want:    http.Header{"Content-Type": {"application/json"}},
+		},
+		{name: "none", want: http.Header{"Content-Type": {"application/json"}}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			wrapped := ResponseHeaders(tc.headers)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+			}))
+
+			rec := httptest.NewRecorder()
+			wrapped.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+			for name, values := range tc.want {
+				if got := rec.Header().Get(name); got != values[0] {
+					t.Errorf("%s = %q, want %q", name, got, values[0])
+				}
+			}
+			if len(rec.Header()) != len(tc.want) {
+				t.Errorf("headers = %v, want %v", rec.Header(), tc.want)
+			}
+		})
+	}
+}
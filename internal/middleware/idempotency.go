@@ -0,0 +1,232 @@
+package middleware
+
+import (
+	"bytes"
+	"container/list"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/metrics"
+)
+
+// IdempotencyKeyHeader is the request header clients use to mark retries.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyEntries and maxIdempotencyBytes bound the replay cache.
+// Past either, the oldest entries are evicted first. A body larger than
+// maxIdempotencyBytes on its own is not cached.
+const (
+	maxIdempotencyEntries = 10_000
+	maxIdempotencyBytes   = 64 << 20
+)
+
+type cachedResponse struct {
+	key     string
+	target  string
+	status  int
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+// lookupState is the outcome of idempotencyCache.begin.
+type lookupState int
+
+const (
+	// lookupReserved means the key was unknown and is now reserved for the
+	// caller, who must call finish.
+	lookupReserved lookupState = iota
+	// lookupHit means a cached response was found.
+	lookupHit
+	// lookupInFlight means another request holds the key.
+	lookupInFlight
+)
+
+// idempotencyCache holds replayable responses by key, oldest first. Every
+// entry lives for the same ttl, so the oldest entries are also the first to
+// expire.
+type idempotencyCache struct {
+	mu         sync.Mutex
+	entries    map[string]*list.Element
+	order      *list.List
+	bytes      int
+	pending    map[string]struct{}
+	maxEntries int
+	maxBytes   int
+}
+
+func newIdempotencyCache(maxEntries, maxBytes int) *idempotencyCache {
+	return &idempotencyCache{
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		pending:    make(map[string]struct{}),
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+	}
+}
+
+// begin returns the cached response for key, reports that another request
+// holds it, or reserves it for the caller.
+func (c *idempotencyCache) begin(key string, now time.Time) (cachedResponse, lookupState) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for front := c.order.Front(); front != nil && !now.Before(front.Value.(cachedResponse).expires); front = c.order.Front() {
+		c.remove(front)
+	}
+	if elem, ok := c.entries[key]; ok {
+		return elem.Value.(cachedResponse), lookupHit
+	}
+	if _, ok := c.pending[key]; ok {
+		return cachedResponse{}, lookupInFlight
+	}
+	c.pending[key] = struct{}{}
+	return cachedResponse{}, lookupReserved
+}
+
+// finish releases the reservation on key, caching entry unless it is nil or
+// too large.
+func (c *idempotencyCache) finish(key string, entry *cachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.pending, key)
+	if entry == nil || len(entry.body) > c.maxBytes {
+		return
+	}
+	c.entries[key] = c.order.PushBack(*entry)
+	c.bytes += len(entry.body)
+	for c.order.Len() > c.maxEntries || c.bytes > c.maxBytes {
+		c.remove(c.order.Front())
+	}
+}
+
+func (c *idempotencyCache) remove(elem *list.Element) {
+	entry := c.order.Remove(elem).(cachedResponse)
+	delete(c.entries, entry.key)
+	c.bytes -= len(entry.body)
+}
+
+// CacheMetrics counts lookups in a response cache. Nil counters are no-ops,
+// so the zero value disables counting.
+type CacheMetrics struct {
+	// Hits counts responses served from the cache.
+	Hits *metrics.Counter
+	// Misses counts cacheable requests that ran the next handler.
+	Misses *metrics.Counter
+}
+
+// Idempotency returns middleware that replays the cached response for a
+// repeated Idempotency-Key within ttl instead of calling the next handler, so
+// downstream side effects such as statistics recording run only once.
+// Requests without the header pass through untouched. A key still being
+// served gets 409, and reusing a key for a different request URI is rejected
+// with 422. Server errors and streamed responses, detected by a flush, are
+// not cached, so they can be retried. Keys are scoped to the client address,
+// r.RemoteAddr, so it should run after the real-IP middleware; one client
+// cannot replay or block another's key. Replays count as hits in counts and
+// keyed requests that run the next handler as misses.
+func Idempotency(ttl time.Duration, counts CacheMetrics) func(http.Handler) http.Handler {
+	cache := newIdempotencyCache(maxIdempotencyEntries, maxIdempotencyBytes)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			client := r.RemoteAddr
+			if addr, ok := parseAddr(client); ok {
+				client = addr.String()
+			}
+			key = client + "\x00" + key
+
+			target := r.Method + " " + r.URL.RequestURI()
+			entry, state := cache.begin(key, time.Now())
+			switch state {
+			case lookupInFlight:
+				writeJSONError(w, http.StatusConflict, "a request with this idempotency key is in progress")
+				return
+			case lookupHit:
+				if entry.target != target {
+					writeJSONError(w, http.StatusUnprocessableEntity, "idempotency key reused for a different request")
+					return
+				}
+				for name, values := range entry.header {
+					w.Header()[name] = append([]string(nil), values...)
+				}
+				counts.Hits.Inc()
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(entry.status)
+				_, _ = w.Write(entry.body)
+				return
+			}
+
+			counts.Misses.Inc()
+			var stored *cachedResponse
+			// Deferred so a panicking handler still releases the key.
+			defer func() { cache.finish(key, stored) }()
+
+			rec := &captureWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			if rec.status >= http.StatusInternalServerError || rec.streamed {
+				return
+			}
+
+			// The body is captured before any outer Gzip compresses it, so
+			// its encoding is not kept; the replay is compressed afresh for
+			// clients that accept it.
+			header := w.Header().Clone()
+			header.Del("Content-Encoding")
+			stored = &cachedResponse{
+				key:     key,
+				target:  target,
+				status:  rec.status,
+				header:  header,
+				body:    bytes.Clone(rec.body.Bytes()),
+				expires: time.Now().Add(ttl),
+			}
+		})
+	}
+}
+
+// captureWriter records the status and body passing through it. Once the
+// handler flushes, the response is streamed and no longer buffered.
+type captureWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	streamed    bool
+	body        bytes.Buffer
+}
+
+// Unwrap exposes the wrapped writer, as responseWriter does.
+func (cw *captureWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+func (cw *captureWriter) WriteHeader(code int) {
+	if !cw.wroteHeader {
+		cw.status = code
+		cw.wroteHeader = true
+	}
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *captureWriter) Write(b []byte) (int, error) {
+	cw.wroteHeader = true
+	if !cw.streamed {
+		cw.body.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// FlushError marks the response as streamed and flushes the wrapped writer.
+func (cw *captureWriter) FlushError() error {
+	cw.streamed = true
+	cw.body = bytes.Buffer{}
+	return http.NewResponseController(cw.ResponseWriter).Flush()
+}
@@ -0,0 +1,285 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/metrics"
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/statistics"
+)
+
+func TestIdempotency_ReplaysCachedResponse(t *testing.T) {
+	store := statistics.NewStore()
+	calls := 0
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"call":%d}`, calls)
+	})
+
+	wrapped := Idempotency(time.Minute, CacheMetrics{})(Statistics(store)(handler))
+	target := "/fizzbuzz?int1=3&int2=5&limit=15&str1=fizz&str2=buzz"
+
+	first := makeIdempotentRequest(t, wrapped, target, "retry-1")
+	second := makeIdempotentRequest(t, wrapped, target, "retry-1")
+
+	if calls != 1 {
+		t.Fatalf("expected handler to run once, ran %d times", calls)
+	}
+	if second.Body.String() != first.Body.String() {
+		t.Fatalf("expected replayed body %q, got %q", first.Body.String(), second.Body.String())
+	}
+	if second.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("expected replayed Content-Type, got %q", second.Header().Get("Content-Type"))
+	}
+	if second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatal("expected Idempotent-Replayed header on replay")
+	}
+
+	assertRecorded(t, store, statistics.RequestParams{Int1: 3, Int2: 5, Limit: 15, Str1: "fizz", Str2: "buzz"}, 1)
+}
+
+func TestIdempotency_KeysAreScopedToTheClient(t *testing.T) {
+	calls := 0
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprintf(w, `{"call":%d}`, calls)
+	})
+
+	wrapped := Idempotency(time.Minute, CacheMetrics{})(handler)
+	request := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/fizzbuzz?limit=15", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set(IdempotencyKeyHeader, "shared")
+		rec := httptest.NewRecorder()
+		wrapped.ServeHTTP(rec, req)
+		return rec
+	}
+
+	request("198.51.100.1:1000")
+	other := request("198.51.100.2:1000")
+	if other.Header().Get("Idempotent-Replayed") != "" || other.Body.String() != `{"call":2}` {
+		t.Fatalf("expected another client's request to run, got %q", other.Body.String())
+	}
+
+	// A new connection from the same client uses another port.
+	retry := request("198.51.100.1:2000")
+	if retry.Header().Get("Idempotent-Replayed") != "true" || retry.Body.String() != `{"call":1}` {
+		t.Fatalf("expected the first client's retry to replay, got %q", retry.Body.String())
+	}
+}
+
+func TestIdempotency_WithoutKeyPassesThrough(t *testing.T) {
+	store := statistics.NewStore()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	wrapped := Idempotency(time.Minute, CacheMetrics{})(Statistics(store)(handler))
+	target := "/fizzbuzz?int1=3&int2=5&limit=15&str1=fizz&str2=buzz"
+
+	makeRequest(t, wrapped, target)
+	makeRequest(t, wrapped, target)
+
+	assertRecorded(t, store, statistics.RequestParams{Int1: 3, Int2: 5, Limit: 15, Str1: "fizz", Str2: "buzz"}, 2)
+}
+
+func TestIdempotency_KeyReusedForDifferentRequest(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	wrapped := Idempotency(time.Minute, CacheMetrics{})(handler)
+
+	makeIdempotentRequest(t, wrapped, "/fizzbuzz?limit=15", "shared")
+	rec := makeIdempotentRequest(t, wrapped, "/fizzbuzz?limit=30", "shared")
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status %d, got %d", http.StatusUnprocessableEntity, rec.Code)
+	}
+}
+
+func TestIdempotency_ServerErrorsAreNotCached(t *testing.T) {
+	calls := 0
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	wrapped := Idempotency(time.Minute, CacheMetrics{})(handler)
+
+	makeIdempotentRequest(t, wrapped, "/fizzbuzz", "retry-1")
+	makeIdempotentRequest(t, wrapped, "/fizzbuzz", "retry-1")
+
+	if calls != 2 {
+		t.Fatalf("expected handler to run twice, ran %d times", calls)
+	}
+}
+
+func TestIdempotency_EntriesExpire(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		calls := 0
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(http.StatusOK)
+		})
+
+		wrapped := Idempotency(time.Minute, CacheMetrics{})(handler)
+
+		makeIdempotentRequest(t, wrapped, "/fizzbuzz", "retry-1")
+		time.Sleep(2 * time.Minute)
+		makeIdempotentRequest(t, wrapped, "/fizzbuzz", "retry-1")
+
+		if calls != 2 {
+			t.Fatalf("expected handler to run again after expiry, ran %d times", calls)
+		}
+	})
+}
+
+func TestIdempotency_CountsCacheHitsAndMisses(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	registry := metrics.NewRegistry()
+	counts := CacheMetrics{Hits: registry.Counter("hits", ""), Misses: registry.Counter("misses", "")}
+	wrapped := Idempotency(time.Minute, counts)(handler)
+	target := "/fizzbuzz?int1=3&int2=5&limit=15&str1=fizz&str2=buzz"
+
+	makeRequest(t, wrapped, target)
+	if counts.Hits.Value() != 0 || counts.Misses.Value() != 0 {
+		t.Fatalf("expected requests without a key to be uncounted, got %d hits and %d misses", counts.Hits.Value(), counts.Misses.Value())
+	}
+
+	makeIdempotentRequest(t, wrapped, target, "retry-1")
+	makeIdempotentRequest(t, wrapped, target, "retry-1")
+
+	if counts.Hits.Value() != 1 || counts.Misses.Value() != 1 {
+		t.Fatalf("expected 1 hit and 1 miss, got %d hits and %d misses", counts.Hits.Value(), counts.Misses.Value())
+	}
+}
+
+func TestIdempotency_ConcurrentDuplicateGetsConflict(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		store := statistics.NewStore()
+		release := make(chan struct{})
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+			w.WriteHeader(http.StatusOK)
+		})
+
+		wrapped := Idempotency(time.Minute, CacheMetrics{})(Statistics(store)(handler))
+		target := "/fizzbuzz?int1=3&int2=5&limit=15&str1=fizz&str2=buzz"
+
+		go makeIdempotentRequest(t, wrapped, target, "retry-1")
+		synctest.Wait()
+
+		if rec := makeIdempotentRequest(t, wrapped, target, "retry-1"); rec.Code != http.StatusConflict {
+			t.Fatalf("expected status %d while the first request runs, got %d", http.StatusConflict, rec.Code)
+		}
+		close(release)
+		synctest.Wait()
+
+		if rec := makeIdempotentRequest(t, wrapped, target, "retry-1"); rec.Header().Get("Idempotent-Replayed") != "true" {
+			t.Fatal("expected a replay once the first request finished")
+		}
+		assertRecorded(t, store, statistics.RequestParams{Int1: 3, Int2: 5, Limit: 15, Str1: "fizz", Str2: "buzz"}, 1)
+	})
+}
+
+func TestIdempotency_PanicReleasesKey(t *testing.T) {
+	calls := 0
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			panic("boom")
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	wrapped := Idempotency(time.Minute, CacheMetrics{})(handler)
+
+	func() {
+		defer func() { _ = recover() }()
+		makeIdempotentRequest(t, wrapped, "/fizzbuzz", "retry-1")
+	}()
+	if rec := makeIdempotentRequest(t, wrapped, "/fizzbuzz", "retry-1"); rec.Code != http.StatusOK {
+		t.Fatalf("expected the retry to run, got status %d", rec.Code)
+	}
+	if calls != 2 {
+		t.Fatalf("expected handler to run twice, ran %d times", calls)
+	}
+}
+
+func TestIdempotency_StreamedResponsesAreNotCached(t *testing.T) {
+	calls := 0
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_, _ = w.Write([]byte(`{"result":[`))
+		_ = http.NewResponseController(w).Flush()
+		_, _ = w.Write([]byte(`"1"]}`))
+	})
+
+	wrapped := Idempotency(time.Minute, CacheMetrics{})(handler)
+
+	first := makeIdempotentRequest(t, wrapped, "/fizzbuzz?stream=true", "retry-1")
+	makeIdempotentRequest(t, wrapped, "/fizzbuzz?stream=true", "retry-1")
+
+	if calls != 2 {
+		t.Fatalf("expected handler to run twice, ran %d times", calls)
+	}
+	if !first.Flushed || first.Body.String() != `{"result":["1"]}` {
+		t.Fatalf("expected the flushed stream to pass through, got %q", first.Body.String())
+	}
+}
+
+func TestIdempotencyCache_Bounds(t *testing.T) {
+	now := time.Now()
+	store := func(c *idempotencyCache, key string, size int) {
+		if _, state := c.begin(key, now); state != lookupReserved {
+			t.Fatalf("%s: expected a reservation, got state %d", key, state)
+		}
+		c.finish(key, &cachedResponse{key: key, body: make([]byte, size), expires: now.Add(time.Minute)})
+	}
+	cached := func(c *idempotencyCache, key string) bool {
+		_, state := c.begin(key, now)
+		if state == lookupReserved {
+			c.finish(key, nil)
+		}
+		return state == lookupHit
+	}
+
+	byCount := newIdempotencyCache(2, 100)
+	store(byCount, "a", 1)
+	store(byCount, "b", 1)
+	store(byCount, "c", 1)
+	if cached(byCount, "a") || !cached(byCount, "b") || !cached(byCount, "c") {
+		t.Fatal("expected the oldest entry to be evicted past the entry cap")
+	}
+
+	byBytes := newIdempotencyCache(10, 100)
+	store(byBytes, "a", 60)
+	store(byBytes, "b", 60)
+	store(byBytes, "huge", 101)
+	if cached(byBytes, "a") || !cached(byBytes, "b") || cached(byBytes, "huge") {
+		t.Fatal("expected eviction past the byte cap and oversized bodies to be skipped")
+	}
+	if byBytes.bytes != 60 {
+		t.Fatalf("expected 60 cached bytes, got %d", byBytes.bytes)
+	}
+}
+
+func makeIdempotentRequest(t *testing.T, handler http.Handler, target, key string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.Header.Set(IdempotencyKeyHeader, key)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	return rec
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import "net/http"
+
+// LimitInFlight returns middleware that serves at most maxInFlight requests
+// at once. Requests beyond the cap are not queued: they receive 503 with
+// Retry-After straight away, so an overloaded server sheds load instead of
+// piling up goroutines. Long-lived requests such as the statistics feed
+// hold their slot until they end. maxInFlight <= 0 disables the cap.
+func LimitInFlight(maxInFlight int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if maxInFlight <= 0 {
+			return next
+		}
+
+		slots := make(chan struct{}, maxInFlight)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case slots <- struct{}{}:
+			default:
+				w.Header().Set("Retry-After", "1")
+				writeJSONError(w, http.StatusServiceUnavailable, "too many concurrent requests")
+				return
+			}
+			defer func() { <-slots }()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
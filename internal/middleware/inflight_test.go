@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"testing/synctest"
+)
+
+func TestLimitInFlight_RejectsBeyondCap(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		release := make(chan struct{})
+		wrapped := LimitInFlight(2)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		var wg sync.WaitGroup
+		for range 2 {
+			wg.Go(func() {
+				if rec := makeRequest(t, wrapped, "/fizzbuzz"); rec.Code != http.StatusOK {
+					t.Errorf("held request: expected status %d, got %d", http.StatusOK, rec.Code)
+				}
+			})
+		}
+		synctest.Wait()
+
+		rec := makeRequest(t, wrapped, "/fizzbuzz")
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Fatalf("request over the cap: expected status %d, got %d", http.StatusServiceUnavailable, rec.Code)
+		}
+		if got := rec.Header().Get("Retry-After"); got != "1" {
+			t.Errorf("expected Retry-After 1, got %q", got)
+		}
+		if got, want := rec.Body.String(), `{"error":"too many concurrent requests"}`; got != want {
+			t.Errorf("expected body %s, got %s", want, got)
+		}
+
+		close(release)
+		wg.Wait()
+
+		if rec := makeRequest(t, wrapped, "/fizzbuzz"); rec.Code != http.StatusOK {
+			t.Fatalf("after release: expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+	})
+}
+
+func TestLimitInFlight_ZeroIsUnbounded(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	if rec := makeRequest(t, LimitInFlight(0)(next), "/fizzbuzz"); rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+}
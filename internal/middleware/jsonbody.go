@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// fizzBuzzBody lists the fields accepted in a POST /fizzbuzz body. Each is
+// copied verbatim to the query parameter of the same name, so validation
+// stays in one place: the handler. rule may be a string or, for repeated
+// rule=divisor:word pairs, an array of strings.
+type fizzBuzzBody struct {
+	Int1      json.Number     `json:"int1"`
+	Int2      json.Number     `json:"int2"`
+	Limit     json.Number     `json:"limit"`
+	Start     json.Number     `json:"start"`
+	Seed      json.Number     `json:"seed"`
+	Base      json.Number     `json:"base"`
+	Str1      *string         `json:"str1"`
+	Str2      *string         `json:"str2"`
+	Str3      *string         `json:"str3"`
+	Only      *string         `json:"only"`
+	Rule      json.RawMessage `json:"rule"`
+	Preset    *string         `json:"preset"`
+	Templated *bool           `json:"templated"`
+	Collapse  *bool           `json:"collapse_equal"`
+	Numeric   *bool           `json:"numeric"`
+	Shuffle   *bool           `json:"shuffle"`
+	Stream    *bool           `json:"stream"`
+	Preview   *bool           `json:"preview"`
+}
+
+// JSONBodyToQuery returns middleware that decodes a JSON object body of at
+// most maxBytes and moves its fields into the query string, letting POST
+// /fizzbuzz share the GET pipeline. Unknown fields, malformed JSON and
+// trailing data are rejected with 400, oversized bodies with 413. Body
+// fields override query parameters of the same name.
+func JSONBodyToQuery(maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body fizzBuzzBody
+			if status, message := decodeBody(w, r, maxBytes, &body); status != 0 {
+				writeJSONError(w, status, message)
+				return
+			}
+
+			rules, ok := ruleValues(body.Rule)
+			if !ok {
+				writeJSONError(w, http.StatusBadRequest, "rule has the wrong JSON type")
+				return
+			}
+
+			query := r.URL.Query()
+			for name, value := range map[string]json.Number{
+				"int1": body.Int1, "int2": body.Int2, "limit": body.Limit,
+				"start": body.Start, "seed": body.Seed, "base": body.Base,
+			} {
+				if value != "" {
+					query.Set(name, value.String())
+				}
+			}
+			for name, value := range map[string]*string{"str1": body.Str1, "str2": body.Str2, "str3": body.Str3, "only": body.Only, "preset": body.Preset} {
+				if value != nil {
+					query.Set(name, *value)
+				}
+			}
+			if rules != nil {
+				query.Del("rule")
+				for _, rule := range rules {
+					query.Add("rule", rule)
+				}
+			}
+			for name, value := range map[string]*bool{
+				"templated": body.Templated, "collapse_equal": body.Collapse, "numeric": body.Numeric,
+				"shuffle": body.Shuffle, "stream": body.Stream, "preview": body.Preview,
+			} {
+				if value != nil {
+					query.Set(name, fmt.Sprint(*value))
+				}
+			}
+
+			r2 := r.Clone(r.Context())
+			r2.URL.RawQuery = query.Encode()
+			r2.RequestURI = r2.URL.RequestURI()
+			next.ServeHTTP(w, r2)
+		})
+	}
+}
+
+// ruleValues reads the rule field as one string or an array of strings. It
+// returns nil when the field is absent or null, and false for any other JSON
+// type.
+func ruleValues(raw json.RawMessage) ([]string, bool) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, true
+	}
+	var one string
+	if json.Unmarshal(raw, &one) == nil {
+		return []string{one}, true
+	}
+	var many []string
+	if json.Unmarshal(raw, &many) == nil {
+		return many, true
+	}
+	return nil, false
+}
+
+// decodeBody decodes exactly one JSON object into dst, returning a non-zero
+// status and message when the body is rejected.
+func decodeBody(w http.ResponseWriter, r *http.Request, maxBytes int64, dst any) (int, string) {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBytes))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(dst); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &maxBytesErr):
+			return http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", maxBytesErr.Limit)
+		case errors.Is(err, io.EOF):
+			return http.StatusBadRequest, "request body is required"
+		case errors.As(err, &typeErr) && typeErr.Field == "":
+			return http.StatusBadRequest, "request body must be a JSON object"
+		case errors.As(err, &typeErr):
+			return http.StatusBadRequest, fmt.Sprintf("%s has the wrong JSON type", typeErr.Field)
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			return http.StatusBadRequest, strings.TrimPrefix(err.Error(), "json: ")
+		default:
+			return http.StatusBadRequest, "request body is not valid JSON"
+		}
+	}
+
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", maxBytesErr.Limit)
+		}
+		return http.StatusBadRequest, "request body must contain a single JSON object"
+	}
+	return 0, ""
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	payload, _ := json.Marshal(map[string]string{"error": message})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(payload)
+}
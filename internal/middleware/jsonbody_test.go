@@ -0,0 +1,159 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONBodyToQuery(t *testing.T) {
+	tests := []struct {
+		name          string
+		target        string
+		body          string
+		wantQuery     url.Values
+		wantStatus    int
+		wantError     string
+		wantNextCalls int
+	}{
+		{
+			name:   "valid body",
+			target: "/fizzbuzz",
+			body:   `{"int1": 3, "int2": 5, "limit": 15, "str1": "fizz", "str2": "buzz", "numeric": true}`,
+			wantQuery: url.Values{
+				"int1": {"3"}, "int2": {"5"}, "limit": {"15"}, "str1": {"fizz"}, "str2": {"buzz"}, "numeric": {"true"},
+			},
+			wantStatus:    http.StatusOK,
+			wantNextCalls: 1,
+		},
+		{
+			name:          "preset",
+			target:        "/fizzbuzz",
+			body:          `{"preset": "classic", "limit": 15}`,
+			wantQuery:     url.Values{"preset": {"classic"}, "limit": {"15"}},
+			wantStatus:    http.StatusOK,
+			wantNextCalls: 1,
+		},
+		{
+			name:          "body overrides query",
+			target:        "/fizzbuzz?limit=100&preview=true",
+			body:          `{"limit": 15}`,
+			wantQuery:     url.Values{"limit": {"15"}, "preview": {"true"}},
+			wantStatus:    http.StatusOK,
+			wantNextCalls: 1,
+		},
+		{
+			name:          "rule pairs",
+			target:        "/fizzbuzz?rule=2:bazz",
+			body:          `{"rule": ["3:fizz", "5:buzz"], "limit": 15}`,
+			wantQuery:     url.Values{"rule": {"3:fizz", "5:buzz"}, "limit": {"15"}},
+			wantStatus:    http.StatusOK,
+			wantNextCalls: 1,
+		},
+		{
+			name:          "single rule",
+			target:        "/fizzbuzz",
+			body:          `{"int1": 3, "rule": "divisible"}`,
+			wantQuery:     url.Values{"int1": {"3"}, "rule": {"divisible"}},
+			wantStatus:    http.StatusOK,
+			wantNextCalls: 1,
+		},
+		{
+			name:       "rule of the wrong type",
+			target:     "/fizzbuzz",
+			body:       `{"rule": [3]}`,
+			wantStatus: http.StatusBadRequest,
+			wantError:  "rule has the wrong JSON type",
+		},
+		{
+			name:       "unknown field",
+			target:     "/fizzbuzz",
+			body:       `{"int1": 3, "int3": 7}`,
+			wantStatus: http.StatusBadRequest,
+			wantError:  `unknown field "int3"`,
+		},
+		{
+			name:       "oversize body",
+			target:     "/fizzbuzz",
+			body:       `{"str1": "` + strings.Repeat("x", 200) + `"}`,
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantError:  "request body exceeds 128 bytes",
+		},
+		{
+			name:       "nested value",
+			target:     "/fizzbuzz",
+			body:       `{"str1": {"a": {"b": {}}}}`,
+			wantStatus: http.StatusBadRequest,
+			wantError:  "str1 has the wrong JSON type",
+		},
+		{
+			name:       "not an object",
+			target:     "/fizzbuzz",
+			body:       `[1, 2]`,
+			wantStatus: http.StatusBadRequest,
+			wantError:  "request body must be a JSON object",
+		},
+		{
+			name:       "malformed",
+			target:     "/fizzbuzz",
+			body:       `{"int1": 3`,
+			wantStatus: http.StatusBadRequest,
+			wantError:  "request body is not valid JSON",
+		},
+		{
+			name:       "trailing data",
+			target:     "/fizzbuzz",
+			body:       `{"int1": 3} {"int1": 4}`,
+			wantStatus: http.StatusBadRequest,
+			wantError:  "request body must contain a single JSON object",
+		},
+		{
+			name:       "empty body",
+			target:     "/fizzbuzz",
+			body:       ``,
+			wantStatus: http.StatusBadRequest,
+			wantError:  "request body is required",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var got url.Values
+			calls := 0
+			wrapped := JSONBodyToQuery(128)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				got = r.URL.Query()
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, tc.target, strings.NewReader(tc.body))
+			rec := httptest.NewRecorder()
+			wrapped.ServeHTTP(rec, req)
+
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("expected status %d, got %d", tc.wantStatus, rec.Code)
+			}
+			if calls != tc.wantNextCalls {
+				t.Fatalf("expected next handler to be called %d times, got %d", tc.wantNextCalls, calls)
+			}
+			if tc.wantError != "" {
+				var body struct {
+					Error string `json:"error"`
+				}
+				if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+					t.Fatalf("failed to decode error body: %v", err)
+				}
+				if body.Error != tc.wantError {
+					t.Fatalf("expected error %q, got %q", tc.wantError, body.Error)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tc.wantQuery) {
+				t.Fatalf("expected query %v, got %v", tc.wantQuery, got)
+			}
+		})
+	}
+}
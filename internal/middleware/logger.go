@@ -3,23 +3,34 @@ package middleware
 import (
 	"log/slog"
 	"net/http"
+	"runtime/debug"
 	"time"
 
 	chimw "github.com/go-chi/chi/v5/middleware"
+
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/metrics"
 )
 
 // RequestLogger provides structured logging for incoming HTTP requests.
 // It captures status code, duration, bytes written, and selected request metadata.
-func RequestLogger(logger *slog.Logger) func(http.Handler) http.Handler {
+// The same duration, in seconds, is observed in durations by route and status;
+// a nil histogram disables it.
+// A panic is logged at ERROR with its value and stack trace, then re-raised
+// for the recovery middleware.
+func RequestLogger(logger *slog.Logger, durations *metrics.HistogramVec) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
 			wrapped := &responseWriter{ResponseWriter: w, status: http.StatusOK}
-			var panicValue any
+			var (
+				panicValue any
+				stack      []byte
+			)
 
 			defer func() {
+				duration := time.Since(start)
+				observeDuration(durations, r, wrapped.status, duration)
 				if logger != nil {
-					duration := time.Since(start)
 					level := levelFromStatus(wrapped.status)
 					id := chimw.GetReqID(r.Context())
 					attrs := []slog.Attr{
@@ -36,7 +47,7 @@ func RequestLogger(logger *slog.Logger) func(http.Handler) http.Handler {
 					}
 					if panicValue != nil {
 						level = slog.LevelError
-						attrs = append(attrs, slog.Any("panic", panicValue))
+						attrs = append(attrs, slog.Any("panic", panicValue), slog.String("stack", string(stack)))
 					}
 					logger.LogAttrs(r.Context(), level, "http request", attrs...)
 				}
@@ -52,6 +63,9 @@ func RequestLogger(logger *slog.Logger) func(http.Handler) http.Handler {
 							wrapped.status = http.StatusInternalServerError
 						}
 						panicValue = rec
+						// Taken here, while the panicking frames are still
+						// on the stack.
+						stack = debug.Stack()
 					}
 				}()
 				next.ServeHTTP(wrapped, r)
@@ -78,6 +92,12 @@ type responseWriter struct {
 	wroteHeader bool
 }
 
+// Unwrap lets http.ResponseController reach the underlying writer, so
+// streaming handlers can flush and extend write deadlines through it.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 func (w *responseWriter) WriteHeader(code int) {
 	w.status = code
 	w.wroteHeader = true
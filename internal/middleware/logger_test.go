@@ -6,13 +6,19 @@ import (
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"testing/synctest"
 	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/metrics"
 )
 
 func TestRequestLogger_LogsRequest(t *testing.T) {
 	logger, buf := createTestLogger(t)
-	mw := RequestLogger(logger)
+	mw := RequestLogger(logger, nil)
 
 	wrapped := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("X-Test", "true")
@@ -55,7 +61,7 @@ func TestRequestLogger_LogLevelByStatus(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			logger, buf := createTestLogger(t)
-			mw := RequestLogger(logger)
+			mw := RequestLogger(logger, nil)
 			h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				w.WriteHeader(tt.status)
 			}))
@@ -106,7 +112,7 @@ func TestRequestLogger_CapturesResponseSize(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			logger, buf := createTestLogger(t)
-			mw := RequestLogger(logger)
+			mw := RequestLogger(logger, nil)
 			h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				if err := tt.writeFunc(w); err != nil {
 					t.Fatalf("writeFunc error = %v", err)
@@ -126,7 +132,7 @@ func TestRequestLogger_CapturesResponseSize(t *testing.T) {
 
 func TestRequestLogger_MeasuresDuration(t *testing.T) {
 	logger, buf := createTestLogger(t)
-	mw := RequestLogger(logger)
+	mw := RequestLogger(logger, nil)
 
 	h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		time.Sleep(50 * time.Millisecond)
@@ -147,9 +153,38 @@ func TestRequestLogger_MeasuresDuration(t *testing.T) {
 	}
 }
 
+func TestRequestLogger_ObservesDurationBuckets(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		registry := metrics.NewRegistry()
+		durations := registry.HistogramVec("http_request_duration_seconds", "Request durations.", []float64{0.01, 0.1, 1}, "route", "status")
+
+		router := chi.NewRouter()
+		router.Use(RequestLogger(nil, durations))
+		router.Get("/slow", func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(50 * time.Millisecond)
+		})
+
+		makeRequest(t, router, "/slow")
+
+		var b strings.Builder
+		if err := registry.WriteText(&b); err != nil {
+			t.Fatalf("WriteText() error = %v", err)
+		}
+		for _, want := range []string{
+			`http_request_duration_seconds_bucket{route="/slow",status="200",le="0.01"} 0`,
+			`http_request_duration_seconds_bucket{route="/slow",status="200",le="0.1"} 1`,
+			`http_request_duration_seconds_count{route="/slow",status="200"} 1`,
+		} {
+			if !strings.Contains(b.String(), want) {
+				t.Errorf("missing %q in:\n%s", want, b.String())
+			}
+		}
+	})
+}
+
 func TestRequestLogger_HandlerPanics(t *testing.T) {
 	logger, buf := createTestLogger(t)
-	mw := RequestLogger(logger)
+	mw := RequestLogger(logger, nil)
 
 	h := mw(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
 		panic("test panic")
@@ -170,6 +205,13 @@ func TestRequestLogger_HandlerPanics(t *testing.T) {
 		if _, ok := entry["panic"]; !ok {
 			t.Fatalf("expected panic field in log entry")
 		}
+		stack, _ := entry["stack"].(string)
+		if stack == "" {
+			t.Fatalf("expected non-empty stack field in log entry")
+		}
+		if !strings.Contains(stack, "TestRequestLogger_HandlerPanics") {
+			t.Fatalf("expected stack to include the panicking handler, got %q", stack)
+		}
 	}()
 
 	h.ServeHTTP(rec, req)
@@ -189,7 +231,7 @@ func TestRequestLogger_DifferentMethods(t *testing.T) {
 	for _, method := range methods {
 		t.Run(method, func(t *testing.T) {
 			logger, buf := createTestLogger(t)
-			mw := RequestLogger(logger)
+			mw := RequestLogger(logger, nil)
 			h := mw(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
 
 			req := httptest.NewRequest(method, "/method", nil)
@@ -205,7 +247,7 @@ func TestRequestLogger_DifferentMethods(t *testing.T) {
 
 func TestRequestLogger_PreservesResponseWriter(t *testing.T) {
 	logger, buf := createTestLogger(t)
-	mw := RequestLogger(logger)
+	mw := RequestLogger(logger, nil)
 
 	h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("X-Custom", "value")
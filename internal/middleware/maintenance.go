@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+)
+
+// Maintenance puts the API into maintenance mode: while enabled, every
+// request except those to exempt paths receives 503 with a JSON message.
+type Maintenance struct {
+	enabled atomic.Bool
+	exempt  map[string]struct{}
+}
+
+// NewMaintenance returns a Maintenance starting in the given state. Requests
+// to exemptPaths are always served.
+func NewMaintenance(enabled bool, exemptPaths ...string) *Maintenance {
+	m := &Maintenance{exempt: make(map[string]struct{}, len(exemptPaths))}
+	for _, path := range exemptPaths {
+		m.exempt[path] = struct{}{}
+	}
+	m.enabled.Store(enabled)
+	return m
+}
+
+// Enabled reports whether maintenance mode is on.
+func (m *Maintenance) Enabled() bool {
+	return m.enabled.Load()
+}
+
+// Handler rejects non-exempt requests with 503 while maintenance mode is on.
+func (m *Maintenance) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := m.exempt[r.URL.Path]; ok || !m.enabled.Load() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`{"error":"service under maintenance"}`))
+	})
+}
+
+// Toggle flips maintenance mode and reports the new state as
+// {"maintenance": bool}.
+func (m *Maintenance) Toggle(w http.ResponseWriter, r *http.Request) {
+	var enabled bool
+	for {
+		current := m.enabled.Load()
+		if m.enabled.CompareAndSwap(current, !current) {
+			enabled = !current
+			break
+		}
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(`{"maintenance":` + strconv.FormatBool(enabled) + `}`))
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestMaintenance_Handler(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name       string
+		enabled    bool
+		target     string
+		wantStatus int
+		wantBody   string
+	}{
+		{"disabled passes through", false, "/fizzbuzz", http.StatusOK, ""},
+		{"enabled rejects", true, "/fizzbuzz", http.StatusServiceUnavailable, `{"error":"service under maintenance"}`},
+		{"enabled serves exempt path", true, "/health", http.StatusOK, ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			m := NewMaintenance(tc.enabled, "/health")
+			rec := makeRequest(t, m.Handler(next), tc.target)
+
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("expected status %d, got %d", tc.wantStatus, rec.Code)
+			}
+			if got := rec.Body.String(); got != tc.wantBody {
+				t.Fatalf("expected body %q, got %q", tc.wantBody, got)
+			}
+		})
+	}
+}
+
+func TestMaintenance_Toggle(t *testing.T) {
+	m := NewMaintenance(false)
+
+	for _, want := range []string{`{"maintenance":true}`, `{"maintenance":false}`} {
+		rec := makeRequest(t, http.HandlerFunc(m.Toggle), "/admin/maintenance")
+		if got := rec.Body.String(); got != want {
+			t.Fatalf("expected body %s, got %s", want, got)
+		}
+	}
+	if m.Enabled() {
+		t.Fatal("expected maintenance mode to be off after two toggles")
+	}
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Presets returns middleware that expands the preset query parameter into
+// the parameters of the named preset. Parameters the request sets itself
+// win over the preset's, and the preset parameter is removed so downstream
+// handlers see only the effective values. An unknown preset is rejected
+// with 400. Requests without a preset pass through unchanged.
+func Presets(presets map[string]map[string]string) func(http.Handler) http.Handler {
+	names := slices.Sorted(maps.Keys(presets))
+	unknown := "preset must be one of: " + strings.Join(names, ", ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			query := r.URL.Query()
+			if _, ok := query["preset"]; !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			preset, ok := presets[query.Get("preset")]
+			if !ok {
+				writeJSONError(w, http.StatusBadRequest, unknown)
+				return
+			}
+			query.Del("preset")
+			for name, value := range preset {
+				if _, ok := query[name]; !ok {
+					query.Set(name, value)
+				}
+			}
+
+			r2 := r.Clone(r.Context())
+			r2.URL.RawQuery = query.Encode()
+			r2.RequestURI = r2.URL.RequestURI()
+			next.ServeHTTP(w, r2)
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestPresets(t *testing.T) {
+	presets := map[string]map[string]string{
+		"classic": {"int1": "3", "int2": "5", "str1": "fizz", "str2": "buzz"},
+		"small":   {"int1": "2", "int2": "7", "limit": "10", "str1": "foo", "str2": "bar"},
+	}
+
+	tests := []struct {
+		name       string
+		target     string
+		want       url.Values
+		wantStatus int
+		wantError  string
+	}{
+		{
+			name:       "expands the preset",
+			target:     "/fizzbuzz?preset=classic&limit=15",
+			want:       url.Values{"int1": {"3"}, "int2": {"5"}, "limit": {"15"}, "str1": {"fizz"}, "str2": {"buzz"}},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "client values win",
+			target:     "/fizzbuzz?preset=small&str2=baz&limit=3",
+			want:       url.Values{"int1": {"2"}, "int2": {"7"}, "limit": {"3"}, "str1": {"foo"}, "str2": {"baz"}},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "no preset",
+			target:     "/fizzbuzz?int1=3",
+			want:       url.Values{"int1": {"3"}},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "unknown preset",
+			target:     "/fizzbuzz?preset=jazz&limit=15",
+			wantStatus: http.StatusBadRequest,
+			wantError:  "preset must be one of: classic, small",
+		},
+		{
+			name:       "empty preset",
+			target:     "/fizzbuzz?preset=",
+			wantStatus: http.StatusBadRequest,
+			wantError:  "preset must be one of: classic, small",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var got url.Values
+			wrapped := Presets(presets)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.URL.Query()
+			}))
+
+			rec := makeRequest(t, wrapped, tc.target)
+
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("expected status %d, got %d", tc.wantStatus, rec.Code)
+			}
+			if tc.wantError != "" {
+				var body struct {
+					Error string `json:"error"`
+				}
+				if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+					t.Fatalf("failed to decode error body: %v", err)
+				}
+				if body.Error != tc.wantError {
+					t.Fatalf("expected error %q, got %q", tc.wantError, body.Error)
+				}
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("query = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
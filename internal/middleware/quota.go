@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// quotaParams are the query parameters that make up a statistics entry, and
+// so the identity of a distinct parameter set.
+var quotaParams = []string{"int1", "int2", "limit", "str1", "str2"}
+
+type distinctQuota struct {
+	mu      sync.Mutex
+	seen    map[string]map[string]struct{}
+	resetAt time.Time
+}
+
+// allow records key for client and reports whether it is within maxDistinct.
+// When it is not, it also returns how long until the counts reset.
+func (q *distinctQuota) allow(client, key string, maxDistinct int, window time.Duration, now time.Time) (bool, time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if !now.Before(q.resetAt) {
+		q.seen = make(map[string]map[string]struct{})
+		q.resetAt = now.Add(window)
+	}
+
+	keys := q.seen[client]
+	if _, ok := keys[key]; ok {
+		return true, 0
+	}
+	if len(keys) >= maxDistinct {
+		return false, q.resetAt.Sub(now)
+	}
+	if keys == nil {
+		keys = make(map[string]struct{})
+		q.seen[client] = keys
+	}
+	keys[key] = struct{}{}
+	return true, 0
+}
+
+// DistinctParamsPerIP returns middleware that lets each client IP use at most
+// maxDistinct distinct parameter sets per window, so a single client cannot
+// flood statistics with unique entries. Further new sets get 429 with
+// Retry-After until the counts reset at the end of the window; sets already
+// used stay allowed. The client is r.RemoteAddr, so it should run after the
+// real-IP middleware. Previews are not counted. maxDistinct <= 0 disables
+// the quota.
+func DistinctParamsPerIP(maxDistinct int, window time.Duration) func(http.Handler) http.Handler {
+	quota := &distinctQuota{}
+
+	return func(next http.Handler) http.Handler {
+		if maxDistinct <= 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			values := r.URL.Query()
+			if isPreview(values) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			client := r.RemoteAddr
+			if addr, ok := parseAddr(client); ok {
+				client = addr.String()
+			}
+			parts := make([]string, len(quotaParams))
+			for i, name := range quotaParams {
+				parts[i] = values.Get(name)
+			}
+
+			ok, wait := quota.allow(client, strings.Join(parts, "\x00"), maxDistinct, window, time.Now())
+			if !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				writeJSONError(w, http.StatusTooManyRequests, "too many distinct parameter sets from this client, retry later")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
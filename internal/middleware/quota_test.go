@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func quotaRequest(handler http.Handler, remoteAddr string, limit int) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/fizzbuzz?int1=3&int2=5&limit=%d&str1=fizz&str2=buzz", limit), nil)
+	req.RemoteAddr = remoteAddr
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestDistinctParamsPerIP(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		handler := DistinctParamsPerIP(3, time.Hour)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		for limit := 1; limit <= 3; limit++ {
+			if rec := quotaRequest(handler, "192.0.2.1:1234", limit); rec.Code != http.StatusOK {
+				t.Fatalf("distinct set %d: expected status %d, got %d", limit, http.StatusOK, rec.Code)
+			}
+		}
+
+		rec := quotaRequest(handler, "192.0.2.1:5678", 4)
+		if rec.Code != http.StatusTooManyRequests {
+			t.Fatalf("set over quota: expected status %d, got %d", http.StatusTooManyRequests, rec.Code)
+		}
+		if got := rec.Header().Get("Retry-After"); got != "3600" {
+			t.Fatalf("Retry-After = %q, want %q", got, "3600")
+		}
+
+		if rec := quotaRequest(handler, "192.0.2.1:1234", 2); rec.Code != http.StatusOK {
+			t.Fatalf("repeated set: expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+		if rec := quotaRequest(handler, "192.0.2.2:1234", 4); rec.Code != http.StatusOK {
+			t.Fatalf("other client: expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+
+		time.Sleep(time.Hour)
+		if rec := quotaRequest(handler, "192.0.2.1:1234", 4); rec.Code != http.StatusOK {
+			t.Fatalf("after reset: expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+	})
+}
+
+func TestDistinctParamsPerIP_UsesTrustedClientAddress(t *testing.T) {
+	handler := TrustedRealIP([]netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")})(DistinctParamsPerIP(1, time.Hour)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	serve := func(client string, limit int) int {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/fizzbuzz?int1=3&int2=5&limit=%d&str1=fizz&str2=buzz", limit), nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		req.Header.Set("X-Forwarded-For", client)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := serve("198.51.100.1", 1); code != http.StatusOK {
+		t.Fatalf("first client: expected status %d, got %d", http.StatusOK, code)
+	}
+	if code := serve("198.51.100.2", 2); code != http.StatusOK {
+		t.Fatalf("second client behind the same proxy: expected status %d, got %d", http.StatusOK, code)
+	}
+	if code := serve("198.51.100.1", 2); code != http.StatusTooManyRequests {
+		t.Fatalf("first client over quota: expected status %d, got %d", http.StatusTooManyRequests, code)
+	}
+}
+
+func TestDistinctParamsPerIP_Disabled(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	handler := DistinctParamsPerIP(0, time.Hour)(next)
+	for limit := 1; limit <= 5; limit++ {
+		if rec := quotaRequest(handler, "192.0.2.1:1234", limit); rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+	}
+}
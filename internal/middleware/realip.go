@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// TrustedRealIP returns middleware that rewrites r.RemoteAddr to the client
+// address carried in X-Forwarded-For or X-Real-IP, but only when the
+// immediate peer belongs to one of the trusted proxy prefixes. Requests from
+// any other peer keep their RemoteAddr, so clients cannot spoof their address
+// by sending forwarding headers directly.
+//
+// X-Forwarded-For is walked from right to left, skipping trusted hops, and the
+// first untrusted address is used as the client.
+func TrustedRealIP(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			peer, ok := parseAddr(r.RemoteAddr)
+			if ok && isTrusted(peer, trusted) {
+				if client, found := forwardedClient(r, trusted); found {
+					r.RemoteAddr = client.String()
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func forwardedClient(r *http.Request, trusted []netip.Prefix) (netip.Addr, bool) {
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		var last netip.Addr
+		for i := len(hops) - 1; i >= 0; i-- {
+			addr, ok := parseAddr(strings.TrimSpace(hops[i]))
+			if !ok {
+				break
+			}
+			last = addr
+			if !isTrusted(addr, trusted) {
+				return addr, true
+			}
+		}
+		if last.IsValid() {
+			return last, true
+		}
+	}
+
+	if addr, ok := parseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ok {
+		return addr, true
+	}
+
+	return netip.Addr{}, false
+}
+
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseAddr(value string) (netip.Addr, bool) {
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestTrustedRealIP(t *testing.T) {
+	trusted := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("192.168.1.1/32"),
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{
+			name:       "trusted proxy forwards client",
+			remoteAddr: "10.0.0.1:41000",
+			headers:    map[string]string{"X-Forwarded-For": "203.0.113.5"},
+			want:       "203.0.113.5",
+		},
+		{
+			name:       "trusted proxy chain skips internal hops",
+			remoteAddr: "10.0.0.1:41000",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1, 203.0.113.5, 10.0.0.2"},
+			want:       "203.0.113.5",
+		},
+		{
+			name:       "trusted proxy with X-Real-IP",
+			remoteAddr: "192.168.1.1:41000",
+			headers:    map[string]string{"X-Real-IP": "203.0.113.9"},
+			want:       "203.0.113.9",
+		},
+		{
+			name:       "untrusted peer spoofing X-Forwarded-For",
+			remoteAddr: "198.51.100.7:5555",
+			headers:    map[string]string{"X-Forwarded-For": "1.2.3.4"},
+			want:       "198.51.100.7:5555",
+		},
+		{
+			name:       "untrusted peer spoofing X-Real-IP",
+			remoteAddr: "198.51.100.7:5555",
+			headers:    map[string]string{"X-Real-IP": "1.2.3.4"},
+			want:       "198.51.100.7:5555",
+		},
+		{
+			name:       "trusted proxy without forwarding headers",
+			remoteAddr: "10.0.0.1:41000",
+			want:       "10.0.0.1:41000",
+		},
+		{
+			name:       "garbage forwarded value is ignored",
+			remoteAddr: "10.0.0.1:41000",
+			headers:    map[string]string{"X-Forwarded-For": "not-an-ip"},
+			want:       "10.0.0.1:41000",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var got string
+			handler := TrustedRealIP(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.RemoteAddr
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tc.remoteAddr
+			for key, value := range tc.headers {
+				req.Header.Set(key, value)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got != tc.want {
+				t.Fatalf("RemoteAddr = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
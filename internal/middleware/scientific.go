@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/query"
+)
+
+// ScientificLimit returns middleware that rewrites a limit written in
+// scientific notation, such as limit=1e6, to its plain integer form so the
+// handler and statistics read it like any other limit. A value that is not a
+// whole number is rejected with 400. When disabled the request passes through
+// unchanged and the handler rejects scientific notation itself.
+func ScientificLimit(enabled bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !enabled {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			values := r.URL.Query()
+			raw := values.Get("limit")
+			if !query.IsScientific(raw) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if len(raw) > 1 && raw[0] == ' ' {
+				raw = "+" + raw[1:]
+			}
+			limit, _ := strconv.ParseFloat(raw, 64)
+			if limit != math.Trunc(limit) {
+				writeJSONError(w, http.StatusBadRequest, "limit must be a whole number")
+				return
+			}
+			values.Set("limit", strconv.FormatFloat(limit, 'f', -1, 64))
+
+			r2 := r.Clone(r.Context())
+			r2.URL.RawQuery = values.Encode()
+			r2.RequestURI = r2.URL.RequestURI()
+			next.ServeHTTP(w, r2)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestScientificLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		enabled    bool
+		target     string
+		wantLimit  string
+		wantStatus int
+		wantError  string
+	}{
+		{name: "rewrites 1e6", enabled: true, target: "/fizzbuzz?limit=1e6", wantLimit: "1000000", wantStatus: http.StatusOK},
+		{name: "rewrites a decimal mantissa", enabled: true, target: "/fizzbuzz?limit=2.5E3", wantLimit: "2500", wantStatus: http.StatusOK},
+		{name: "plain limit unchanged", enabled: true, target: "/fizzbuzz?limit=15", wantLimit: "15", wantStatus: http.StatusOK},
+		{
+			name:       "fraction rejected",
+			enabled:    true,
+			target:     "/fizzbuzz?limit=1.5e0",
+			wantStatus: http.StatusBadRequest,
+			wantError:  "limit must be a whole number",
+		},
+		{name: "disabled leaves 1e6 for the handler", target: "/fizzbuzz?limit=1e6", wantLimit: "1e6", wantStatus: http.StatusOK},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var got string
+			wrapped := ScientificLimit(tc.enabled)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.URL.Query().Get("limit")
+			}))
+
+			rec := makeRequest(t, wrapped, tc.target)
+
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("expected status %d, got %d", tc.wantStatus, rec.Code)
+			}
+			if tc.wantError != "" {
+				var body struct {
+					Error string `json:"error"`
+				}
+				if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+					t.Fatalf("failed to decode error body: %v", err)
+				}
+				if body.Error != tc.wantError {
+					t.Fatalf("expected error %q, got %q", tc.wantError, body.Error)
+				}
+			}
+			if got != tc.wantLimit {
+				t.Fatalf("limit = %q, want %q", got, tc.wantLimit)
+			}
+		})
+	}
+}
@@ -2,16 +2,26 @@ package middleware
 
 import (
 	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
+	"strings"
 
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/query"
 	"github.com/Cerebrovinny/fizz-buzz-rest/internal/statistics"
 )
 
-// Statistics returns middleware that records successful FizzBuzz requests.
+// PreviewParam is the query flag marking a request as a preview. Previews
+// are served normally but never counted in statistics.
+const PreviewParam = "preview"
+
+// Statistics returns middleware that records successful FizzBuzz requests and
+// counts rejected ones by failure reason. Requests with preview=true are
+// skipped entirely.
 func Statistics(store *statistics.Store) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if store == nil {
+			if store == nil || isPreview(r.URL.Query()) {
 				next.ServeHTTP(w, r)
 				return
 			}
@@ -19,33 +29,34 @@ func Statistics(store *statistics.Store) func(http.Handler) http.Handler {
 			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
 			next.ServeHTTP(rec, r)
 
+			values := r.URL.Query()
+
 			if rec.status != http.StatusOK {
+				store.RecordFailure(failureReason(rec.status, values))
 				return
 			}
 
-			query := r.URL.Query()
-
-			int1Str := query.Get("int1")
-			int2Str := query.Get("int2")
-			limitStr := query.Get("limit")
-			str1 := query.Get("str1")
-			str2 := query.Get("str2")
+			int1Str := values.Get("int1")
+			int2Str := values.Get("int2")
+			limitStr := values.Get("limit")
+			str1 := values.Get("str1")
+			str2 := values.Get("str2")
 
 			if int1Str == "" || int2Str == "" || limitStr == "" || str1 == "" || str2 == "" {
 				return
 			}
 
-			int1, err := strconv.Atoi(int1Str)
+			int1, err := query.ParseInt(int1Str, 64)
 			if err != nil {
 				return
 			}
 
-			int2, err := strconv.Atoi(int2Str)
+			int2, err := query.ParseInt(int2Str, 64)
 			if err != nil {
 				return
 			}
 
-			limit, err := strconv.Atoi(limitStr)
+			limit, err := query.Atoi(limitStr)
 			if err != nil {
 				return
 			}
@@ -61,11 +72,101 @@ func Statistics(store *statistics.Store) func(http.Handler) http.Handler {
 	}
 }
 
+func isPreview(values url.Values) bool {
+	preview, err := strconv.ParseBool(values.Get(PreviewParam))
+	return err == nil && preview
+}
+
+// Failure reasons recorded for rejected FizzBuzz requests.
+const (
+	FailureMissingParameter = "missing_parameter"
+	FailureEmptyString      = "empty_string"
+	FailureInvalidInteger   = "invalid_integer"
+	FailureNonPositive      = "non_positive"
+	FailureInvalidParameter = "invalid_parameter"
+)
+
+// failureReason classifies a rejected request by re-reading its values in the
+// same order the handler validates it. Non-400 statuses are reported as
+// "status_<code>".
+func failureReason(status int, values url.Values) string {
+	if status != http.StatusBadRequest {
+		return "status_" + strconv.Itoa(status)
+	}
+	if slices.ContainsFunc(values["rule"], func(v string) bool { return strings.Contains(v, ":") }) {
+		return ruleFailureReason(values)
+	}
+
+	for _, param := range []string{"int1", "int2", "limit", "str1", "str2"} {
+		if _, ok := values[param]; !ok {
+			return FailureMissingParameter
+		}
+	}
+	if values.Get("str1") == "" || values.Get("str2") == "" {
+		return FailureEmptyString
+	}
+	for _, param := range []string{"int1", "int2", "limit"} {
+		bitSize := 64
+		if param == "limit" {
+			bitSize = strconv.IntSize
+		}
+		n, err := query.ParseInt(values.Get(param), bitSize)
+		if err != nil {
+			return FailureInvalidInteger
+		}
+		if n <= 0 {
+			return FailureNonPositive
+		}
+	}
+	return FailureInvalidParameter
+}
+
+// ruleFailureReason classifies a rejected request listing rule=divisor:word
+// pairs, which has no int1/int2/str1/str2 to check. Combining the pairs with
+// the two-divisor parameters is reported as an invalid parameter.
+func ruleFailureReason(values url.Values) string {
+	if slices.ContainsFunc(twoDivisorParams, values.Has) {
+		return FailureInvalidParameter
+	}
+	if !values.Has("limit") {
+		return FailureMissingParameter
+	}
+	for _, raw := range values["rule"] {
+		rawDivisor, word, ok := strings.Cut(raw, ":")
+		if !ok {
+			continue
+		}
+		divisor, err := query.ParseInt(rawDivisor, 64)
+		if err != nil {
+			return FailureInvalidInteger
+		}
+		if divisor <= 0 {
+			return FailureNonPositive
+		}
+		if word == "" {
+			return FailureEmptyString
+		}
+	}
+	limit, err := query.ParseInt(values.Get("limit"), strconv.IntSize)
+	if err != nil {
+		return FailureInvalidInteger
+	}
+	if limit <= 0 {
+		return FailureNonPositive
+	}
+	return FailureInvalidParameter
+}
+
 type statusRecorder struct {
 	http.ResponseWriter
 	status int
 }
 
+// Unwrap exposes the wrapped writer to http.ResponseController.
+func (sr *statusRecorder) Unwrap() http.ResponseWriter {
+	return sr.ResponseWriter
+}
+
 func (sr *statusRecorder) WriteHeader(code int) {
 	sr.status = code
 	sr.ResponseWriter.WriteHeader(code)
@@ -3,12 +3,14 @@ package middleware
 import (
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"sync"
 	"testing"
 	"testing/synctest"
 
 	"github.com/go-chi/chi/v5"
 
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/handler"
 	"github.com/Cerebrovinny/fizz-buzz-rest/internal/statistics"
 )
 
@@ -37,6 +39,28 @@ func TestStatistics_RecordsValidRequest(t *testing.T) {
 	}, 1)
 }
 
+func TestStatistics_NormalizesIntegerForms(t *testing.T) {
+	store := statistics.NewStore()
+	wrapped := Statistics(store)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, target := range []string{
+		"/fizzbuzz?int1=3&int2=5&limit=15&str1=fizz&str2=buzz",
+		"/fizzbuzz?int1=+3&int2=%2B5&limit=015&str1=fizz&str2=buzz",
+	} {
+		makeRequest(t, wrapped, target)
+	}
+
+	assertRecorded(t, store, statistics.RequestParams{
+		Int1:  3,
+		Int2:  5,
+		Limit: 15,
+		Str1:  "fizz",
+		Str2:  "buzz",
+	}, 2)
+}
+
 func TestStatistics_RecordsMultipleRequests(t *testing.T) {
 	store := statistics.NewStore()
 	mw := Statistics(store)
@@ -238,6 +262,105 @@ func TestStatistics_DifferentPaths(t *testing.T) {
 	}
 }
 
+func TestStatistics_CountsFailuresByReason(t *testing.T) {
+	store := statistics.NewStore()
+	h := handler.NewHandler(store, nil, handler.WithMaxLimit(100, false))
+	wrapped := Statistics(store)(http.HandlerFunc(h.FizzBuzz))
+
+	targets := []string{
+		"/fizzbuzz?int2=5&limit=15&str1=fizz&str2=buzz",
+		"/fizzbuzz",
+		"/fizzbuzz?int1=3&int2=5&limit=15&str1=&str2=buzz",
+		"/fizzbuzz?int1=abc&int2=5&limit=15&str1=fizz&str2=buzz",
+		"/fizzbuzz?int1=3&int2=0&limit=15&str1=fizz&str2=buzz",
+		"/fizzbuzz?int1=3&int2=5&limit=-1&str1=fizz&str2=buzz",
+		"/fizzbuzz?int1=3&int2=5&limit=500&str1=fizz&str2=buzz",
+		"/fizzbuzz?int1=3&int2=5&limit=15&str1=fizz&str2=buzz",
+	}
+	for _, target := range targets {
+		makeRequest(t, wrapped, target)
+	}
+
+	want := map[string]int{
+		FailureMissingParameter: 2,
+		FailureEmptyString:      1,
+		FailureInvalidInteger:   1,
+		FailureNonPositive:      2,
+		FailureInvalidParameter: 1,
+	}
+	if got := store.Failures(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Failures() = %v, want %v", got, want)
+	}
+
+	assertRecorded(t, store, statistics.RequestParams{Int1: 3, Int2: 5, Limit: 15, Str1: "fizz", Str2: "buzz"}, 1)
+}
+
+func TestStatistics_ClassifiesRulePairFailures(t *testing.T) {
+	store := statistics.NewStore()
+	h := handler.NewHandler(store, nil, handler.WithMaxLimit(100, false))
+	wrapped := Statistics(store)(http.HandlerFunc(h.FizzBuzz))
+
+	targets := []string{
+		"/fizzbuzz?rule=3:fizz",
+		"/fizzbuzz?rule=3:&limit=15",
+		"/fizzbuzz?rule=x:fizz&limit=15",
+		"/fizzbuzz?rule=0:fizz&limit=15",
+		"/fizzbuzz?rule=3:fizz&limit=0",
+		"/fizzbuzz?rule=3:fizz&int1=3&limit=15",
+		"/fizzbuzz?rule=3:fizz&limit=500",
+	}
+	for _, target := range targets {
+		makeRequest(t, wrapped, target)
+	}
+
+	want := map[string]int{
+		FailureMissingParameter: 1,
+		FailureEmptyString:      1,
+		FailureInvalidInteger:   1,
+		FailureNonPositive:      2,
+		FailureInvalidParameter: 2,
+	}
+	if got := store.Failures(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Failures() = %v, want %v", got, want)
+	}
+}
+
+func TestStatistics_CountsNonValidationFailures(t *testing.T) {
+	store := statistics.NewStore()
+	wrapped := Statistics(store)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+
+	makeRequest(t, wrapped, "/fizzbuzz?int1=3&int2=5&limit=15&str1=fizz&str2=buzz")
+
+	want := map[string]int{"status_503": 1}
+	if got := store.Failures(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Failures() = %v, want %v", got, want)
+	}
+}
+
+func TestStatistics_SkipsPreviewRequests(t *testing.T) {
+	store := statistics.NewStore()
+	h := handler.NewHandler(store, nil)
+	wrapped := Statistics(store)(http.HandlerFunc(h.FizzBuzz))
+
+	for range 3 {
+		rec := makeRequest(t, wrapped, "/fizzbuzz?int1=2&int2=7&limit=14&str1=foo&str2=bar&preview=true")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+	}
+	assertNotRecorded(t, store)
+
+	makeRequest(t, wrapped, "/fizzbuzz?int1=3&int2=5&limit=15&str1=fizz&str2=buzz&preview=false")
+	makeRequest(t, wrapped, "/fizzbuzz?preview=true")
+
+	assertRecorded(t, store, statistics.RequestParams{Int1: 3, Int2: 5, Limit: 15, Str1: "fizz", Str2: "buzz"}, 1)
+	if failures := store.Failures(); len(failures) != 0 {
+		t.Fatalf("expected preview failures to be skipped, got %v", failures)
+	}
+}
+
 func makeRequest(t *testing.T, handler http.Handler, target string) *httptest.ResponseRecorder {
 	t.Helper()
 
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/query"
+)
+
+// tolerantParams are the integer parameters TolerantNumbers rewrites.
+var tolerantParams = []string{"int1", "int2", "limit", "start"}
+
+// TolerantNumbers returns middleware that strips digit separators from the
+// integer parameters, so a pasted limit=1,000 or limit=1_000 reaches the
+// handler and statistics as limit=1000. Values that are not separated
+// integers are left for the handler to reject. When disabled the request
+// passes through unchanged.
+func TolerantNumbers(enabled bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !enabled {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			values := r.URL.Query()
+			rewritten := false
+			for _, name := range tolerantParams {
+				for i, raw := range values[name] {
+					if stripped, ok := query.StripSeparators(raw); ok {
+						values[name][i] = stripped
+						rewritten = true
+					}
+				}
+			}
+			if !rewritten {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			r2 := r.Clone(r.Context())
+			r2.URL.RawQuery = values.Encode()
+			r2.RequestURI = r2.URL.RequestURI()
+			next.ServeHTTP(w, r2)
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestTolerantNumbers(t *testing.T) {
+	tests := []struct {
+		name      string
+		enabled   bool
+		target    string
+		wantLimit string
+		wantStart string
+	}{
+		{name: "strips commas", enabled: true, target: "/fizzbuzz?limit=1,000", wantLimit: "1000"},
+		{name: "strips underscores", enabled: true, target: "/fizzbuzz?limit=1_000", wantLimit: "1000"},
+		{name: "plain limit unchanged", enabled: true, target: "/fizzbuzz?limit=1000", wantLimit: "1000"},
+		{name: "keeps the sign", enabled: true, target: "/fizzbuzz?limit=5&start=-1,000", wantLimit: "5", wantStart: "-1000"},
+		{name: "misplaced comma left for the handler", enabled: true, target: "/fizzbuzz?limit=1,00", wantLimit: "1,00"},
+		{name: "disabled leaves separators", target: "/fizzbuzz?limit=1,000", wantLimit: "1,000"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var limit, start string
+			wrapped := TolerantNumbers(tc.enabled)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				limit = r.URL.Query().Get("limit")
+				start = r.URL.Query().Get("start")
+			}))
+
+			rec := makeRequest(t, wrapped, tc.target)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+			if limit != tc.wantLimit {
+				t.Fatalf("limit = %q, want %q", limit, tc.wantLimit)
+			}
+			if start != tc.wantStart {
+				t.Fatalf("start = %q, want %q", start, tc.wantStart)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+// Package query parses integer query parameters the same way everywhere they
+// are read: by the handlers that validate them, the middleware that records
+// statistics, and the config that names hot parameter sets.
+package query
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxQuotedLen caps how many bytes of a rejected value an Error echoes back,
+// so a megabyte of junk in a query string stays out of responses and logs.
+const maxQuotedLen = 32
+
+// Error reports a query value that is not a valid integer. Its message is
+// phrased as the reason half of a parameter error and quotes the value.
+type Error struct {
+	Value string
+}
+
+func (e *Error) Error() string {
+	return "must be a valid integer (got " + Quote(e.Value) + ")"
+}
+
+// Quote returns value as a Go string literal, truncated to maxQuotedLen bytes
+// on a rune boundary with a trailing "..." when longer.
+func Quote(value string) string {
+	if len(value) <= maxQuotedLen {
+		return strconv.Quote(value)
+	}
+	cut := maxQuotedLen
+	for cut > 0 && !utf8.RuneStart(value[cut]) {
+		cut--
+	}
+	return strconv.Quote(value[:cut] + "...")
+}
+
+// ParseInt parses a decimal integer query value of the given bit size. It
+// accepts an optional leading + or - followed by digits. An unescaped + is
+// decoded to a space in query strings, so a single leading space is read as
+// +. Leading zeros are decimal, so 015 is 15, never octal. Failures are
+// reported as *Error carrying the value as received.
+func ParseInt(value string, bitSize int) (int64, error) {
+	normalized := value
+	if len(value) > 1 && value[0] == ' ' {
+		normalized = "+" + value[1:]
+	}
+	n, err := strconv.ParseInt(normalized, 10, bitSize)
+	if err != nil {
+		return 0, &Error{Value: value}
+	}
+	return n, nil
+}
+
+// Atoi is ParseInt for an int.
+func Atoi(value string) (int, error) {
+	n, err := ParseInt(value, strconv.IntSize)
+	return int(n), err
+}
+
+// IsScientific reports whether value is a number in scientific notation,
+// such as 1e6 or 2.5E3, which ParseInt rejects.
+func IsScientific(value string) bool {
+	if !strings.ContainsAny(value, "eE") {
+		return false
+	}
+	if len(value) > 1 && value[0] == ' ' {
+		value = "+" + value[1:]
+	}
+	_, err := strconv.ParseFloat(value, 64)
+	return err == nil
+}
+
+// StripSeparators removes digit separators from an integer value: commas
+// grouping thousands, as in 1,000,000, or single underscores between digits,
+// as in the Go literal 1_000_000. It reports false, leaving value unchanged,
+// when value has no separators, mixes the two, or places one anywhere else.
+func StripSeparators(value string) (string, bool) {
+	sign, digits := "", value
+	if digits != "" && (digits[0] == '+' || digits[0] == '-' || digits[0] == ' ') {
+		sign, digits = digits[:1], digits[1:]
+	}
+
+	var groups []string
+	switch {
+	case strings.Contains(digits, ",") && strings.Contains(digits, "_"):
+		return value, false
+	case strings.Contains(digits, ","):
+		groups = strings.Split(digits, ",")
+		if len(groups[0]) > 3 {
+			return value, false
+		}
+		for _, group := range groups[1:] {
+			if len(group) != 3 {
+				return value, false
+			}
+		}
+	case strings.Contains(digits, "_"):
+		groups = strings.Split(digits, "_")
+	default:
+		return value, false
+	}
+
+	for _, group := range groups {
+		if group == "" || strings.Trim(group, "0123456789") != "" {
+			return value, false
+		}
+	}
+	return sign + strings.Join(groups, ""), true
+}
+
+// HasLeadingZeros reports whether value, after an optional sign, is a
+// multi-digit number starting with 0.
+func HasLeadingZeros(value string) bool {
+	if value != "" && (value[0] == '+' || value[0] == '-' || value[0] == ' ') {
+		value = value[1:]
+	}
+	return len(value) > 1 && value[0] == '0'
+}
@@ -0,0 +1,146 @@
+package query
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseInt(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{value: "3", want: 3},
+		{value: "+3", want: 3},
+		{value: " 3", want: 3},
+		{value: "-3", want: -3},
+		{value: "015", want: 15},
+		{value: "+015", want: 15},
+		{value: "0", want: 0},
+		{value: "", wantErr: true},
+		{value: " ", wantErr: true},
+		{value: "+-3", wantErr: true},
+		{value: "  3", wantErr: true},
+		{value: "3 ", wantErr: true},
+		{value: "0x10", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.value, func(t *testing.T) {
+			got, err := ParseInt(tc.value, 64)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseInt(%q) error = %v, wantErr %v", tc.value, err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Fatalf("ParseInt(%q) = %d, want %d", tc.value, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHasLeadingZeros(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"3", false},
+		{"0", false},
+		{"+0", false},
+		{"30", false},
+		{"015", true},
+		{"+015", true},
+		{" 015", true},
+		{"-015", true},
+		{"00", true},
+	}
+
+	for _, tc := range tests {
+		if got := HasLeadingZeros(tc.value); got != tc.want {
+			t.Errorf("HasLeadingZeros(%q) = %v, want %v", tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestIsScientific(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"1e6", true},
+		{"2.5E3", true},
+		{" 1e6", true},
+		{"1e-2", true},
+		{"1000000", false},
+		{"1e", false},
+		{"abc", false},
+		{"Inf", false},
+	}
+
+	for _, tc := range tests {
+		if got := IsScientific(tc.value); got != tc.want {
+			t.Errorf("IsScientific(%q) = %v, want %v", tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestStripSeparators(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   string
+		wantOK bool
+	}{
+		{"1,000", "1000", true},
+		{"1_000", "1000", true},
+		{"1000", "1000", false},
+		{"12,345,678", "12345678", true},
+		{"-1,000", "-1000", true},
+		{" 1_000", " 1000", true},
+		{"1_0_0", "100", true},
+		{"1,00", "1,00", false},
+		{"1000,000", "1000,000", false},
+		{",000", ",000", false},
+		{"1__000", "1__000", false},
+		{"_1000", "_1000", false},
+		{"1000_", "1000_", false},
+		{"1,000_000", "1,000_000", false},
+		{"1,0a0", "1,0a0", false},
+	}
+
+	for _, tc := range tests {
+		got, ok := StripSeparators(tc.value)
+		if got != tc.want || ok != tc.wantOK {
+			t.Errorf("StripSeparators(%q) = %q, %v, want %q, %v", tc.value, got, ok, tc.want, tc.wantOK)
+		}
+	}
+}
+
+func TestParseInt_ErrorQuotesValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "abc", want: `must be a valid integer (got "abc")`},
+		{value: "", want: `must be a valid integer (got "")`},
+		{value: "a\nb", want: `must be a valid integer (got "a\nb")`},
+		{value: strings.Repeat("9", 40), want: `must be a valid integer (got "` + strings.Repeat("9", 32) + `...")`},
+		{value: strings.Repeat("a", 31) + "é", want: `must be a valid integer (got "` + strings.Repeat("a", 31) + `...")`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.value, func(t *testing.T) {
+			_, err := ParseInt(tc.value, 64)
+			var qerr *Error
+			if !errors.As(err, &qerr) {
+				t.Fatalf("ParseInt(%q) error = %v, want *Error", tc.value, err)
+			}
+			if qerr.Value != tc.value {
+				t.Errorf("Value = %q, want %q", qerr.Value, tc.value)
+			}
+			if got := err.Error(); got != tc.want {
+				t.Errorf("Error() = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
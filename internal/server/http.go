@@ -0,0 +1,28 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/config"
+)
+
+// NewHTTPServer returns the server to listen with: cfg's port, timeouts and
+// keep-alive setting, serving handler. Every request context derives from
+// baseCtx, so cancelling it ends long-lived responses such as
+// /statistics/stream.
+func NewHTTPServer(baseCtx context.Context, cfg *config.Config, handler http.Handler) *http.Server {
+	srv := &http.Server{
+		Addr:         ":" + cfg.Port,
+		Handler:      handler,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+		BaseContext:  func(net.Listener) context.Context { return baseCtx },
+	}
+	if cfg.DisableKeepAlive {
+		srv.SetKeepAlivesEnabled(false)
+	}
+	return srv
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestNewHTTPServer_AppliesConfig(t *testing.T) {
+	cfg := testConfig()
+	cfg.Port = "9090"
+	cfg.ReadTimeout = time.Second
+	cfg.WriteTimeout = 2 * time.Second
+	cfg.IdleTimeout = 3 * time.Second
+
+	type key struct{}
+	baseCtx := context.WithValue(context.Background(), key{}, "base")
+	srv := NewHTTPServer(baseCtx, cfg, http.NotFoundHandler())
+
+	if srv.Addr != ":9090" {
+		t.Fatalf("Addr = %q, want %q", srv.Addr, ":9090")
+	}
+	if srv.ReadTimeout != cfg.ReadTimeout || srv.WriteTimeout != cfg.WriteTimeout || srv.IdleTimeout != cfg.IdleTimeout {
+		t.Fatalf("timeouts = %s/%s/%s, want %s/%s/%s", srv.ReadTimeout, srv.WriteTimeout, srv.IdleTimeout,
+			cfg.ReadTimeout, cfg.WriteTimeout, cfg.IdleTimeout)
+	}
+	if got := srv.BaseContext(nil).Value(key{}); got != "base" {
+		t.Fatalf("BaseContext value = %v, want %q", got, "base")
+	}
+}
+
+func TestNewHTTPServer_DisableKeepAlive(t *testing.T) {
+	tests := []struct {
+		name      string
+		disable   bool
+		wantClose bool
+	}{
+		{name: "keep-alive by default", disable: false, wantClose: false},
+		{name: "disabled", disable: true, wantClose: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.DisableKeepAlive = tc.disable
+			srv := NewHTTPServer(context.Background(), cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			}))
+
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("listen: %v", err)
+			}
+			go func() { _ = srv.Serve(ln) }()
+			t.Cleanup(func() { _ = srv.Close() })
+
+			resp, err := http.Get("http://" + ln.Addr().String())
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.Close != tc.wantClose {
+				t.Fatalf("response Close = %t, want %t (Connection: %q)", resp.Close, tc.wantClose, resp.Header.Get("Connection"))
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/config"
+)
+
+// LimitSetter applies a new limit cap at runtime. *handler.Handler
+// satisfies it.
+type LimitSetter interface {
+	SetMaxLimit(maxLimit int, truncate bool)
+}
+
+// Runtime holds the settings that can change without a restart: the log
+// level, the CORS allowed origins of both route groups, and the FizzBuzz
+// limit cap. Everything else in config.Config only takes effect on the next
+// start.
+type Runtime struct {
+	level      *slog.LevelVar
+	origins    atomic.Pointer[[]string]
+	opsOrigins atomic.Pointer[[]string]
+	limits     LimitSetter
+
+	mu      sync.Mutex
+	current *config.Config
+}
+
+// NewRuntime seeds the runtime state from cfg. level is the LevelVar backing
+// the application logger; limits may be nil.
+func NewRuntime(cfg *config.Config, level *slog.LevelVar, limits LimitSetter) *Runtime {
+	rt := &Runtime{level: level, limits: limits, current: cfg}
+	rt.origins.Store(&cfg.CORSAllowedOrigins)
+	rt.opsOrigins.Store(&cfg.OpsCORSOrigins)
+	return rt
+}
+
+// allowOrigin reports whether origin is in the current CORS allow list of
+// the data routes.
+func (rt *Runtime) allowOrigin(_ *http.Request, origin string) bool {
+	return originAllowed(*rt.origins.Load(), origin)
+}
+
+// allowOpsOrigin reports whether origin is in the current CORS allow list of
+// the ops routes.
+func (rt *Runtime) allowOpsOrigin(_ *http.Request, origin string) bool {
+	return originAllowed(*rt.opsOrigins.Load(), origin)
+}
+
+func originAllowed(origins []string, origin string) bool {
+	return slices.Contains(origins, "*") || slices.Contains(origins, origin)
+}
+
+// restartOnly lists the settings that Reload reports but does not apply.
+var restartOnly = []struct {
+	name  string
+	value func(*config.Config) string
+}{
+	{"PORT", func(c *config.Config) string { return c.Port }},
+	{"ROUTE_PREFIX", func(c *config.Config) string { return c.RoutePrefix }},
+	{"REQUIRED_HEADER", func(c *config.Config) string { return c.RequiredHeader }},
+	{"EXTRA_RESPONSE_HEADERS", func(c *config.Config) string { return fmt.Sprint(c.ExtraResponseHeaders) }},
+	{"TRAILING_SLASH", func(c *config.Config) string { return c.TrailingSlash }},
+	{"READ_TIMEOUT", func(c *config.Config) string { return c.ReadTimeout.String() }},
+	{"WRITE_TIMEOUT", func(c *config.Config) string { return c.WriteTimeout.String() }},
+	{"IDLE_TIMEOUT", func(c *config.Config) string { return c.IdleTimeout.String() }},
+	{"DISABLE_KEEPALIVE", func(c *config.Config) string { return fmt.Sprint(c.DisableKeepAlive) }},
+	{"REQUEST_TIMEOUT", func(c *config.Config) string { return c.RequestTimeout.String() }},
+	{"FIZZBUZZ_TIMEOUT", func(c *config.Config) string { return c.FizzBuzzTimeout.String() }},
+	{"STATISTICS_TIMEOUT", func(c *config.Config) string { return c.StatisticsTimeout.String() }},
+	{"HEALTH_TIMEOUT", func(c *config.Config) string { return c.HealthTimeout.String() }},
+	{"SHUTDOWN_TIMEOUT", func(c *config.Config) string { return c.ShutdownTimeout.String() }},
+	{"STATISTICS_STREAM_INTERVAL", func(c *config.Config) string { return c.StreamInterval.String() }},
+	{"LOG_FORMAT", func(c *config.Config) string { return c.LogFormat }},
+	{"IDEMPOTENCY_TTL", func(c *config.Config) string { return c.IdempotencyTTL.String() }},
+	{"TRUSTED_PROXIES", func(c *config.Config) string { return fmt.Sprint(c.TrustedProxies) }},
+	{"ADMIN_API_KEY", func(c *config.Config) string { return c.AdminAPIKey }},
+	{"RESPONSE_SHAPE", func(c *config.Config) string { return c.ResponseShape }},
+	{"MAX_DISTINCT_PARAMS", func(c *config.Config) string { return fmt.Sprint(c.MaxDistinctParams) }},
+	{"STATS_DECAY_HALFLIFE", func(c *config.Config) string { return c.StatsDecayHalfLife.String() }},
+	{"STATS_HOT_PARAMS", func(c *config.Config) string { return fmt.Sprint(c.StatsHotParams) }},
+	{"STATS_PERSIST_PATH", func(c *config.Config) string { return c.StatsPersistPath }},
+	{"STATS_PERSIST_INTERVAL", func(c *config.Config) string { return c.StatsPersistInterval.String() }},
+	{"STATS_SAVE_RETRIES", func(c *config.Config) string { return fmt.Sprint(c.StatsSaveRetries) }},
+	{"STATS_CREATE_DIR", func(c *config.Config) string { return fmt.Sprint(c.StatsCreateDir) }},
+	{"MAX_CONNECTIONS", func(c *config.Config) string { return fmt.Sprint(c.MaxConnections) }},
+	{"MAX_CONCURRENT_GENERATIONS", func(c *config.Config) string { return fmt.Sprint(c.MaxConcurrentGenerations) }},
+	{"HEAVY_GENERATION_LIMIT", func(c *config.Config) string { return fmt.Sprint(c.HeavyGenerationLimit) }},
+	{"MAX_RESPONSE_BYTES", func(c *config.Config) string { return fmt.Sprint(c.MaxResponseBytes) }},
+	{"MAX_BODY_BYTES", func(c *config.Config) string { return fmt.Sprint(c.MaxBodyBytes) }},
+	{"MAX_TOP_N", func(c *config.Config) string { return fmt.Sprint(c.MaxTopN) }},
+	{"MAX_RULES", func(c *config.Config) string { return fmt.Sprint(c.MaxRules) }},
+	{"MAX_DISTINCT_PER_IP", func(c *config.Config) string { return fmt.Sprint(c.MaxDistinctPerIP) }},
+	{"DISTINCT_PER_IP_WINDOW", func(c *config.Config) string { return c.DistinctPerIPWindow.String() }},
+	{"MIN_DIVISOR", func(c *config.Config) string { return fmt.Sprint(c.MinDivisor) }},
+	{"MAX_DIVISOR", func(c *config.Config) string { return fmt.Sprint(c.MaxDivisor) }},
+	{"ALLOWED_STRINGS", func(c *config.Config) string { return fmt.Sprint(c.AllowedStrings) }},
+	{"STRICT_INTEGERS", func(c *config.Config) string { return fmt.Sprint(c.StrictIntegers) }},
+	{"ALLOW_SCIENTIFIC_LIMIT", func(c *config.Config) string { return fmt.Sprint(c.AllowScientificLimit) }},
+	{"TOLERANT_NUMBERS", func(c *config.Config) string { return fmt.Sprint(c.TolerantNumbers) }},
+	{"SEMANTIC_ERROR_STATUS", func(c *config.Config) string { return fmt.Sprint(c.SemanticErrorStatus) }},
+	{"USAGE_HINT", func(c *config.Config) string { return fmt.Sprint(c.UsageHint) }},
+	{"LATENCY_BUCKETS", func(c *config.Config) string { return fmt.Sprint(c.LatencyBuckets) }},
+	{"MAINTENANCE_MODE", func(c *config.Config) string { return fmt.Sprint(c.MaintenanceMode) }},
+	{"COMPRESS_RESPONSES", func(c *config.Config) string { return fmt.Sprint(c.CompressResponses) }},
+	{"DEFAULT_*", func(c *config.Config) string { return fmt.Sprint(c.DefaultParams) }},
+	{"FIZZBUZZ_PRESETS", func(c *config.Config) string { return fmt.Sprint(c.Presets) }},
+}
+
+// Reload applies the runtime-changeable settings from next and logs each
+// change. Changed settings that need a restart are logged as ignored.
+func (rt *Runtime) Reload(next *config.Config, logger *slog.Logger) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	prev := rt.current
+
+	if next.LogLevel != prev.LogLevel {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(next.LogLevel)); err == nil {
+			rt.level.Set(level)
+			logger.Info("config reloaded", slog.String("setting", "LOG_LEVEL"),
+				slog.String("from", prev.LogLevel), slog.String("to", next.LogLevel))
+		}
+	}
+
+	if !slices.Equal(next.CORSAllowedOrigins, prev.CORSAllowedOrigins) {
+		rt.origins.Store(&next.CORSAllowedOrigins)
+		logger.Info("config reloaded", slog.String("setting", "CORS_ALLOWED_ORIGINS"),
+			slog.Any("from", prev.CORSAllowedOrigins), slog.Any("to", next.CORSAllowedOrigins))
+	}
+
+	if !slices.Equal(next.OpsCORSOrigins, prev.OpsCORSOrigins) {
+		rt.opsOrigins.Store(&next.OpsCORSOrigins)
+		logger.Info("config reloaded", slog.String("setting", "OPS_CORS_ALLOWED_ORIGINS"),
+			slog.Any("from", prev.OpsCORSOrigins), slog.Any("to", next.OpsCORSOrigins))
+	}
+
+	if next.MaxLimit != prev.MaxLimit || next.TruncateMode != prev.TruncateMode {
+		if rt.limits != nil {
+			rt.limits.SetMaxLimit(next.MaxLimit, next.TruncateMode)
+		}
+		logger.Info("config reloaded", slog.String("setting", "MAX_LIMIT"),
+			slog.Int("max_limit", next.MaxLimit), slog.Bool("truncate_mode", next.TruncateMode))
+	}
+
+	for _, setting := range restartOnly {
+		if setting.value(prev) != setting.value(next) {
+			logger.Warn("config change ignored until restart", slog.String("setting", setting.name))
+		}
+	}
+
+	// Restart-only values stay as they were so later reloads compare
+	// against what is actually in effect.
+	applied := *prev
+	applied.LogLevel = next.LogLevel
+	applied.CORSAllowedOrigins = next.CORSAllowedOrigins
+	applied.OpsCORSOrigins = next.OpsCORSOrigins
+	applied.MaxLimit = next.MaxLimit
+	applied.TruncateMode = next.TruncateMode
+	rt.current = &applied
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/handler"
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/statistics"
+)
+
+func TestRuntime_ReloadAppliesLogLevel(t *testing.T) {
+	cfg := testConfig()
+	cfg.LogLevel = "info"
+
+	level := new(slog.LevelVar)
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: level}))
+	rt := NewRuntime(cfg, level, nil)
+
+	next := *cfg
+	next.LogLevel = "debug"
+	rt.Reload(&next, logger)
+
+	if got := level.Level(); got != slog.LevelDebug {
+		t.Fatalf("level = %v, want %v", got, slog.LevelDebug)
+	}
+	if !logger.Enabled(t.Context(), slog.LevelDebug) {
+		t.Fatal("expected debug logging to be enabled after reload")
+	}
+	if !strings.Contains(logs.String(), "setting=LOG_LEVEL") {
+		t.Fatalf("expected the level change to be logged, got %q", logs.String())
+	}
+}
+
+func TestRuntime_ReloadIgnoresRestartOnlySettings(t *testing.T) {
+	cfg := testConfig()
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	rt := NewRuntime(cfg, new(slog.LevelVar), nil)
+
+	next := *cfg
+	next.Port = "9090"
+	rt.Reload(&next, logger)
+
+	if !strings.Contains(logs.String(), "config change ignored until restart") || !strings.Contains(logs.String(), "setting=PORT") {
+		t.Fatalf("expected PORT change to be logged as ignored, got %q", logs.String())
+	}
+	if rt.current.Port != cfg.Port {
+		t.Fatalf("running port = %q, want %q", rt.current.Port, cfg.Port)
+	}
+}
+
+func TestRuntime_ReloadAppliesCORSAndLimits(t *testing.T) {
+	cfg := testConfig()
+	cfg.CORSAllowedOrigins = []string{"https://old.example.com"}
+	cfg.OpsCORSOrigins = []string{"https://old-ops.example.com"}
+
+	h := handler.NewHandler(statistics.NewStore(), nil, handler.WithMaxLimit(cfg.MaxLimit, false))
+	rt := NewRuntime(cfg, new(slog.LevelVar), h)
+	router := NewRouter(Options{Config: cfg, Logger: slog.New(slog.DiscardHandler), Store: statistics.NewStore(), Handlers: h, Runtime: rt})
+
+	next := *cfg
+	next.CORSAllowedOrigins = []string{"https://new.example.com"}
+	next.OpsCORSOrigins = []string{"https://new-ops.example.com"}
+	next.MaxLimit = 5
+	rt.Reload(&next, slog.New(slog.DiscardHandler))
+
+	req := httptest.NewRequest(http.MethodGet, "/statistics", nil)
+	req.Header.Set("Origin", "https://new.example.com")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://new.example.com" {
+		t.Fatalf("Access-Control-Allow-Origin = %q, want reloaded origin", got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/statistics", nil)
+	req.Header.Set("Origin", "https://old.example.com")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Fatalf("Access-Control-Allow-Origin = %q, want old origin rejected", got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.Header.Set("Origin", "https://new-ops.example.com")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://new-ops.example.com" {
+		t.Fatalf("Access-Control-Allow-Origin = %q, want reloaded ops origin", got)
+	}
+
+	if rec := serve(router, "/fizzbuzz?int1=3&int2=5&limit=6&str1=fizz&str2=buzz"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("limit above reloaded cap: expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
@@ -0,0 +1,241 @@
+package server
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/cors"
+
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/config"
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/metrics"
+	mw "github.com/Cerebrovinny/fizz-buzz-rest/internal/middleware"
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/statistics"
+)
+
+// Handlers is the set of endpoints served by the router. *handler.Handler
+// satisfies it; tests can embed one and override individual endpoints.
+type Handlers interface {
+	FizzBuzz(w http.ResponseWriter, r *http.Request)
+	FizzBuzzOptions(w http.ResponseWriter, r *http.Request)
+	Statistics(w http.ResponseWriter, r *http.Request)
+	LimitStatistics(w http.ResponseWriter, r *http.Request)
+	FailureStatistics(w http.ResponseWriter, r *http.Request)
+	ExportStatistics(w http.ResponseWriter, r *http.Request)
+	BreakdownStatistics(w http.ResponseWriter, r *http.Request)
+	StreamStatistics(w http.ResponseWriter, r *http.Request)
+	ReplayStatistics(w http.ResponseWriter, r *http.Request)
+	TopStatistics(w http.ResponseWriter, r *http.Request)
+	CountStatistics(w http.ResponseWriter, r *http.Request)
+	DiffStatistics(w http.ResponseWriter, r *http.Request)
+	WordsStatistics(w http.ResponseWriter, r *http.Request)
+	SampleStatistics(w http.ResponseWriter, r *http.Request)
+	Health(w http.ResponseWriter, r *http.Request)
+	Ready(w http.ResponseWriter, r *http.Request)
+	ToggleHealth(w http.ResponseWriter, r *http.Request)
+	DetailedHealth(w http.ResponseWriter, r *http.Request)
+}
+
+// Options bundles the collaborators wired together by NewRouter.
+type Options struct {
+	Config *config.Config
+	Logger *slog.Logger
+	// AccessLog receives Common Log Format lines when LOG_FORMAT is clf.
+	AccessLog io.Writer
+	Store     *statistics.Store
+	Metrics   *metrics.Registry
+	Handlers  Handlers
+	// Runtime, when set, supplies the CORS allowed origins so they can be
+	// reloaded without rebuilding the router.
+	Runtime *Runtime
+}
+
+// NewRouter builds the HTTP router with shared middleware and per-route
+// timeouts.
+func NewRouter(opts Options) chi.Router {
+	cfg := opts.Config
+	h := opts.Handlers
+
+	router := chi.NewRouter()
+
+	router.Use(chimiddleware.RequestID)
+	router.Use(mw.ResponseHeaders(cfg.ExtraResponseHeaders))
+	if len(cfg.TrustedProxies) > 0 {
+		router.Use(mw.TrustedRealIP(cfg.TrustedProxies))
+	} else {
+		router.Use(chimiddleware.RealIP)
+	}
+	// Recoverer wraps the request logger, which logs a panic with its stack
+	// and re-raises it for Recoverer to answer 500.
+	router.Use(chimiddleware.Recoverer)
+	durations := opts.Metrics.HistogramVec("http_request_duration_seconds",
+		"Request durations by route and status.", cfg.LatencyBuckets, "route", "status")
+	if cfg.LogFormat == "clf" {
+		router.Use(mw.CommonLogFormat(opts.AccessLog, durations))
+	} else {
+		router.Use(mw.RequestLogger(opts.Logger, durations))
+	}
+	router.Use(mw.ResponseSizes(opts.Metrics.HistogramVec("http_response_size_bytes",
+		"Response body sizes by route and status.", mw.ResponseSizeBuckets, "route", "status")))
+	router.Use(mw.LimitInFlight(cfg.MaxConnections))
+	switch cfg.TrailingSlash {
+	case "strip":
+		router.Use(chimiddleware.StripSlashes)
+	case "redirect":
+		router.Use(chimiddleware.RedirectSlashes)
+	}
+
+	prefix := cfg.RoutePrefix
+	maintenance := mw.NewMaintenance(cfg.MaintenanceMode, prefix+"/health", prefix+"/health/detailed", prefix+"/admin/maintenance")
+	router.Use(maintenance.Handler)
+
+	// With ROUTE_PREFIX set every route lives on a mounted subrouter, so
+	// the bare paths fall through to the root router's 404.
+	routes := chi.Router(router)
+	if prefix != "" {
+		routes = chi.NewRouter()
+		router.Mount(prefix, routes)
+	}
+
+	// Data and ops routes answer to separate CORS allow lists, so the CORS
+	// middleware is applied per group rather than globally.
+	dataCORS := corsOptions(cfg.CORSAllowedOrigins)
+	opsCORS := corsOptions(cfg.OpsCORSOrigins)
+	if opts.Runtime != nil {
+		dataCORS.AllowOriginFunc = opts.Runtime.allowOrigin
+		opsCORS.AllowOriginFunc = opts.Runtime.allowOpsOrigin
+	}
+
+	routes.Group(func(router chi.Router) {
+		router.Use(cors.Handler(dataCORS))
+		// Preflights never carry custom headers, so the check runs after
+		// CORS has answered them.
+		router.Use(mw.RequireHeader(cfg.RequiredHeader))
+		if cfg.CompressResponses {
+			router.Use(mw.Gzip(opts.Logger))
+		}
+
+		fizzBuzzBytes := mw.CountBytes(opts.Metrics.Counter("fizzbuzz_response_bytes_total", "Response body bytes served by /fizzbuzz."))
+		quota := mw.DistinctParamsPerIP(cfg.MaxDistinctPerIP, cfg.DistinctPerIPWindow)
+		tolerant := mw.TolerantNumbers(cfg.TolerantNumbers)
+		scientific := mw.ScientificLimit(cfg.AllowScientificLimit)
+		idempotency := mw.Idempotency(cfg.IdempotencyTTL, mw.CacheMetrics{
+			Hits:   opts.Metrics.Counter("fizzbuzz_cache_hits_total", "/fizzbuzz responses replayed from the Idempotency-Key cache."),
+			Misses: opts.Metrics.Counter("fizzbuzz_cache_misses_total", "/fizzbuzz requests with an Idempotency-Key that were not in the cache."),
+		})
+		router.With(
+			timeout(cfg.FizzBuzzTimeout),
+			fizzBuzzBytes,
+			idempotency,
+			mw.Presets(cfg.Presets),
+			mw.DefaultQueryParams(cfg.DefaultParams),
+			tolerant,
+			scientific,
+			quota,
+			mw.Statistics(opts.Store),
+		).Get("/fizzbuzz", h.FizzBuzz)
+		// POST takes the same parameters as a JSON body. They are moved into
+		// the query string before idempotency so a reused key with a
+		// different body is detected.
+		router.With(
+			timeout(cfg.FizzBuzzTimeout),
+			fizzBuzzBytes,
+			mw.JSONBodyToQuery(int64(cfg.MaxBodyBytes)),
+			idempotency,
+			mw.Presets(cfg.Presets),
+			mw.DefaultQueryParams(cfg.DefaultParams),
+			tolerant,
+			scientific,
+			quota,
+			mw.Statistics(opts.Store),
+		).Post("/fizzbuzz", h.FizzBuzz)
+		// HEAD runs the same validation and headers for monitoring probes
+		// but is not counted in statistics.
+		router.With(
+			timeout(cfg.FizzBuzzTimeout),
+			mw.Presets(cfg.Presets),
+			mw.DefaultQueryParams(cfg.DefaultParams),
+			tolerant,
+			scientific,
+		).Head("/fizzbuzz", h.FizzBuzz)
+		// Preflights are answered by the CORS middleware; any other OPTIONS
+		// request gets the allowed methods and a short description.
+		router.With(timeout(cfg.FizzBuzzTimeout)).Options("/fizzbuzz", h.FizzBuzzOptions)
+		router.With(timeout(cfg.StatisticsTimeout)).Get("/statistics", h.Statistics)
+		router.With(timeout(cfg.StatisticsTimeout)).Get("/statistics/limits", h.LimitStatistics)
+		router.With(timeout(cfg.StatisticsTimeout)).Get("/statistics/errors", h.FailureStatistics)
+		router.With(timeout(cfg.StatisticsTimeout)).Get("/statistics/export", h.ExportStatistics)
+		router.With(timeout(cfg.StatisticsTimeout)).Get("/statistics/breakdown", h.BreakdownStatistics)
+		router.With(timeout(cfg.StatisticsTimeout)).Get("/statistics/replay", h.ReplayStatistics)
+		router.With(timeout(cfg.StatisticsTimeout)).Get("/statistics/top", h.TopStatistics)
+		router.With(
+			timeout(cfg.StatisticsTimeout),
+			mw.Presets(cfg.Presets),
+			mw.DefaultQueryParams(cfg.DefaultParams),
+			tolerant,
+			scientific,
+		).Get("/statistics/count", h.CountStatistics)
+		router.With(timeout(cfg.StatisticsTimeout)).Get("/statistics/diff", h.DiffStatistics)
+		router.With(timeout(cfg.StatisticsTimeout)).Get("/statistics/words", h.WordsStatistics)
+		router.With(timeout(cfg.StatisticsTimeout)).Get("/statistics/sample", h.SampleStatistics)
+		// The live feed runs until the client leaves, so it has no handler
+		// timeout; write deadlines are extended per event instead.
+		router.Get("/statistics/stream", h.StreamStatistics)
+		preflight(router, "/statistics", "/statistics/limits", "/statistics/errors", "/statistics/export",
+			"/statistics/breakdown", "/statistics/replay", "/statistics/top", "/statistics/count", "/statistics/diff",
+			"/statistics/words", "/statistics/sample", "/statistics/stream")
+	})
+
+	routes.Group(func(router chi.Router) {
+		router.Use(cors.Handler(opsCORS))
+
+		router.With(timeout(cfg.HealthTimeout)).Get("/health", h.Health)
+		router.With(timeout(cfg.HealthTimeout)).Head("/health", h.Health)
+		router.With(timeout(cfg.HealthTimeout)).Get("/ready", h.Ready)
+		preflight(router, "/health", "/ready")
+		if cfg.AdminAPIKey != "" {
+			router.With(timeout(cfg.HealthTimeout), mw.RequireAPIKey(cfg.AdminAPIKey)).Post("/health/toggle", h.ToggleHealth)
+			router.With(timeout(cfg.HealthTimeout), mw.RequireAPIKey(cfg.AdminAPIKey)).Get("/health/detailed", h.DetailedHealth)
+			router.With(timeout(cfg.RequestTimeout), mw.RequireAPIKey(cfg.AdminAPIKey)).Post("/admin/maintenance", maintenance.Toggle)
+			preflight(router, "/health/toggle", "/health/detailed", "/admin/maintenance")
+		}
+		if opts.Metrics != nil {
+			router.With(timeout(cfg.RequestTimeout)).Method(http.MethodGet, "/metrics", opts.Metrics)
+			preflight(router, "/metrics")
+		}
+	})
+
+	return router
+}
+
+// corsOptions returns the CORS settings shared by both route groups, with
+// origins as the allow list.
+func corsOptions(origins []string) cors.Options {
+	return cors.Options{
+		AllowedOrigins:   origins,
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Request-ID", "Idempotency-Key"},
+		ExposedHeaders:   []string{"Link", "Idempotent-Replayed", "X-Content-SHA256"},
+		AllowCredentials: false,
+		MaxAge:           300,
+	}
+}
+
+// preflight registers OPTIONS on patterns. Group middleware only runs for
+// routes that exist, so without it chi would answer a preflight with 405
+// before the group's CORS handler saw it. OPTIONS requests that are not
+// preflights still get 405.
+func preflight(router chi.Router, patterns ...string) {
+	for _, pattern := range patterns {
+		router.Options(pattern, func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		})
+	}
+}
+
+func timeout(d time.Duration) func(http.Handler) http.Handler {
+	return chimiddleware.Timeout(d)
+}
@@ -0,0 +1,959 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/config"
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/handler"
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/metrics"
+	"github.com/Cerebrovinny/fizz-buzz-rest/internal/statistics"
+)
+
+// slowHandlers delays FizzBuzz and Health to exercise per-route timeouts.
+type slowHandlers struct {
+	*handler.Handler
+	delay time.Duration
+}
+
+func (s slowHandlers) FizzBuzz(w http.ResponseWriter, r *http.Request) {
+	if !s.wait(r) {
+		return
+	}
+	s.Handler.FizzBuzz(w, r)
+}
+
+func (s slowHandlers) Health(w http.ResponseWriter, r *http.Request) {
+	if !s.wait(r) {
+		return
+	}
+	s.Handler.Health(w, r)
+}
+
+func (s slowHandlers) wait(r *http.Request) bool {
+	select {
+	case <-time.After(s.delay):
+		return true
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+// panickingHandlers panics in Health to exercise panic recovery.
+type panickingHandlers struct {
+	*handler.Handler
+}
+
+func (panickingHandlers) Health(http.ResponseWriter, *http.Request) {
+	panic("health exploded")
+}
+
+func TestNewRouter_PanicsAreLoggedWithStack(t *testing.T) {
+	var logs bytes.Buffer
+	store := statistics.NewStore()
+	router := NewRouter(Options{
+		Config:   testConfig(),
+		Logger:   slog.New(slog.NewJSONHandler(&logs, nil)),
+		Store:    store,
+		Handlers: panickingHandlers{Handler: handler.NewHandler(store, nil)},
+	})
+
+	rec := serve(router, "/health")
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status %d, got %d", http.StatusInternalServerError, rec.Code)
+	}
+
+	var entry map[string]any
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("failed to decode log entry %q: %v", logs.String(), err)
+	}
+	if entry["panic"] != "health exploded" {
+		t.Fatalf("expected panic value in log entry, got %v", entry["panic"])
+	}
+	if stack, _ := entry["stack"].(string); !strings.Contains(stack, "panickingHandlers.Health") {
+		t.Fatalf("expected stack through the panicking handler, got %q", stack)
+	}
+}
+
+func TestNewRouter_PerRouteTimeouts(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		cfg := testConfig()
+		cfg.FizzBuzzTimeout = time.Second
+		cfg.HealthTimeout = 10 * time.Millisecond
+
+		store := statistics.NewStore()
+		router := NewRouter(Options{
+			Config:   cfg,
+			Store:    store,
+			Handlers: slowHandlers{Handler: handler.NewHandler(store, nil), delay: 100 * time.Millisecond},
+		})
+
+		rec := serve(router, "/fizzbuzz?int1=3&int2=5&limit=15&str1=fizz&str2=buzz")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected slow fizzbuzz to succeed with status %d, got %d", http.StatusOK, rec.Code)
+		}
+
+		rec = serve(router, "/health")
+		if rec.Code != http.StatusGatewayTimeout {
+			t.Fatalf("expected health to time out with status %d, got %d", http.StatusGatewayTimeout, rec.Code)
+		}
+	})
+}
+
+func TestNewRouter_RegistersRoutes(t *testing.T) {
+	store := statistics.NewStore()
+	router := NewRouter(Options{
+		Config:   testConfig(),
+		Store:    store,
+		Handlers: handler.NewHandler(store, nil),
+	})
+
+	tests := []struct {
+		target string
+		want   int
+	}{
+		{"/fizzbuzz?int1=3&int2=5&limit=15&str1=fizz&str2=buzz", http.StatusOK},
+		{"/statistics", http.StatusOK},
+		{"/statistics/limits", http.StatusOK},
+		{"/statistics/errors", http.StatusOK},
+		{"/statistics/export", http.StatusOK},
+		{"/statistics/breakdown", http.StatusOK},
+		{"/statistics/replay", http.StatusOK},
+		{"/statistics/top", http.StatusOK},
+		{"/statistics/count?int1=3&int2=5&limit=15&str1=fizz&str2=buzz", http.StatusOK},
+		{"/statistics/diff", http.StatusOK},
+		{"/statistics/words", http.StatusOK},
+		{"/statistics/sample?rate=0.5", http.StatusOK},
+		{"/health", http.StatusOK},
+		{"/ready", http.StatusOK},
+		{"/unknown", http.StatusNotFound},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.target, func(t *testing.T) {
+			if rec := serve(router, tc.target); rec.Code != tc.want {
+				t.Fatalf("expected status %d, got %d", tc.want, rec.Code)
+			}
+		})
+	}
+}
+
+func TestNewRouter_CLFAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := testConfig()
+	cfg.LogFormat = "clf"
+
+	store := statistics.NewStore()
+	router := NewRouter(Options{
+		Config:    cfg,
+		AccessLog: &buf,
+		Store:     store,
+		Handlers:  handler.NewHandler(store, nil),
+	})
+
+	serve(router, "/health")
+
+	if line := buf.String(); !strings.Contains(line, `"GET /health HTTP/1.1" 200 `) {
+		t.Fatalf("expected CLF access log line, got %q", line)
+	}
+}
+
+func TestNewRouter_TrustedProxiesIgnoreSpoofedHeaders(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := testConfig()
+	cfg.LogFormat = "clf"
+	cfg.TrustedProxies = []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+
+	store := statistics.NewStore()
+	router := NewRouter(Options{
+		Config:    cfg,
+		AccessLog: &buf,
+		Store:     store,
+		Handlers:  handler.NewHandler(store, nil),
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.RemoteAddr = "198.51.100.7:5555"
+	req.Header.Set("X-Forwarded-For", "1.2.3.4")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	if line := buf.String(); !strings.HasPrefix(line, "198.51.100.7 ") {
+		t.Fatalf("expected access log for untrusted peer address, got %q", line)
+	}
+}
+
+func TestNewRouter_HealthToggle(t *testing.T) {
+	cfg := testConfig()
+	cfg.AdminAPIKey = "s3cret"
+
+	store := statistics.NewStore()
+	router := NewRouter(Options{
+		Config:   cfg,
+		Store:    store,
+		Handlers: handler.NewHandler(store, nil),
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/health/toggle", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected unauthenticated toggle to return %d, got %d", http.StatusUnauthorized, rec.Code)
+	}
+	if rec := serve(router, "/health"); rec.Code != http.StatusOK {
+		t.Fatalf("expected health to stay %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/health/toggle", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected authenticated toggle to return %d, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+	if rec := serve(router, "/health"); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected health to report %d after toggle, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+}
+
+func TestNewRouter_DetailedHealthRequiresKey(t *testing.T) {
+	cfg := testConfig()
+	cfg.AdminAPIKey = "s3cret"
+
+	store := statistics.NewStore()
+	router := NewRouter(Options{
+		Config:   cfg,
+		Store:    store,
+		Handlers: handler.NewHandler(store, nil),
+	})
+
+	if rec := serve(router, "/health/detailed"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected unauthenticated detailed health to return %d, got %d", http.StatusUnauthorized, rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/health/detailed", nil)
+	req.Header.Set("X-API-Key", "s3cret")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected authenticated detailed health to return %d, got %d", http.StatusOK, rec.Code)
+	}
+	var body handler.DetailedHealthResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode detailed health: %v", err)
+	}
+	if body.Components["store"].Status != "ok" {
+		t.Fatalf("expected store component ok, got %+v", body.Components)
+	}
+
+	plain := serve(router, "/health")
+	if strings.Contains(plain.Body.String(), "components") {
+		t.Fatalf("expected /health to keep its minimal shape, got %s", plain.Body.String())
+	}
+}
+
+func TestNewRouter_DetailedHealthDisabledWithoutKey(t *testing.T) {
+	store := statistics.NewStore()
+	router := NewRouter(Options{
+		Config:   testConfig(),
+		Store:    store,
+		Handlers: handler.NewHandler(store, nil),
+	})
+
+	if rec := serve(router, "/health/detailed"); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected detailed health to be unrouted, got %d", rec.Code)
+	}
+}
+
+func TestNewRouter_HealthToggleDisabledWithoutKey(t *testing.T) {
+	store := statistics.NewStore()
+	router := NewRouter(Options{
+		Config:   testConfig(),
+		Store:    store,
+		Handlers: handler.NewHandler(store, nil),
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/health/toggle", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code == http.StatusOK || rec.Code == http.StatusServiceUnavailable {
+		t.Fatalf("expected toggle route to be unavailable, got %d", rec.Code)
+	}
+}
+
+func TestNewRouter_Head(t *testing.T) {
+	store := statistics.NewStore()
+	router := NewRouter(Options{
+		Config:   testConfig(),
+		Store:    store,
+		Handlers: handler.NewHandler(store, nil),
+	})
+
+	tests := []struct {
+		name       string
+		target     string
+		wantStatus int
+		wantLength string
+	}{
+		{"valid fizzbuzz", "/fizzbuzz?int1=3&int2=5&limit=5&str1=fizz&str2=buzz", http.StatusOK, "38"},
+		{"invalid fizzbuzz", "/fizzbuzz?int1=abc&int2=5&limit=5&str1=fizz&str2=buzz", http.StatusBadRequest, "54"},
+		{"health", "/health", http.StatusOK, "40"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodHead, tc.target, nil)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("expected status %d, got %d", tc.wantStatus, rec.Code)
+			}
+			if rec.Body.Len() != 0 {
+				t.Fatalf("expected no body, got %q", rec.Body.String())
+			}
+			if got := rec.Header().Get("Content-Type"); got != "application/json" {
+				t.Fatalf("expected Content-Type application/json, got %q", got)
+			}
+			if got := rec.Header().Get("Content-Length"); got != tc.wantLength {
+				t.Fatalf("expected Content-Length %s, got %q", tc.wantLength, got)
+			}
+		})
+	}
+
+	if _, ok := store.GetMostFrequent(); ok {
+		t.Fatal("expected HEAD requests not to be recorded in statistics")
+	}
+}
+
+func TestNewRouter_CountsFizzBuzzBytes(t *testing.T) {
+	store := statistics.NewStore()
+	registry := metrics.NewRegistry()
+	router := NewRouter(Options{
+		Config:   testConfig(),
+		Store:    store,
+		Metrics:  registry,
+		Handlers: handler.NewHandler(store, nil),
+	})
+
+	var want int
+	for _, target := range []string{
+		"/fizzbuzz?int1=3&int2=5&limit=5&str1=fizz&str2=buzz",
+		"/fizzbuzz?int1=3&int2=5&limit=15&str1=fizz&str2=buzz",
+		"/fizzbuzz?int1=abc",
+	} {
+		want += serve(router, target).Body.Len()
+	}
+	serve(router, "/health")
+
+	body := serve(router, "/metrics").Body.String()
+	if line := fmt.Sprintf("fizzbuzz_response_bytes_total %d\n", want); !strings.Contains(body, line) {
+		t.Fatalf("expected %q in metrics output, got:\n%s", line, body)
+	}
+}
+
+func TestNewRouter_MaintenanceMode(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaintenanceMode = true
+	cfg.AdminAPIKey = "s3cret"
+
+	store := statistics.NewStore()
+	router := NewRouter(Options{
+		Config:   cfg,
+		Store:    store,
+		Handlers: handler.NewHandler(store, nil),
+	})
+
+	rec := serve(router, "/fizzbuzz?int1=3&int2=5&limit=15&str1=fizz&str2=buzz")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("fizzbuzz: expected status %d, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+	if got, want := rec.Body.String(), `{"error":"service under maintenance"}`; got != want {
+		t.Fatalf("fizzbuzz: expected body %s, got %s", want, got)
+	}
+	if rec := serve(router, "/health"); rec.Code != http.StatusOK {
+		t.Fatalf("health: expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/maintenance", nil)
+	req.Header.Set("X-API-Key", "s3cret")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if got, want := rec.Body.String(), `{"maintenance":false}`; got != want {
+		t.Fatalf("toggle: expected body %s, got %s", want, got)
+	}
+
+	if rec := serve(router, "/fizzbuzz?int1=3&int2=5&limit=15&str1=fizz&str2=buzz"); rec.Code != http.StatusOK {
+		t.Fatalf("fizzbuzz after toggle: expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+}
+
+func TestNewRouter_DefaultParams(t *testing.T) {
+	cfg := testConfig()
+	cfg.DefaultParams = map[string]string{"int1": "3", "int2": "5", "str1": "fizz", "str2": "buzz"}
+
+	store := statistics.NewStore()
+	router := NewRouter(Options{
+		Config:   cfg,
+		Store:    store,
+		Handlers: handler.NewHandler(store, nil),
+	})
+
+	rec := serve(router, "/fizzbuzz?limit=5")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("defaulted request: expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if got, want := strings.TrimSpace(rec.Body.String()), `{"result":["1","2","fizz","4","buzz"]}`; got != want {
+		t.Fatalf("defaulted request: body = %s, want %s", got, want)
+	}
+
+	stats, ok := store.GetMostFrequent()
+	if !ok || stats.Params != (statistics.RequestParams{Int1: 3, Int2: 5, Limit: 5, Str1: "fizz", Str2: "buzz"}) {
+		t.Fatalf("expected the defaulted params to be recorded, got %+v", stats)
+	}
+
+	if rec := serve(router, "/fizzbuzz?str1=foo"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("limit without default: expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	rec = serve(router, "/fizzbuzz?rule=3:fizz&rule=5:buzz&limit=5")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("rule pairs: expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if got, want := strings.TrimSpace(rec.Body.String()), `{"result":["1","2","fizz","4","buzz"]}`; got != want {
+		t.Fatalf("rule pairs: body = %s, want %s", got, want)
+	}
+}
+
+func TestNewRouter_Presets(t *testing.T) {
+	cfg := testConfig()
+	cfg.Presets = map[string]map[string]string{"classic": {"int1": "3", "int2": "5", "str1": "fizz", "str2": "buzz"}}
+	cfg.DefaultParams = map[string]string{"str1": "foo"}
+
+	store := statistics.NewStore()
+	router := NewRouter(Options{
+		Config:   cfg,
+		Store:    store,
+		Handlers: handler.NewHandler(store, nil),
+	})
+
+	tests := []struct {
+		target     string
+		wantStatus int
+		wantBody   string
+	}{
+		{"/fizzbuzz?preset=classic&limit=5", http.StatusOK, `{"result":["1","2","fizz","4","buzz"]}`},
+		{"/fizzbuzz?preset=classic&limit=5&str2=bar", http.StatusOK, `{"result":["1","2","fizz","4","bar"]}`},
+		{"/fizzbuzz?preset=jazz&limit=5", http.StatusBadRequest, `{"error":"preset must be one of: classic"}`},
+	}
+	for _, tc := range tests {
+		rec := serve(router, tc.target)
+		if rec.Code != tc.wantStatus {
+			t.Fatalf("%s: expected status %d, got %d: %s", tc.target, tc.wantStatus, rec.Code, rec.Body.String())
+		}
+		if got := strings.TrimSpace(rec.Body.String()); got != tc.wantBody {
+			t.Fatalf("%s: body = %s, want %s", tc.target, got, tc.wantBody)
+		}
+	}
+
+	if hits := store.Get(statistics.RequestParams{Int1: 3, Int2: 5, Limit: 5, Str1: "fizz", Str2: "buzz"}); hits != 1 {
+		t.Fatalf("expected the expanded preset to be recorded once, got %d hits", hits)
+	}
+}
+
+func TestNewRouter_ScientificLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		allow      bool
+		target     string
+		wantStatus int
+		wantBody   string
+	}{
+		{"rejected by default", false, "/fizzbuzz?int1=3&int2=5&limit=5e0&str1=fizz&str2=buzz", http.StatusBadRequest,
+			`{"error":"limit must be a plain integer (scientific notation not allowed)"}`},
+		{"accepted when allowed", true, "/fizzbuzz?int1=3&int2=5&limit=5e0&str1=fizz&str2=buzz", http.StatusOK,
+			`{"result":["1","2","fizz","4","buzz"]}`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.AllowScientificLimit = tc.allow
+
+			store := statistics.NewStore()
+			router := NewRouter(Options{
+				Config:   cfg,
+				Store:    store,
+				Handlers: handler.NewHandler(store, nil),
+			})
+
+			rec := serve(router, tc.target)
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.wantStatus, rec.Code, rec.Body.String())
+			}
+			if got := strings.TrimSpace(rec.Body.String()); got != tc.wantBody {
+				t.Fatalf("body = %s, want %s", got, tc.wantBody)
+			}
+
+			wantHits := 0
+			if tc.allow {
+				wantHits = 1
+			}
+			if hits := store.Get(statistics.RequestParams{Int1: 3, Int2: 5, Limit: 5, Str1: "fizz", Str2: "buzz"}); hits != wantHits {
+				t.Fatalf("expected %d hits for limit=5, got %d", wantHits, hits)
+			}
+		})
+	}
+}
+
+func TestNewRouter_TolerantNumbers(t *testing.T) {
+	tests := []struct {
+		name       string
+		tolerant   bool
+		limit      string
+		wantStatus int
+	}{
+		{"comma rejected by default", false, "1,000", http.StatusBadRequest},
+		{"comma accepted when tolerant", true, "1,000", http.StatusOK},
+		{"underscore accepted when tolerant", true, "1_000", http.StatusOK},
+		{"plain accepted when tolerant", true, "1000", http.StatusOK},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.TolerantNumbers = tc.tolerant
+
+			store := statistics.NewStore()
+			router := NewRouter(Options{
+				Config:   cfg,
+				Store:    store,
+				Handlers: handler.NewHandler(store, nil),
+			})
+
+			rec := serve(router, "/fizzbuzz?int1=3&int2=5&limit="+tc.limit+"&str1=fizz&str2=buzz")
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.wantStatus, rec.Code, rec.Body.String())
+			}
+
+			wantHits := 0
+			if tc.wantStatus == http.StatusOK {
+				wantHits = 1
+			}
+			if hits := store.Get(statistics.RequestParams{Int1: 3, Int2: 5, Limit: 1000, Str1: "fizz", Str2: "buzz"}); hits != wantHits {
+				t.Fatalf("expected %d hits for limit=1000, got %d", wantHits, hits)
+			}
+		})
+	}
+}
+
+func TestNewRouter_ExtraResponseHeaders(t *testing.T) {
+	cfg := testConfig()
+	cfg.ExtraResponseHeaders = map[string]string{"X-Env": "prod", "X-Team": "platform"}
+
+	store := statistics.NewStore()
+	router := NewRouter(Options{
+		Config:   cfg,
+		Store:    store,
+		Handlers: handler.NewHandler(store, nil),
+	})
+
+	for _, target := range []string{
+		"/fizzbuzz?int1=3&int2=5&limit=15&str1=fizz&str2=buzz",
+		"/fizzbuzz?int1=0",
+		"/health",
+		"/missing",
+	} {
+		rec := serve(router, target)
+		if got := rec.Header().Get("X-Env"); got != "prod" {
+			t.Errorf("%s: X-Env = %q, want prod", target, got)
+		}
+		if got := rec.Header().Get("X-Team"); got != "platform" {
+			t.Errorf("%s: X-Team = %q, want platform", target, got)
+		}
+	}
+}
+
+func TestNewRouter_RequiredHeader(t *testing.T) {
+	cfg := testConfig()
+	cfg.RequiredHeader = "X-Gateway-Auth"
+
+	store := statistics.NewStore()
+	router := NewRouter(Options{
+		Config:   cfg,
+		Store:    store,
+		Handlers: handler.NewHandler(store, nil),
+	})
+
+	tests := []struct {
+		name       string
+		target     string
+		header     string
+		wantStatus int
+	}{
+		{"fizzbuzz with header", "/fizzbuzz?int1=3&int2=5&limit=15&str1=fizz&str2=buzz", "token", http.StatusOK},
+		{"fizzbuzz without header", "/fizzbuzz?int1=3&int2=5&limit=15&str1=fizz&str2=buzz", "", http.StatusBadRequest},
+		{"statistics without header", "/statistics", "", http.StatusBadRequest},
+		{"health without header", "/health", "", http.StatusOK},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.target, nil)
+			if tc.header != "" {
+				req.Header.Set("X-Gateway-Auth", tc.header)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.wantStatus, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestNewRouter_TrailingSlash(t *testing.T) {
+	tests := []struct {
+		mode         string
+		prefix       string
+		target       string
+		wantStatus   int
+		wantLocation string
+	}{
+		{mode: "", target: "/health/", wantStatus: http.StatusNotFound},
+		{mode: "strict", target: "/health/", wantStatus: http.StatusNotFound},
+		{mode: "strict", target: "/health", wantStatus: http.StatusOK},
+		{mode: "strip", target: "/health/", wantStatus: http.StatusOK},
+		{mode: "strip", target: "/health", wantStatus: http.StatusOK},
+		{mode: "strip", prefix: "/api/v1", target: "/api/v1/health/", wantStatus: http.StatusOK},
+		{mode: "redirect", target: "/health/", wantStatus: http.StatusMovedPermanently, wantLocation: "//example.com/health"},
+		{mode: "redirect", target: "/health", wantStatus: http.StatusOK},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.mode+tc.prefix+tc.target, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.TrailingSlash = tc.mode
+			cfg.RoutePrefix = tc.prefix
+			store := statistics.NewStore()
+			router := NewRouter(Options{
+				Config:   cfg,
+				Store:    store,
+				Handlers: handler.NewHandler(store, nil),
+			})
+
+			rec := serve(router, tc.target)
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("expected status %d, got %d", tc.wantStatus, rec.Code)
+			}
+			if got := rec.Header().Get("Location"); got != tc.wantLocation {
+				t.Fatalf("Location = %q, want %q", got, tc.wantLocation)
+			}
+		})
+	}
+}
+
+func TestNewRouter_RoutePrefix(t *testing.T) {
+	cfg := testConfig()
+	cfg.RoutePrefix = "/api/v1"
+	cfg.MaintenanceMode = true
+
+	store := statistics.NewStore()
+	router := NewRouter(Options{
+		Config:   cfg,
+		Store:    store,
+		Handlers: handler.NewHandler(store, nil),
+	})
+
+	tests := []struct {
+		target     string
+		wantStatus int
+	}{
+		{"/api/v1/health", http.StatusOK},
+		{"/api/v1/fizzbuzz?int1=3&int2=5&limit=15&str1=fizz&str2=buzz", http.StatusServiceUnavailable},
+	}
+	for _, tc := range tests {
+		if rec := serve(router, tc.target); rec.Code != tc.wantStatus {
+			t.Fatalf("%s: expected status %d, got %d", tc.target, tc.wantStatus, rec.Code)
+		}
+	}
+
+	cfg.MaintenanceMode = false
+	router = NewRouter(Options{
+		Config:   cfg,
+		Store:    store,
+		Handlers: handler.NewHandler(store, nil),
+	})
+	for _, tc := range []struct {
+		target     string
+		wantStatus int
+	}{
+		{"/api/v1/fizzbuzz?int1=3&int2=5&limit=15&str1=fizz&str2=buzz", http.StatusOK},
+		{"/api/v1/statistics", http.StatusOK},
+		{"/api/v1/ready", http.StatusOK},
+		{"/fizzbuzz?int1=3&int2=5&limit=15&str1=fizz&str2=buzz", http.StatusNotFound},
+		{"/statistics", http.StatusNotFound},
+		{"/health", http.StatusNotFound},
+	} {
+		if rec := serve(router, tc.target); rec.Code != tc.wantStatus {
+			t.Fatalf("%s: expected status %d, got %d", tc.target, tc.wantStatus, rec.Code)
+		}
+	}
+}
+
+func TestNewRouter_CompressResponses(t *testing.T) {
+	cfg := testConfig()
+	cfg.CompressResponses = true
+
+	store := statistics.NewStore()
+	router := NewRouter(Options{
+		Config:   cfg,
+		Store:    store,
+		Handlers: handler.NewHandler(store, nil),
+	})
+
+	for _, target := range []string{"/fizzbuzz?int1=3&int2=5&limit=15&str1=fizz&str2=buzz", "/health"} {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		want := ""
+		if strings.HasPrefix(target, "/fizzbuzz") {
+			want = "gzip"
+		}
+		if got := rec.Header().Get("Content-Encoding"); got != want {
+			t.Fatalf("%s: Content-Encoding = %q, want %q", target, got, want)
+		}
+		if want == "" {
+			continue
+		}
+		zr, err := gzip.NewReader(rec.Body)
+		if err != nil {
+			t.Fatalf("gzip.NewReader() error = %v", err)
+		}
+		plain, _ := io.ReadAll(zr)
+		if !strings.HasSuffix(string(plain), `"fizzbuzz"]}`) {
+			t.Fatalf("unexpected decompressed body %s", plain)
+		}
+	}
+}
+
+func TestNewRouter_CompressResponses_IdempotentReplay(t *testing.T) {
+	cfg := testConfig()
+	cfg.CompressResponses = true
+
+	store := statistics.NewStore()
+	router := NewRouter(Options{
+		Config:   cfg,
+		Store:    store,
+		Handlers: handler.NewHandler(store, nil),
+	})
+
+	const body = `{"result":["1","2","fizz"]}`
+	for i, acceptEncoding := range []string{"gzip", "", "gzip", ""} {
+		req := httptest.NewRequest(http.MethodGet, "/fizzbuzz?int1=3&int2=5&limit=3&str1=fizz&str2=buzz", nil)
+		req.Header.Set("Idempotency-Key", "gzip-key")
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("request %d: expected status %d, got %d", i, http.StatusOK, rec.Code)
+		}
+		if got := rec.Header().Get("Content-Encoding"); got != acceptEncoding {
+			t.Fatalf("request %d: Content-Encoding = %q, want %q", i, got, acceptEncoding)
+		}
+		plain := rec.Body.Bytes()
+		if acceptEncoding == "gzip" {
+			zr, err := gzip.NewReader(rec.Body)
+			if err != nil {
+				t.Fatalf("request %d: gzip.NewReader() error = %v", i, err)
+			}
+			plain, _ = io.ReadAll(zr)
+		}
+		if string(plain) != body {
+			t.Fatalf("request %d: body = %s, want %s", i, plain, body)
+		}
+	}
+
+	if hits := store.Get(statistics.RequestParams{Int1: 3, Int2: 5, Limit: 3, Str1: "fizz", Str2: "buzz"}); hits != 1 {
+		t.Fatalf("expected one recorded hit, got %d", hits)
+	}
+}
+
+func TestNewRouter_StatisticsStream(t *testing.T) {
+	store := statistics.NewStore()
+	store.Record(statistics.RequestParams{Int1: 3, Int2: 5, Limit: 15, Str1: "fizz", Str2: "buzz"})
+	router := NewRouter(Options{
+		Config:   testConfig(),
+		Store:    store,
+		Handlers: handler.NewHandler(store, nil),
+	})
+
+	// An already-cancelled request receives the initial event and returns.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequestWithContext(ctx, http.MethodGet, "/statistics/stream", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if !rec.Flushed {
+		t.Fatal("expected the event to be flushed through the middleware")
+	}
+	if !strings.HasPrefix(rec.Body.String(), "event: statistics\ndata: ") {
+		t.Fatalf("expected a statistics event, got %q", rec.Body.String())
+	}
+}
+
+func TestNewRouter_PostFizzBuzz(t *testing.T) {
+	store := statistics.NewStore()
+	router := NewRouter(Options{
+		Config:   testConfig(),
+		Store:    store,
+		Handlers: handler.NewHandler(store, nil),
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/fizzbuzz",
+		strings.NewReader(`{"int1": 3, "int2": 5, "limit": 5, "str1": "fizz", "str2": "buzz"}`))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if got, want := rec.Body.String(), `{"result":["1","2","fizz","4","buzz"]}`; got != want {
+		t.Fatalf("expected body %s, got %s", want, got)
+	}
+	stats, ok := store.GetMostFrequent()
+	if !ok || stats.Params.Limit != 5 || stats.Hits != 1 {
+		t.Fatalf("expected the POST to be recorded, got %+v", stats)
+	}
+
+	// An idempotency key reused with a different body is a different request.
+	for i, body := range []string{`{"limit": 5}`, `{"limit": 6}`} {
+		req := httptest.NewRequest(http.MethodPost, "/fizzbuzz?int1=3&int2=5&str1=fizz&str2=buzz", strings.NewReader(body))
+		req.Header.Set("Idempotency-Key", "post-key")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		want := []int{http.StatusOK, http.StatusUnprocessableEntity}[i]
+		if rec.Code != want {
+			t.Fatalf("request %d: expected status %d, got %d", i, want, rec.Code)
+		}
+	}
+}
+
+func TestNewRouter_CORSPerRouteGroup(t *testing.T) {
+	cfg := testConfig()
+	cfg.CORSAllowedOrigins = []string{"https://app.example.com"}
+	cfg.OpsCORSOrigins = []string{"https://ops.example.com"}
+
+	router := NewRouter(Options{
+		Config:   cfg,
+		Store:    statistics.NewStore(),
+		Metrics:  metrics.NewRegistry(),
+		Handlers: handler.NewHandler(statistics.NewStore(), nil),
+	})
+
+	tests := []struct {
+		path   string
+		origin string
+		want   string
+	}{
+		{path: "/fizzbuzz", origin: "https://app.example.com", want: "https://app.example.com"},
+		{path: "/fizzbuzz", origin: "https://ops.example.com", want: ""},
+		{path: "/statistics/top", origin: "https://app.example.com", want: "https://app.example.com"},
+		{path: "/metrics", origin: "https://ops.example.com", want: "https://ops.example.com"},
+		{path: "/metrics", origin: "https://app.example.com", want: ""},
+		{path: "/health", origin: "https://ops.example.com", want: "https://ops.example.com"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.path+" from "+tc.origin, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, tc.path, nil)
+			req.Header.Set("Origin", tc.origin)
+			req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tc.want {
+				t.Fatalf("Access-Control-Allow-Origin = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewRouter_FizzBuzzOptions(t *testing.T) {
+	router := NewRouter(Options{
+		Config:   testConfig(),
+		Store:    statistics.NewStore(),
+		Handlers: handler.NewHandler(statistics.NewStore(), nil),
+	})
+
+	req := httptest.NewRequest(http.MethodOptions, "/fizzbuzz", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if got, want := rec.Header().Get("Allow"), "GET, HEAD, POST, OPTIONS"; got != want {
+		t.Fatalf("Allow = %q, want %q", got, want)
+	}
+	if !strings.Contains(rec.Body.String(), `"methods":["GET","HEAD","POST","OPTIONS"]`) {
+		t.Fatalf("expected methods in body, got %s", rec.Body.String())
+	}
+
+	// A preflight is still answered by the CORS middleware alone.
+	req = httptest.NewRequest(http.MethodOptions, "/fizzbuzz", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Header().Get("Allow") != "" || rec.Body.Len() != 0 {
+		t.Fatalf("expected a bare preflight response, got Allow %q and body %s", rec.Header().Get("Allow"), rec.Body.String())
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Fatalf("Access-Control-Allow-Origin = %q, want %q", got, "*")
+	}
+}
+
+func testConfig() *config.Config {
+	return &config.Config{
+		RequestTimeout:     time.Second,
+		FizzBuzzTimeout:    time.Second,
+		StatisticsTimeout:  time.Second,
+		HealthTimeout:      time.Second,
+		IdempotencyTTL:     time.Minute,
+		CORSAllowedOrigins: []string{"*"},
+		OpsCORSOrigins:     []string{"*"},
+		MaxBodyBytes:       1024,
+	}
+}
+
+func serve(h http.Handler, target string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
@@ -0,0 +1,114 @@
+package statistics
+
+import (
+	"cmp"
+	"container/heap"
+	"slices"
+	"strings"
+)
+
+// valueCount is one tracked value of a request parameter.
+type valueCount[K comparable] struct {
+	value K
+	hits  int
+}
+
+// valueCounts counts hits per value of one request parameter. With a
+// positive max it keeps at most max values: a new value past the bound
+// evicts the least counted one, so a flood of one-off values cannot grow it
+// without limit while frequently requested values stay. The values form a
+// min-heap on hits, making both updates and evictions logarithmic. Callers
+// hold the store lock.
+type valueCounts[K comparable] struct {
+	max   int
+	index map[K]int
+	heap  []valueCount[K]
+}
+
+func newValueCounts[K comparable](max int) *valueCounts[K] {
+	return &valueCounts[K]{max: max, index: make(map[K]int)}
+}
+
+// add counts hits more for value.
+func (c *valueCounts[K]) add(value K, hits int) {
+	if i, ok := c.index[value]; ok {
+		c.heap[i].hits += hits
+		heap.Fix(c, i)
+		return
+	}
+	if c.max > 0 && len(c.heap) >= c.max {
+		heap.Pop(c)
+	}
+	heap.Push(c, valueCount[K]{value: value, hits: hits})
+}
+
+// clone returns the counts as a map the caller owns.
+func (c *valueCounts[K]) clone() map[K]int {
+	counts := make(map[K]int, len(c.heap))
+	for _, entry := range c.heap {
+		counts[entry.value] = entry.hits
+	}
+	return counts
+}
+
+// topWords returns at most n words of c, most counted first with ties
+// ordered by word, and whether any were left out. extra holds hits counted
+// outside c, such as hot parameter sets, and is consumed. Only the n best
+// are kept while scanning, so the counts are never copied or sorted whole.
+func topWords(c *valueCounts[string], n int, extra map[string]int) ([]WordCount, bool) {
+	best := make([]WordCount, 0, min(n, len(c.heap)+len(extra))+1)
+	distinct := 0
+	consider := func(word string, hits int) {
+		distinct++
+		candidate := WordCount{Word: word, Hits: hits}
+		i, _ := slices.BinarySearchFunc(best, candidate, compareWordCounts)
+		if i >= n {
+			return
+		}
+		best = slices.Insert(best, i, candidate)
+		if len(best) > n {
+			best = best[:n]
+		}
+	}
+	for _, entry := range c.heap {
+		consider(entry.value, entry.hits+extra[entry.value])
+		delete(extra, entry.value)
+	}
+	for word, hits := range extra {
+		consider(word, hits)
+	}
+	return best, distinct > n
+}
+
+// compareWordCounts orders by hits, highest first, then by word.
+func compareWordCounts(a, b WordCount) int {
+	if c := cmp.Compare(b.Hits, a.Hits); c != 0 {
+		return c
+	}
+	return strings.Compare(a.Word, b.Word)
+}
+
+// Len, Less, Swap, Push and Pop implement heap.Interface; use add instead.
+
+func (c *valueCounts[K]) Len() int { return len(c.heap) }
+
+func (c *valueCounts[K]) Less(i, j int) bool { return c.heap[i].hits < c.heap[j].hits }
+
+func (c *valueCounts[K]) Swap(i, j int) {
+	c.heap[i], c.heap[j] = c.heap[j], c.heap[i]
+	c.index[c.heap[i].value] = i
+	c.index[c.heap[j].value] = j
+}
+
+func (c *valueCounts[K]) Push(x any) {
+	entry := x.(valueCount[K])
+	c.index[entry.value] = len(c.heap)
+	c.heap = append(c.heap, entry)
+}
+
+func (c *valueCounts[K]) Pop() any {
+	last := c.heap[len(c.heap)-1]
+	c.heap = c.heap[:len(c.heap)-1]
+	delete(c.index, last.value)
+	return last
+}
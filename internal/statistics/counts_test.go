@@ -0,0 +1,33 @@
+package statistics
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestValueCounts_EvictsLeastCounted(t *testing.T) {
+	counts := newValueCounts[string](2)
+	counts.add("fizz", 3)
+	counts.add("buzz", 1)
+	counts.add("bazz", 2)
+
+	if got, want := counts.clone(), map[string]int{"fizz": 3, "bazz": 2}; !maps.Equal(got, want) {
+		t.Fatalf("clone() = %v, want %v", got, want)
+	}
+
+	counts.add("bazz", 5)
+	counts.add("word", 1)
+	if got, want := counts.clone(), map[string]int{"bazz": 7, "word": 1}; !maps.Equal(got, want) {
+		t.Fatalf("clone() = %v, want %v", got, want)
+	}
+}
+
+func TestValueCounts_Unbounded(t *testing.T) {
+	counts := newValueCounts[int](0)
+	for i := range 100 {
+		counts.add(i, 1)
+	}
+	if got := len(counts.clone()); got != 100 {
+		t.Fatalf("len(clone()) = %d, want 100", got)
+	}
+}
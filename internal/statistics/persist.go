@@ -0,0 +1,151 @@
+package statistics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// snapshotVersion is bumped whenever the snapshot format changes
+// incompatibly, so an old file is rejected rather than misread.
+const snapshotVersion = 1
+
+type snapshot struct {
+	Version int             `json:"version"`
+	Entries []snapshotEntry `json:"entries"`
+}
+
+type snapshotEntry struct {
+	Int1  int64  `json:"int1"`
+	Int2  int64  `json:"int2"`
+	Limit int    `json:"limit"`
+	Str1  string `json:"str1"`
+	Str2  string `json:"str2"`
+	Hits  int    `json:"hits"`
+}
+
+// WriteSnapshot writes the per-parameter hit counts to w as JSON, in Entries
+// order. Failure counts and ranking weights are not included.
+func (s *Store) WriteSnapshot(w io.Writer) error {
+	entries := s.Entries()
+	snap := snapshot{Version: snapshotVersion, Entries: make([]snapshotEntry, 0, len(entries))}
+	for _, entry := range entries {
+		p := entry.Params
+		snap.Entries = append(snap.Entries, snapshotEntry{
+			Int1: p.Int1, Int2: p.Int2, Limit: p.Limit, Str1: p.Str1, Str2: p.Str2, Hits: entry.Hits,
+		})
+	}
+	return json.NewEncoder(w).Encode(snap)
+}
+
+// ReadSnapshot adds the hit counts of a snapshot written by WriteSnapshot to
+// the store. The snapshot is validated in full before anything is added.
+func (s *Store) ReadSnapshot(r io.Reader) error {
+	var snap snapshot
+	if err := json.NewDecoder(r).Decode(&snap); err != nil {
+		return fmt.Errorf("decode statistics snapshot: %w", err)
+	}
+	if snap.Version != snapshotVersion {
+		return fmt.Errorf("unsupported statistics snapshot version %d", snap.Version)
+	}
+	for _, entry := range snap.Entries {
+		if entry.Hits <= 0 {
+			return fmt.Errorf("statistics snapshot entry has %d hits", entry.Hits)
+		}
+	}
+
+	for _, entry := range snap.Entries {
+		s.add(RequestParams{Int1: entry.Int1, Int2: entry.Int2, Limit: entry.Limit, Str1: entry.Str1, Str2: entry.Str2}, entry.Hits)
+	}
+	return nil
+}
+
+// SaveFile writes a snapshot to path. It is written to a temporary file in
+// the same directory and renamed into place, so a crash part way through
+// leaves the previous snapshot intact.
+func (s *Store) SaveFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := s.WriteSnapshot(tmp); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// saveRetryBackoff is the wait before the first retry of a failed save. It
+// doubles before each further retry.
+const saveRetryBackoff = 100 * time.Millisecond
+
+// SaveWithRetry calls save and, while it fails, retries it up to retries
+// more times with exponential backoff, so a transient disk error does not
+// drop a snapshot. It stops waiting when ctx is done and returns the last
+// error.
+func SaveWithRetry(ctx context.Context, retries int, save func() error) error {
+	err := save()
+	backoff := saveRetryBackoff
+	for attempt := 0; err != nil && attempt < retries; attempt++ {
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff *= 2
+		err = save()
+	}
+	return err
+}
+
+// PreparePersistDir checks that the directory path will be saved in exists,
+// so a mistyped path fails at startup rather than at the first save. A
+// missing directory is created with its parents when create is true and is
+// an error otherwise.
+func PreparePersistDir(path string, create bool) error {
+	dir := filepath.Dir(path)
+	info, err := os.Stat(dir)
+	switch {
+	case err == nil && !info.IsDir():
+		return fmt.Errorf("statistics directory %s is not a directory", dir)
+	case err == nil:
+		return nil
+	case !errors.Is(err, fs.ErrNotExist):
+		return err
+	case !create:
+		return fmt.Errorf("statistics directory %s does not exist", dir)
+	}
+	return os.MkdirAll(dir, 0o755)
+}
+
+// LoadFile reads the snapshot at path into the store. A missing file is not
+// an error: there is simply nothing to restore yet.
+func (s *Store) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return s.ReadSnapshot(f)
+}
@@ -0,0 +1,228 @@
+package statistics
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/synctest"
+)
+
+func TestStore_SnapshotRoundTrip(t *testing.T) {
+	source := NewStore()
+	classic := createParams(3, 5, 100, "fizz", "buzz")
+	other := createParams(2, 7, 15, "foo", "bar")
+	for range 3 {
+		source.Record(classic)
+	}
+	source.Record(other)
+
+	var buf bytes.Buffer
+	if err := source.WriteSnapshot(&buf); err != nil {
+		t.Fatalf("WriteSnapshot() error = %v", err)
+	}
+
+	restored := NewStore()
+	restored.Record(other)
+	if err := restored.ReadSnapshot(&buf); err != nil {
+		t.Fatalf("ReadSnapshot() error = %v", err)
+	}
+
+	want := map[RequestParams]int{classic: 3, other: 2}
+	if got := restored.Clone(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("restored counts = %v, want %v", got, want)
+	}
+	if got := restored.LimitHistogram(); got[100] != 3 || got[15] != 2 {
+		t.Fatalf("restored limit histogram = %v", got)
+	}
+	if stats, ok := restored.GetMostFrequent(); !ok || stats.Params != classic || stats.Hits != 3 {
+		t.Fatalf("GetMostFrequent() = %+v, %v", stats, ok)
+	}
+}
+
+func TestStore_ReadSnapshotRejectsBadInput(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"not json", "hits"},
+		{"wrong version", `{"version": 2, "entries": []}`},
+		{"non-positive hits", `{"version": 1, "entries": [{"int1": 3, "int2": 5, "limit": 15, "str1": "a", "str2": "b", "hits": 0}]}`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store := NewStore()
+			if err := store.ReadSnapshot(strings.NewReader(tc.input)); err == nil {
+				t.Fatal("expected an error")
+			}
+			if store.Len() != 0 {
+				t.Fatalf("expected nothing restored, got %d entries", store.Len())
+			}
+		})
+	}
+}
+
+func TestStore_SaveAndLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.json")
+
+	empty := NewStore()
+	if err := empty.LoadFile(path); err != nil {
+		t.Fatalf("LoadFile() on a missing file error = %v", err)
+	}
+
+	source := NewStore()
+	source.Record(createParams(3, 5, 100, "fizz", "buzz"))
+	if err := source.SaveFile(path); err != nil {
+		t.Fatalf("SaveFile() error = %v", err)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Fatalf("expected only the snapshot in the directory, found %d entries", len(entries))
+	}
+
+	restored := NewStore()
+	if err := restored.LoadFile(path); err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	if !reflect.DeepEqual(restored.Clone(), source.Clone()) {
+		t.Fatalf("restored counts = %v, want %v", restored.Clone(), source.Clone())
+	}
+}
+
+func TestPreparePersistDir(t *testing.T) {
+	t.Run("creates a missing directory", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "nested", "stats")
+		path := filepath.Join(dir, "stats.json")
+
+		if err := PreparePersistDir(path, true); err != nil {
+			t.Fatalf("PreparePersistDir() error = %v", err)
+		}
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			t.Fatalf("expected %s to be created, stat error = %v", dir, err)
+		}
+		if err := NewStore().SaveFile(path); err != nil {
+			t.Fatalf("SaveFile() after PreparePersistDir error = %v", err)
+		}
+	})
+
+	t.Run("fails fast on a missing directory", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "missing")
+
+		err := PreparePersistDir(filepath.Join(dir, "stats.json"), false)
+		if err == nil || !strings.Contains(err.Error(), dir+" does not exist") {
+			t.Fatalf("PreparePersistDir() error = %v, want it to name %s", err, dir)
+		}
+		if _, err := os.Stat(dir); !errors.Is(err, fs.ErrNotExist) {
+			t.Fatalf("expected %s not to be created, stat error = %v", dir, err)
+		}
+	})
+
+	t.Run("existing directory", func(t *testing.T) {
+		for _, create := range []bool{false, true} {
+			if err := PreparePersistDir(filepath.Join(t.TempDir(), "stats.json"), create); err != nil {
+				t.Fatalf("PreparePersistDir(create=%t) error = %v", create, err)
+			}
+		}
+	})
+
+	t.Run("parent is a file", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "file")
+		if err := os.WriteFile(file, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := PreparePersistDir(filepath.Join(file, "stats.json"), true); err == nil {
+			t.Fatal("PreparePersistDir() under a file succeeded, want an error")
+		}
+	})
+}
+
+// flakyWriter fails its first failures writes.
+type flakyWriter struct {
+	failures int
+	buf      bytes.Buffer
+}
+
+func (w *flakyWriter) Write(p []byte) (int, error) {
+	if w.failures > 0 {
+		w.failures--
+		return 0, errors.New("disk unavailable")
+	}
+	return w.buf.Write(p)
+}
+
+func TestSaveWithRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		retries  int
+		wantErr  bool
+	}{
+		{"succeeds first time", 0, 0, false},
+		{"succeeds after transient failures", 2, 3, false},
+		{"gives up after retries", 4, 3, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			synctest.Test(t, func(t *testing.T) {
+				source := NewStore()
+				source.Record(createParams(3, 5, 100, "fizz", "buzz"))
+
+				w := &flakyWriter{failures: tc.failures}
+				attempts := 0
+				err := SaveWithRetry(t.Context(), tc.retries, func() error {
+					attempts++
+					w.buf.Reset()
+					return source.WriteSnapshot(w)
+				})
+
+				if tc.wantErr {
+					if err == nil {
+						t.Fatal("SaveWithRetry() error = nil, want error")
+					}
+					if attempts != tc.retries+1 {
+						t.Fatalf("expected %d attempts, got %d", tc.retries+1, attempts)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("SaveWithRetry() error = %v", err)
+				}
+				if attempts != tc.failures+1 {
+					t.Fatalf("expected %d attempts, got %d", tc.failures+1, attempts)
+				}
+
+				restored := NewStore()
+				if err := restored.ReadSnapshot(&w.buf); err != nil {
+					t.Fatalf("ReadSnapshot() error = %v", err)
+				}
+				if !reflect.DeepEqual(restored.Clone(), source.Clone()) {
+					t.Fatalf("restored counts = %v, want %v", restored.Clone(), source.Clone())
+				}
+			})
+		})
+	}
+}
+
+func TestSaveWithRetry_StopsWhenContextIsDone(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		attempts := 0
+		err := SaveWithRetry(ctx, 5, func() error {
+			attempts++
+			cancel()
+			return errors.New("disk unavailable")
+		})
+
+		if err == nil || attempts != 1 {
+			t.Fatalf("expected one failed attempt, got %d attempts and error %v", attempts, err)
+		}
+	})
+}
@@ -1,11 +1,21 @@
 package statistics
 
-import "sync"
+import (
+	"cmp"
+	"container/list"
+	"context"
+	"maps"
+	"math"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
 
 // RequestParams represents the parameters of a FizzBuzz request.
 type RequestParams struct {
-	Int1  int
-	Int2  int
+	Int1  int64
+	Int2  int64
 	Limit int
 	Str1  string
 	Str2  string
@@ -21,50 +31,506 @@ type Stats struct {
 type Store struct {
 	mu       sync.RWMutex
 	requests map[RequestParams]int
+	failures map[string]int
+
+	// Per-parameter aggregates. They survive the eviction of the parameter
+	// sets they count, but each is bounded by maxEntries on its own.
+	limits *valueCounts[int]
+	int1s  *valueCounts[int64]
+	int2s  *valueCounts[int64]
+	str1s  *valueCounts[string]
+	str2s  *valueCounts[string]
+
+	// maxEntries bounds len(requests) when positive. recency orders the
+	// tracked parameter sets from most to least recently recorded.
+	maxEntries int
+	recency    *list.List
+	elements   map[RequestParams]*list.Element
+
+	// leader is the highest ranked entry of requests, kept current on
+	// every add so GetMostFrequent need not scan. It is never evicted.
+	leader    RequestParams
+	hasLeader bool
+
+	// weights holds exponentially decayed hit counts used to rank requests
+	// when halfLife is positive; nil otherwise.
+	halfLife time.Duration
+	weights  map[RequestParams]float64
+
+	// hot holds lock-free counters for the parameter sets passed to
+	// WithHotParams. The map itself is built once and never modified, so it
+	// is read without holding mu.
+	hot map[RequestParams]*atomic.Int64
+}
+
+// Option configures optional Store behavior.
+type Option func(*Store)
+
+// WithMaxEntries bounds the number of distinct parameter sets tracked. Once
+// the bound is exceeded, the least recently recorded set is evicted and its
+// count is lost; the per-parameter aggregates keep its hits. The current
+// most frequent set is never evicted, so the answer to GetMostFrequent stays
+// correct unless a set is evicted and later re-recorded past it. The
+// aggregates are bounded too: each keeps at most n values, evicting the least
+// counted one. A value of zero or less leaves the store unbounded.
+func WithMaxEntries(n int) Option {
+	return func(s *Store) {
+		s.maxEntries = n
+	}
+}
+
+// WithDecay makes GetMostFrequent favor recent requests: once RunDecay is
+// running, every parameter set's weight halves each halfLife, so a burst of
+// old requests is eventually overtaken by a steady trickle of new ones.
+// Reported hit counts stay all-time totals. Zero or less disables decay.
+func WithDecay(halfLife time.Duration) Option {
+	return func(s *Store) {
+		s.halfLife = halfLife
+	}
+}
+
+// WithHotParams counts the given parameter sets with atomic counters instead
+// of under the store lock, for deployments where a few sets dominate traffic.
+// Readers merge the atomic counts into every view, but a read is no longer a
+// single snapshot: hot counts may advance between the locked and lock-free
+// parts of it. Hot sets are never evicted. The option is ignored together
+// with WithDecay, whose weights need the lock on every record.
+//
+// This is an experimental fast path.
+func WithHotParams(params ...RequestParams) Option {
+	return func(s *Store) {
+		s.hot = make(map[RequestParams]*atomic.Int64, len(params))
+		for _, p := range params {
+			s.hot[p] = new(atomic.Int64)
+		}
+	}
 }
 
 // NewStore returns an initialized Store instance.
-func NewStore() *Store {
-	return &Store{
+func NewStore(opts ...Option) *Store {
+	s := &Store{
 		requests: make(map[RequestParams]int),
+		failures: make(map[string]int),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.limits = newValueCounts[int](s.maxEntries)
+	s.int1s = newValueCounts[int64](s.maxEntries)
+	s.int2s = newValueCounts[int64](s.maxEntries)
+	s.str1s = newValueCounts[string](s.maxEntries)
+	s.str2s = newValueCounts[string](s.maxEntries)
+	if s.maxEntries > 0 {
+		s.recency = list.New()
+		s.elements = make(map[RequestParams]*list.Element)
 	}
+	if s.halfLife > 0 {
+		s.weights = make(map[RequestParams]float64)
+		s.hot = nil
+	}
+	return s
+}
+
+// decaySteps is the number of times per half-life RunDecay applies decay.
+const decaySteps = 10
+
+// RunDecay applies the decay configured with WithDecay until ctx is done. It
+// returns immediately when decay is disabled.
+func (s *Store) RunDecay(ctx context.Context) {
+	if s.halfLife <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(max(s.halfLife/decaySteps, time.Nanosecond))
+	defer ticker.Stop()
+	factor := math.Pow(0.5, 1.0/decaySteps)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Decay(factor)
+		}
+	}
+}
+
+// Decay multiplies every ranking weight by factor. It is a no-op unless the
+// store was created WithDecay.
+func (s *Store) Decay(factor float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for params := range s.weights {
+		s.weights[params] *= factor
+	}
+	// Scaling keeps the order in theory, but rounding may swap near ties.
+	s.electLeader()
 }
 
 // Record increments the hit counter for the provided parameters.
 func (s *Store) Record(params RequestParams) {
+	s.add(params, 1)
+}
+
+// add counts hits requests for params at once, as if each were recorded.
+func (s *Store) add(params RequestParams, hits int) {
+	if counter, ok := s.hot[params]; ok {
+		counter.Add(int64(hits))
+		return
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.requests[params]++
+	s.requests[params] += hits
+	s.limits.add(params.Limit, hits)
+	s.int1s.add(params.Int1, hits)
+	s.int2s.add(params.Int2, hits)
+	s.str1s.add(params.Str1, hits)
+	s.str2s.add(params.Str2, hits)
+	if s.weights != nil {
+		s.weights[params] += float64(hits)
+	}
+	// Scores only grow here, so params is the only entry that can have
+	// overtaken the leader.
+	if !s.hasLeader || outranks(s.score(params), params, s.score(s.leader), s.leader) {
+		s.leader, s.hasLeader = params, true
+	}
+
+	if s.maxEntries <= 0 {
+		return
+	}
+
+	if elem, ok := s.elements[params]; ok {
+		s.recency.MoveToFront(elem)
+	} else {
+		s.elements[params] = s.recency.PushFront(params)
+	}
+
+	if s.recency.Len() > s.maxEntries {
+		s.evictOldest()
+	}
+}
+
+// evictOldest drops the least recently recorded parameter set, skipping the
+// current most frequent one.
+func (s *Store) evictOldest() {
+	victim := s.recency.Back()
+	if victim.Value.(RequestParams) == s.leader {
+		victim = victim.Prev()
+	}
+	if victim == nil {
+		return
+	}
+
+	params := s.recency.Remove(victim).(RequestParams)
+	delete(s.elements, params)
+	delete(s.requests, params)
+	delete(s.weights, params)
+}
+
+// score is the ranking score of a tracked entry: its decayed weight with
+// decay enabled, its hit count otherwise. Callers hold mu.
+func (s *Store) score(params RequestParams) float64 {
+	if s.weights != nil {
+		return s.weights[params]
+	}
+	return float64(s.requests[params])
+}
+
+// outranks reports whether a, scoring aScore, ranks above b, scoring bScore.
+// Ties go to the parameter set CompareParams orders first, so the result
+// never depends on map iteration order.
+func outranks(aScore float64, a RequestParams, bScore float64, b RequestParams) bool {
+	if aScore != bScore {
+		return aScore > bScore
+	}
+	return CompareParams(a, b) < 0
+}
+
+// electLeader recomputes leader with a full scan. Callers hold mu for
+// writing.
+func (s *Store) electLeader() {
+	s.hasLeader = false
+	for params := range s.requests {
+		if !s.hasLeader || outranks(s.score(params), params, s.score(s.leader), s.leader) {
+			s.leader, s.hasLeader = params, true
+		}
+	}
 }
 
-// GetMostFrequent returns the most frequent request, if any exist.
+// Len returns the number of distinct parameter sets currently tracked.
+func (s *Store) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.requests) + len(s.hotCounts())
+}
+
+// Get returns the hit count recorded for params, or zero if it was never
+// recorded or has been evicted.
+func (s *Store) Get(params RequestParams) int {
+	if counter, ok := s.hot[params]; ok {
+		return int(counter.Load())
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.requests[params]
+}
+
+// hotCounts returns the hot parameter sets recorded at least once, for
+// readers to merge with the locked maps.
+func (s *Store) hotCounts() map[RequestParams]int {
+	var counts map[RequestParams]int
+	for params, counter := range s.hot {
+		if n := counter.Load(); n > 0 {
+			if counts == nil {
+				counts = make(map[RequestParams]int, len(s.hot))
+			}
+			counts[params] = int(n)
+		}
+	}
+	return counts
+}
+
+// RecordFailure increments the counter for a rejected request of the given kind.
+func (s *Store) RecordFailure(reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures[reason]++
+}
+
+// Failures returns a copy of the rejected request counts keyed by reason.
+func (s *Store) Failures() map[string]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	failures := make(map[string]int, len(s.failures))
+	for reason, count := range s.failures {
+		failures[reason] = count
+	}
+	return failures
+}
+
+// LimitHistogram returns a copy of the hit counts per requested limit,
+// aggregated across all other parameters.
+func (s *Store) LimitHistogram() map[int]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	histogram := s.limits.clone()
+	for params, hits := range s.hotCounts() {
+		histogram[params.Limit] += hits
+	}
+	return histogram
+}
+
+// Clone returns a copy of the hit counts keyed by parameter set, taken
+// atomically with respect to concurrent Record calls.
+func (s *Store) Clone() map[RequestParams]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	clone := make(map[RequestParams]int, len(s.requests))
+	for params, hits := range s.requests {
+		clone[params] = hits
+	}
+	maps.Copy(clone, s.hotCounts())
+	return clone
+}
+
+// Diff returns how many hits each parameter set gained since previous, a
+// snapshot taken earlier with Clone. See the package-level Diff.
+func (s *Store) Diff(previous map[RequestParams]int) map[RequestParams]int {
+	return Diff(s.Clone(), previous)
+}
+
+// Diff returns the per parameter set increase from previous to current. Sets
+// that did not grow are left out, including any evicted since previous, so
+// every value is positive.
+func Diff(current, previous map[RequestParams]int) map[RequestParams]int {
+	deltas := make(map[RequestParams]int)
+	for params, hits := range current {
+		if delta := hits - previous[params]; delta > 0 {
+			deltas[params] = delta
+		}
+	}
+	return deltas
+}
+
+// Entries returns a snapshot of every tracked parameter set with its hit
+// count, ordered by int1, int2, limit, str1, then str2.
+func (s *Store) Entries() []Stats {
+	s.mu.RLock()
+	entries := make([]Stats, 0, len(s.requests))
+	for params, hits := range s.requests {
+		entries = append(entries, Stats{Params: params, Hits: hits})
+	}
+	for params, hits := range s.hotCounts() {
+		entries = append(entries, Stats{Params: params, Hits: hits})
+	}
+	s.mu.RUnlock()
+
+	slices.SortFunc(entries, func(a, b Stats) int {
+		return CompareParams(a.Params, b.Params)
+	})
+	return entries
+}
+
+// CompareParams orders parameter sets by int1, int2, limit, str1, then str2,
+// returning -1, 0 or +1 like cmp.Compare.
+func CompareParams(a, b RequestParams) int {
+	return cmp.Or(
+		cmp.Compare(a.Int1, b.Int1),
+		cmp.Compare(a.Int2, b.Int2),
+		cmp.Compare(a.Limit, b.Limit),
+		cmp.Compare(a.Str1, b.Str1),
+		cmp.Compare(a.Str2, b.Str2),
+	)
+}
+
+// Ranked returns every tracked parameter set from most to least frequent,
+// ranked the same way as GetMostFrequent. Ties keep the Entries order.
+func (s *Store) Ranked() []Stats {
+	entries := s.Entries()
+
+	s.mu.RLock()
+	score := func(stats Stats) float64 {
+		if s.weights != nil {
+			return s.weights[stats.Params]
+		}
+		return float64(stats.Hits)
+	}
+	slices.SortStableFunc(entries, func(a, b Stats) int {
+		return cmp.Compare(score(b), score(a))
+	})
+	s.mu.RUnlock()
+
+	return entries
+}
+
+// Breakdown holds hit counts per value of each request parameter, counted
+// independently of the other parameters.
+type Breakdown struct {
+	Int1Counts  map[int64]int
+	Int2Counts  map[int64]int
+	LimitCounts map[int]int
+	Str1Counts  map[string]int
+	Str2Counts  map[string]int
+}
+
+// Breakdown returns a copy of the per-parameter hit counts.
+func (s *Store) Breakdown() Breakdown {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	breakdown := Breakdown{
+		Int1Counts:  s.int1s.clone(),
+		Int2Counts:  s.int2s.clone(),
+		LimitCounts: s.limits.clone(),
+		Str1Counts:  s.str1s.clone(),
+		Str2Counts:  s.str2s.clone(),
+	}
+	for params, hits := range s.hotCounts() {
+		breakdown.Int1Counts[params.Int1] += hits
+		breakdown.Int2Counts[params.Int2] += hits
+		breakdown.LimitCounts[params.Limit] += hits
+		breakdown.Str1Counts[params.Str1] += hits
+		breakdown.Str2Counts[params.Str2] += hits
+	}
+	return breakdown
+}
+
+// WordCount is how often a word was requested in one position.
+type WordCount struct {
+	Word string
+	Hits int
+}
+
+// TopWords returns at most n of the str1 and of the str2 values requested,
+// most requested first with ties ordered by word, and whether either list
+// was cut. Like the other per-parameter aggregates the counts survive
+// eviction.
+func (s *Store) TopWords(n int) (str1, str2 []WordCount, truncated bool) {
+	hot1, hot2 := make(map[string]int), make(map[string]int)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for params, hits := range s.hotCounts() {
+		hot1[params.Str1] += hits
+		hot2[params.Str2] += hits
+	}
+	str1, cut1 := topWords(s.str1s, n, hot1)
+	str2, cut2 := topWords(s.str2s, n, hot2)
+	return str1, str2, cut1 || cut2
+}
+
+// GetMostFrequent returns the most frequent request, if any exist. Ties go
+// to the parameter set CompareParams orders first, so it always agrees with
+// the head of Ranked.
 func (s *Store) GetMostFrequent() (*Stats, bool) {
+	return s.GetMostFrequentAtLeast(1)
+}
+
+// GetMostFrequentAtLeast returns the most frequent request among those seen at
+// least min times, if any qualify. With decay enabled, "most frequent" means
+// the highest decayed weight.
+func (s *Store) GetMostFrequentAtLeast(min int) (*Stats, bool) {
+	return s.GetMostFrequentMatching(min, nil)
+}
+
+// GetMostFrequentMatching is GetMostFrequentAtLeast restricted to the
+// parameter sets for which match returns true. A nil match accepts every
+// set. Without a match it compares only the tracked leader and the hot sets;
+// with one it scans the tracked sets once, under the read lock, so match
+// must be fast and must not call back into the store.
+func (s *Store) GetMostFrequentMatching(min int, match func(RequestParams) bool) (*Stats, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	var (
-		maxParams RequestParams
-		maxHits   int
+		best      Stats
+		bestScore float64
 		found     bool
 	)
-
-	for params, hits := range s.requests {
-		if !found || hits > maxHits {
-			maxParams = params
-			maxHits = hits
+	consider := func(params RequestParams, hits int) {
+		// Hot sets that were never recorded have zero hits.
+		if hits == 0 || hits < min {
+			return
+		}
+		if match != nil && !match(params) {
+			return
+		}
+		score := float64(hits)
+		if s.weights != nil {
+			score = s.weights[params]
+		}
+		if !found || outranks(score, params, bestScore, best.Params) {
+			best = Stats{Params: params, Hits: hits}
+			bestScore = score
 			found = true
 		}
 	}
 
-	if !found {
-		return nil, false
+	// The leader outranks every other tracked set, and without decay it
+	// also has the most hits, so below min it rules them all out. Only a
+	// filter, or a decayed leader with too few hits, needs the full scan.
+	if match == nil && s.hasLeader && (s.weights == nil || s.requests[s.leader] >= min) {
+		consider(s.leader, s.requests[s.leader])
+	} else {
+		for params, hits := range s.requests {
+			consider(params, hits)
+		}
 	}
-
-	result := Stats{
-		Params: maxParams,
-		Hits:   maxHits,
+	for params, counter := range s.hot {
+		consider(params, int(counter.Load()))
 	}
 
-	return &result, true
+	if !found {
+		return nil, false
+	}
+	return &best, true
 }
@@ -1,9 +1,14 @@
 package statistics
 
 import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"reflect"
 	"sync"
 	"testing"
 	"testing/synctest"
+	"time"
 )
 
 func TestStore_Record_Sequential(t *testing.T) {
@@ -243,6 +248,799 @@ func TestStore_MultipleRequests_FindMax(t *testing.T) {
 	}
 }
 
+func TestStore_GetMostFrequentAtLeast(t *testing.T) {
+	top := createParams(3, 5, 15, "fizz", "buzz")
+	lower := createParams(2, 7, 30, "foo", "bar")
+
+	tests := []struct {
+		name       string
+		min        int
+		wantParams RequestParams
+		wantHits   int
+		wantOK     bool
+	}{
+		{name: "default threshold returns top", min: 1, wantParams: top, wantHits: 8, wantOK: true},
+		{name: "threshold equal to top hits", min: 8, wantParams: top, wantHits: 8, wantOK: true},
+		{name: "threshold above every entry", min: 9, wantOK: false},
+		{name: "zero threshold behaves like default", min: 0, wantParams: top, wantHits: 8, wantOK: true},
+	}
+
+	store := NewStore()
+	for range 8 {
+		store.Record(top)
+	}
+	for range 3 {
+		store.Record(lower)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := store.GetMostFrequentAtLeast(tt.min)
+			if ok != tt.wantOK {
+				t.Fatalf("GetMostFrequentAtLeast(%d) ok = %v, want %v", tt.min, ok, tt.wantOK)
+			}
+			if !tt.wantOK {
+				if got != nil {
+					t.Fatalf("GetMostFrequentAtLeast(%d) = %+v, want nil", tt.min, got)
+				}
+				return
+			}
+			assertStats(t, got, tt.wantParams, tt.wantHits)
+		})
+	}
+}
+
+func TestStore_GetMostFrequentMatching(t *testing.T) {
+	top := createParams(3, 5, 15, "fizz", "buzz")
+	foo := createParams(2, 7, 30, "foo", "bar")
+	fooHot := createParams(2, 7, 45, "foo", "baz")
+
+	store := NewStore(WithHotParams(fooHot))
+	for range 8 {
+		store.Record(top)
+	}
+	for range 3 {
+		store.Record(foo)
+	}
+	for range 5 {
+		store.Record(fooHot)
+	}
+
+	str1 := func(want string) func(RequestParams) bool {
+		return func(params RequestParams) bool { return params.Str1 == want }
+	}
+
+	tests := []struct {
+		name       string
+		min        int
+		match      func(RequestParams) bool
+		wantParams RequestParams
+		wantHits   int
+		wantOK     bool
+	}{
+		{name: "nil match considers everything", min: 1, wantParams: top, wantHits: 8, wantOK: true},
+		{name: "match narrows to hot set", min: 1, match: str1("foo"), wantParams: fooHot, wantHits: 5, wantOK: true},
+		{name: "match and threshold", min: 6, match: str1("foo"), wantOK: false},
+		{name: "nothing matches", min: 1, match: str1("none"), wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := store.GetMostFrequentMatching(tt.min, tt.match)
+			if ok != tt.wantOK {
+				t.Fatalf("GetMostFrequentMatching ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !tt.wantOK {
+				return
+			}
+			assertStats(t, got, tt.wantParams, tt.wantHits)
+		})
+	}
+}
+
+func TestStore_Get(t *testing.T) {
+	recorded := RequestParams{Int1: 3, Int2: 5, Limit: 15, Str1: "fizz", Str2: "buzz"}
+	hot := RequestParams{Int1: 2, Int2: 7, Limit: 10, Str1: "foo", Str2: "bar"}
+	store := NewStore(WithHotParams(hot))
+	for range 3 {
+		store.Record(recorded)
+	}
+	store.Record(hot)
+
+	if got := store.Get(recorded); got != 3 {
+		t.Errorf("Get(recorded) = %d, want 3", got)
+	}
+	if got := store.Get(hot); got != 1 {
+		t.Errorf("Get(hot) = %d, want 1", got)
+	}
+	if got := store.Get(RequestParams{Int1: 1, Int2: 1, Limit: 1, Str1: "a", Str2: "b"}); got != 0 {
+		t.Errorf("Get(unrecorded) = %d, want 0", got)
+	}
+}
+
+func TestStore_LimitHistogram(t *testing.T) {
+	store := NewStore()
+	store.Record(createParams(3, 5, 15, "fizz", "buzz"))
+	store.Record(createParams(3, 5, 15, "fizz", "buzz"))
+	store.Record(createParams(2, 7, 15, "foo", "bar"))
+	store.Record(createParams(3, 5, 100, "fizz", "buzz"))
+
+	got := store.LimitHistogram()
+	want := map[int]int{15: 3, 100: 1}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("LimitHistogram() = %v, want %v", got, want)
+	}
+
+	got[15] = 0
+	if again := store.LimitHistogram(); again[15] != 3 {
+		t.Fatalf("LimitHistogram() returned shared map, got %v after mutation", again)
+	}
+}
+
+func TestStore_Words(t *testing.T) {
+	store := NewStore(WithHotParams(createParams(3, 5, 15, "fizz", "buzz")))
+	store.Record(createParams(3, 5, 15, "fizz", "buzz"))
+	store.Record(createParams(3, 5, 100, "fizz", "buzz"))
+	store.Record(createParams(2, 7, 15, "foo", "buzz"))
+
+	str1, str2, truncated := store.TopWords(10)
+	if want := []WordCount{{"fizz", 2}, {"foo", 1}}; !reflect.DeepEqual(str1, want) {
+		t.Fatalf("TopWords() str1 = %v, want %v", str1, want)
+	}
+	if want := []WordCount{{"buzz", 3}}; !reflect.DeepEqual(str2, want) {
+		t.Fatalf("TopWords() str2 = %v, want %v", str2, want)
+	}
+	if truncated {
+		t.Fatal("TopWords() reported truncation for short lists")
+	}
+}
+
+func TestStore_TopWords_KeepsTheMostRequested(t *testing.T) {
+	store := NewStore()
+	for i, word := range []string{"b", "a", "c", "d", "a", "c", "a"} {
+		store.Record(createParams(3, 5, i+1, word, "buzz"))
+	}
+
+	str1, _, truncated := store.TopWords(2)
+	if want := []WordCount{{"a", 3}, {"c", 2}}; !reflect.DeepEqual(str1, want) {
+		t.Fatalf("TopWords(2) str1 = %v, want %v", str1, want)
+	}
+	if !truncated {
+		t.Fatal("expected TopWords(2) to report truncation")
+	}
+
+	// Ties are ordered by word.
+	if str1, _, _ := store.TopWords(4); !reflect.DeepEqual(str1[2:], []WordCount{{"b", 1}, {"d", 1}}) {
+		t.Fatalf("TopWords(4) str1 = %v, want ties ordered by word", str1)
+	}
+}
+
+func TestStore_Entries(t *testing.T) {
+	store := NewStore()
+	store.Record(createParams(3, 5, 15, "fizz", "buzz"))
+	store.Record(createParams(3, 5, 15, "fizz", "buzz"))
+	store.Record(createParams(2, 7, 15, "foo", "bar"))
+	store.Record(createParams(3, 5, 15, "a", "b"))
+	store.Record(createParams(3, 5, 10, "fizz", "buzz"))
+
+	want := []Stats{
+		{Params: createParams(2, 7, 15, "foo", "bar"), Hits: 1},
+		{Params: createParams(3, 5, 10, "fizz", "buzz"), Hits: 1},
+		{Params: createParams(3, 5, 15, "a", "b"), Hits: 1},
+		{Params: createParams(3, 5, 15, "fizz", "buzz"), Hits: 2},
+	}
+	got := store.Entries()
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Entries() = %v, want %v", got, want)
+	}
+
+	got[0].Hits = 100
+	if again := store.Entries(); again[0].Hits != 1 {
+		t.Fatalf("Entries() returned shared data, got %v after mutation", again)
+	}
+
+	if empty := NewStore().Entries(); len(empty) != 0 {
+		t.Fatalf("Entries() on empty store = %v, want none", empty)
+	}
+}
+
+func TestStore_Ranked(t *testing.T) {
+	store := NewStore()
+	low := createParams(3, 5, 15, "fizz", "buzz")
+	tieB := createParams(2, 7, 30, "foo", "bar")
+	tieA := createParams(2, 5, 30, "foo", "bar")
+	high := createParams(9, 9, 9, "x", "y")
+
+	for params, hits := range map[RequestParams]int{low: 1, tieB: 2, tieA: 2, high: 3} {
+		for range hits {
+			store.Record(params)
+		}
+	}
+
+	want := []Stats{{high, 3}, {tieA, 2}, {tieB, 2}, {low, 1}}
+	if got := store.Ranked(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Ranked() = %v, want %v", got, want)
+	}
+}
+
+func TestStore_Clone(t *testing.T) {
+	store := NewStore()
+	store.Record(createParams(3, 5, 15, "fizz", "buzz"))
+	store.Record(createParams(3, 5, 15, "fizz", "buzz"))
+	store.Record(createParams(2, 7, 15, "foo", "bar"))
+
+	got := store.Clone()
+	want := map[RequestParams]int{
+		createParams(3, 5, 15, "fizz", "buzz"): 2,
+		createParams(2, 7, 15, "foo", "bar"):   1,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Clone() = %v, want %v", got, want)
+	}
+
+	got[createParams(3, 5, 15, "fizz", "buzz")] = 100
+	delete(got, createParams(2, 7, 15, "foo", "bar"))
+	if again := store.Clone(); !reflect.DeepEqual(again, want) {
+		t.Fatalf("Clone() shares state with the store, got %v after mutation", again)
+	}
+}
+
+func TestStore_Diff(t *testing.T) {
+	store := NewStore(WithMaxEntries(2))
+	steady := createParams(3, 5, 15, "fizz", "buzz")
+	evicted := createParams(1, 1, 1, "a", "b")
+	added := createParams(9, 9, 9, "x", "y")
+
+	for range 5 {
+		store.Record(steady)
+	}
+	store.Record(evicted)
+	before := store.Clone()
+
+	// added pushes evicted out, so it is missing from the current counts.
+	store.Record(added)
+	store.Record(added)
+
+	want := map[RequestParams]int{added: 2}
+	if got := store.Diff(before); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Diff() = %v, want %v", got, want)
+	}
+
+	store.Record(steady)
+	want = map[RequestParams]int{steady: 1, added: 2}
+	if got := store.Diff(before); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Diff() after growth = %v, want %v", got, want)
+	}
+	if got := store.Diff(store.Clone()); len(got) != 0 {
+		t.Fatalf("Diff(current) = %v, want empty", got)
+	}
+	if got := store.Diff(nil); !reflect.DeepEqual(got, store.Clone()) {
+		t.Fatalf("Diff(nil) = %v, want every entry", got)
+	}
+}
+
+func TestStore_Clone_Concurrent(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		store := NewStore()
+
+		var wg sync.WaitGroup
+		for i := range 100 {
+			wg.Go(func() {
+				store.Record(createParams(3, 5, 10+i%4, "fizz", "buzz"))
+			})
+			wg.Go(func() {
+				total := 0
+				for _, hits := range store.Clone() {
+					total += hits
+				}
+				if total > 100 {
+					t.Errorf("Clone() saw %d hits, more than were recorded", total)
+				}
+			})
+		}
+		wg.Wait()
+
+		total := 0
+		for _, hits := range store.Clone() {
+			total += hits
+		}
+		if total != 100 {
+			t.Fatalf("Clone() total hits = %d, want 100", total)
+		}
+	})
+}
+
+func TestStore_Breakdown(t *testing.T) {
+	store := NewStore(WithMaxEntries(2))
+	store.Record(createParams(3, 5, 15, "fizz", "buzz"))
+	store.Record(createParams(3, 7, 100, "fizz", "bar"))
+	store.Record(createParams(2, 7, 100, "foo", "bar"))
+	store.Record(createParams(3, 5, 100, "fizz", "buzz"))
+
+	want := Breakdown{
+		Int1Counts:  map[int64]int{3: 3, 2: 1},
+		Int2Counts:  map[int64]int{5: 2, 7: 2},
+		LimitCounts: map[int]int{15: 1, 100: 3},
+		Str1Counts:  map[string]int{"fizz": 3, "foo": 1},
+		Str2Counts:  map[string]int{"buzz": 2, "bar": 2},
+	}
+	got := store.Breakdown()
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Breakdown() = %+v, want %+v", got, want)
+	}
+
+	got.Str1Counts["fizz"] = 0
+	if again := store.Breakdown(); again.Str1Counts["fizz"] != 3 {
+		t.Fatalf("Breakdown() returned shared maps, got %v after mutation", again.Str1Counts)
+	}
+}
+
+func TestStore_Breakdown_BoundedByMaxEntries(t *testing.T) {
+	store := NewStore(WithMaxEntries(3))
+	frequent := createParams(3, 5, 15, "fizz", "buzz")
+	for range 5 {
+		store.Record(frequent)
+	}
+	for i := range 100 {
+		word := fmt.Sprintf("word%d", i)
+		store.Record(createParams(int64(i+10), int64(i+10), i+100, word, word))
+	}
+
+	got := store.Breakdown()
+	for name, size := range map[string]int{
+		"int1": len(got.Int1Counts), "int2": len(got.Int2Counts), "limit": len(got.LimitCounts),
+		"str1": len(got.Str1Counts), "str2": len(got.Str2Counts),
+	} {
+		if size != 3 {
+			t.Fatalf("%s counts hold %d values, want 3", name, size)
+		}
+	}
+	if got.Str1Counts["fizz"] != 5 || got.LimitCounts[15] != 5 {
+		t.Fatalf("expected the most counted values to stay, got %v and %v", got.Str1Counts, got.LimitCounts)
+	}
+	if str1, _, _ := store.TopWords(10); len(str1) != 3 {
+		t.Fatalf("TopWords() holds %d str1 values, want 3", len(str1))
+	}
+}
+
+func TestStore_Breakdown_Concurrent(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		store := NewStore()
+
+		var wg sync.WaitGroup
+		for i := range 100 {
+			wg.Go(func() {
+				store.Record(createParams(int64(1+i%2), 5, 10, "fizz", "buzz"))
+				_ = store.Breakdown()
+			})
+		}
+		wg.Wait()
+
+		got := store.Breakdown()
+		if want := map[int64]int{1: 50, 2: 50}; !reflect.DeepEqual(got.Int1Counts, want) {
+			t.Fatalf("Int1Counts = %v, want %v", got.Int1Counts, want)
+		}
+		if got.Str1Counts["fizz"] != 100 {
+			t.Fatalf("Str1Counts[fizz] = %d, want 100", got.Str1Counts["fizz"])
+		}
+	})
+}
+
+func TestStore_LimitHistogram_Concurrent(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		store := NewStore()
+
+		var wg sync.WaitGroup
+		for i := range 100 {
+			wg.Go(func() {
+				store.Record(createParams(3, 5, 10+i%4, "fizz", "buzz"))
+				_ = store.LimitHistogram()
+			})
+		}
+		wg.Wait()
+
+		want := map[int]int{10: 25, 11: 25, 12: 25, 13: 25}
+		if got := store.LimitHistogram(); !reflect.DeepEqual(got, want) {
+			t.Fatalf("LimitHistogram() = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestStore_RecordFailure(t *testing.T) {
+	store := NewStore()
+	if got := store.Failures(); len(got) != 0 {
+		t.Fatalf("Failures() = %v, want empty", got)
+	}
+
+	store.RecordFailure("missing_parameter")
+	store.RecordFailure("missing_parameter")
+	store.RecordFailure("invalid_integer")
+
+	want := map[string]int{"missing_parameter": 2, "invalid_integer": 1}
+	if got := store.Failures(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Failures() = %v, want %v", got, want)
+	}
+
+	if _, ok := store.GetMostFrequent(); ok {
+		t.Fatal("failures must not count as successful requests")
+	}
+}
+
+func TestStore_WithMaxEntries_EvictsLeastRecentlyRecorded(t *testing.T) {
+	store := NewStore(WithMaxEntries(3))
+
+	popular := createParams(3, 5, 15, "fizz", "buzz")
+	oldest := createParams(1, 1, 1, "old", "old")
+	middle := createParams(2, 2, 2, "mid", "mid")
+	newest := createParams(4, 4, 4, "new", "new")
+
+	store.Record(popular)
+	store.Record(popular)
+	store.Record(oldest)
+	store.Record(middle)
+	store.Record(newest)
+
+	if got := store.Len(); got != 3 {
+		t.Fatalf("Len() = %d, want 3", got)
+	}
+	if hits := hitsFor(store, oldest); hits != 0 {
+		t.Fatalf("expected oldest entry to be evicted, still has %d hits", hits)
+	}
+	for _, params := range []RequestParams{popular, middle, newest} {
+		if hits := hitsFor(store, params); hits == 0 {
+			t.Fatalf("expected %+v to be retained", params)
+		}
+	}
+
+	store.Record(oldest)
+	if got := store.Len(); got != 3 {
+		t.Fatalf("Len() = %d after further recording, want 3", got)
+	}
+	if hits := hitsFor(store, oldest); hits != 1 {
+		t.Fatalf("expected re-recorded entry to restart at 1 hit, got %d", hits)
+	}
+	if hits := hitsFor(store, middle); hits != 0 {
+		t.Fatalf("expected middle entry to be evicted next, still has %d hits", hits)
+	}
+
+	stats, ok := store.GetMostFrequent()
+	if !ok {
+		t.Fatal("expected statistics to remain available")
+	}
+	assertStats(t, stats, popular, 2)
+}
+
+func TestStore_WithMaxEntries_ProtectsMostFrequent(t *testing.T) {
+	store := NewStore(WithMaxEntries(2))
+
+	top := createParams(3, 5, 15, "fizz", "buzz")
+	store.Record(top)
+	store.Record(top)
+
+	for i := range 10 {
+		store.Record(createParams(1, 1, i+100, "noise", "noise"))
+	}
+
+	if got := store.Len(); got != 2 {
+		t.Fatalf("Len() = %d, want 2", got)
+	}
+	stats, ok := store.GetMostFrequent()
+	if !ok {
+		t.Fatal("expected statistics to remain available")
+	}
+	assertStats(t, stats, top, 2)
+}
+
+func TestStore_WithMaxEntries_Concurrent(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		store := NewStore(WithMaxEntries(10))
+
+		var wg sync.WaitGroup
+		for i := range 200 {
+			wg.Go(func() {
+				store.Record(createParams(3, 5, i, "fizz", "buzz"))
+			})
+		}
+		wg.Wait()
+
+		if got := store.Len(); got != 10 {
+			t.Fatalf("Len() = %d, want 10", got)
+		}
+	})
+}
+
+func TestStore_WithDecay_FavorsRecentRequests(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		store := NewStore(WithDecay(time.Minute))
+		ctx, cancel := context.WithCancel(t.Context())
+		defer cancel()
+		go store.RunDecay(ctx)
+
+		old := createParams(3, 5, 15, "fizz", "buzz")
+		for range 10 {
+			store.Record(old)
+		}
+
+		// Three half-lives bring the old weight down to 10/8.
+		time.Sleep(3 * time.Minute)
+		synctest.Wait()
+
+		recent := createParams(2, 7, 30, "foo", "bar")
+		store.Record(recent)
+		store.Record(recent)
+
+		stats, ok := store.GetMostFrequent()
+		if !ok {
+			t.Fatal("expected statistics to be available")
+		}
+		assertStats(t, stats, recent, 2)
+
+		// The old set still qualifies on all-time hits.
+		stats, ok = store.GetMostFrequentAtLeast(3)
+		if !ok {
+			t.Fatal("expected the old set to meet min hits")
+		}
+		assertStats(t, stats, old, 10)
+	})
+}
+
+func TestStore_WithoutDecay_KeepsAllTimeCounts(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		store := NewStore()
+		ctx, cancel := context.WithCancel(t.Context())
+		defer cancel()
+		go store.RunDecay(ctx)
+
+		old := createParams(3, 5, 15, "fizz", "buzz")
+		for range 10 {
+			store.Record(old)
+		}
+		time.Sleep(3 * time.Minute)
+		synctest.Wait()
+		store.Record(createParams(2, 7, 30, "foo", "bar"))
+		store.Record(createParams(2, 7, 30, "foo", "bar"))
+
+		stats, ok := store.GetMostFrequent()
+		if !ok {
+			t.Fatal("expected statistics to be available")
+		}
+		assertStats(t, stats, old, 10)
+	})
+}
+
+func TestStore_Decay(t *testing.T) {
+	store := NewStore(WithDecay(time.Hour))
+
+	a := createParams(3, 5, 15, "fizz", "buzz")
+	b := createParams(2, 7, 30, "foo", "bar")
+	for range 4 {
+		store.Record(a)
+	}
+	store.Decay(0.25)
+	store.Record(b)
+	store.Record(b)
+
+	stats, ok := store.GetMostFrequent()
+	if !ok {
+		t.Fatal("expected statistics to be available")
+	}
+	assertStats(t, stats, b, 2)
+}
+
+func TestStore_WithHotParams_MatchesLockedStore(t *testing.T) {
+	classic := createParams(3, 5, 100, "fizz", "buzz")
+	short := createParams(3, 5, 15, "fizz", "buzz")
+	cold := createParams(2, 7, 100, "foo", "buzz")
+
+	locked := NewStore()
+	hot := NewStore(WithHotParams(classic, short, createParams(9, 9, 9, "never", "seen")))
+
+	for _, store := range []*Store{locked, hot} {
+		for range 5 {
+			store.Record(classic)
+		}
+		for range 3 {
+			store.Record(cold)
+		}
+		store.Record(short)
+	}
+
+	if got, want := hot.Len(), locked.Len(); got != want {
+		t.Fatalf("Len() = %d, want %d", got, want)
+	}
+	if got, want := hot.Clone(), locked.Clone(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Clone() = %v, want %v", got, want)
+	}
+	if got, want := hot.Entries(), locked.Entries(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Entries() = %v, want %v", got, want)
+	}
+	if got, want := hot.LimitHistogram(), locked.LimitHistogram(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("LimitHistogram() = %v, want %v", got, want)
+	}
+	if got, want := hot.Breakdown(), locked.Breakdown(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Breakdown() = %+v, want %+v", got, want)
+	}
+
+	stats, ok := hot.GetMostFrequent()
+	if !ok {
+		t.Fatal("expected statistics to be available")
+	}
+	assertStats(t, stats, classic, 5)
+
+	stats, ok = hot.GetMostFrequentAtLeast(3)
+	if !ok {
+		t.Fatal("expected a set with at least 3 hits")
+	}
+	assertStats(t, stats, classic, 5)
+	if _, ok := hot.GetMostFrequentAtLeast(6); ok {
+		t.Fatal("expected no set with at least 6 hits")
+	}
+}
+
+func TestStore_WithHotParams_Concurrent(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		classic := createParams(3, 5, 100, "fizz", "buzz")
+		store := NewStore(WithHotParams(classic))
+
+		var wg sync.WaitGroup
+		for i := range 200 {
+			wg.Go(func() {
+				store.Record(classic)
+				store.Record(createParams(2, 7, i%4, "foo", "bar"))
+			})
+		}
+		wg.Wait()
+
+		stats, ok := store.GetMostFrequent()
+		if !ok {
+			t.Fatal("expected statistics to be available")
+		}
+		assertStats(t, stats, classic, 200)
+		if got := store.LimitHistogram()[100]; got != 200 {
+			t.Fatalf("LimitHistogram()[100] = %d, want 200", got)
+		}
+	})
+}
+
+func TestStore_WithHotParams_IgnoredWithDecay(t *testing.T) {
+	classic := createParams(3, 5, 100, "fizz", "buzz")
+	store := NewStore(WithHotParams(classic), WithDecay(time.Minute))
+
+	store.Record(classic)
+	store.Decay(0.5)
+	store.Record(createParams(2, 7, 30, "foo", "bar"))
+
+	stats, ok := store.GetMostFrequent()
+	if !ok {
+		t.Fatal("expected statistics to be available")
+	}
+	assertStats(t, stats, createParams(2, 7, 30, "foo", "bar"), 1)
+}
+
+func TestStore_GetMostFrequent_MatchesRanked(t *testing.T) {
+	hot := createParams(3, 5, 0, "fizz", "buzz")
+
+	for _, tc := range []struct {
+		name  string
+		opts  []Option
+		decay bool
+	}{
+		{name: "plain"},
+		{name: "bounded", opts: []Option{WithMaxEntries(8)}},
+		{name: "decay", opts: []Option{WithDecay(time.Hour)}, decay: true},
+		{name: "bounded decay", opts: []Option{WithMaxEntries(8), WithDecay(time.Hour)}, decay: true},
+		{name: "hot", opts: []Option{WithHotParams(hot)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			store := NewStore(tc.opts...)
+			rng := rand.New(rand.NewPCG(1, 2))
+
+			for i := range 2000 {
+				store.Record(createParams(3, 5, rng.IntN(20), "fizz", "buzz"))
+				if tc.decay && i%50 == 0 {
+					store.Decay(0.5)
+				}
+
+				for _, min := range []int{1, 5, 50} {
+					want, wantOK := naiveMostFrequent(store, min)
+					got, ok := store.GetMostFrequentAtLeast(min)
+					if ok != wantOK {
+						t.Fatalf("record %d, min %d: ok = %v, want %v", i, min, ok, wantOK)
+					}
+					if ok && *got != want {
+						t.Fatalf("record %d, min %d: got %+v, want %+v", i, min, *got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestStore_GetMostFrequent_TieBreak(t *testing.T) {
+	store := NewStore()
+
+	first := createParams(2, 7, 30, "foo", "bar")
+	second := createParams(3, 5, 15, "fizz", "buzz")
+	store.Record(second)
+	store.Record(first)
+
+	stats, ok := store.GetMostFrequent()
+	if !ok {
+		t.Fatal("expected statistics to be available")
+	}
+	assertStats(t, stats, first, 1)
+}
+
+func BenchmarkStore_Record(b *testing.B) {
+	classic := createParams(3, 5, 100, "fizz", "buzz")
+
+	for _, bc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"locked", nil},
+		{"hot", []Option{WithHotParams(classic)}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			store := NewStore(bc.opts...)
+
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					store.Record(classic)
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkStore_GetMostFrequent(b *testing.B) {
+	classic := createParams(3, 5, 100, "fizz", "buzz")
+
+	for _, bc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"locked", nil},
+		{"hot", []Option{WithHotParams(classic)}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			store := NewStore(bc.opts...)
+			for i := range 100 {
+				store.Record(createParams(3, 5, i, "fizz", "buzz"))
+				store.Record(classic)
+			}
+
+			b.ReportAllocs()
+			for b.Loop() {
+				store.GetMostFrequent()
+			}
+		})
+	}
+}
+
+func BenchmarkStore_GetMostFrequent_Distinct(b *testing.B) {
+	for _, size := range []int{1_000, 100_000} {
+		store := NewStore()
+		for i := range size {
+			store.Record(createParams(3, 5, i, "fizz", "buzz"))
+		}
+
+		b.Run(fmt.Sprintf("leader/%d", size), func(b *testing.B) {
+			for b.Loop() {
+				store.GetMostFrequent()
+			}
+		})
+		// A match forces the full scan GetMostFrequent used to do.
+		b.Run(fmt.Sprintf("scan/%d", size), func(b *testing.B) {
+			all := func(RequestParams) bool { return true }
+			for b.Loop() {
+				store.GetMostFrequentMatching(1, all)
+			}
+		})
+	}
+}
+
 func TestRequestParams_AsMapKey(t *testing.T) {
 	paramsA := createParams(3, 5, 15, "fizz", "buzz")
 	paramsB := createParams(3, 5, 15, "fizz", "buzz")
@@ -284,7 +1082,25 @@ func assertStats(t *testing.T, got *Stats, wantParams RequestParams, wantHits in
 	}
 }
 
-func createParams(int1, int2, limit int, str1, str2 string) RequestParams {
+// naiveMostFrequent is the reference GetMostFrequentAtLeast: the first
+// entry of Ranked with at least min hits.
+func naiveMostFrequent(store *Store, min int) (Stats, bool) {
+	for _, stats := range store.Ranked() {
+		if stats.Hits >= min {
+			return stats, true
+		}
+	}
+	return Stats{}, false
+}
+
+func hitsFor(store *Store, params RequestParams) int {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	return store.requests[params]
+}
+
+func createParams(int1, int2 int64, limit int, str1, str2 string) RequestParams {
 	return RequestParams{
 		Int1:  int1,
 		Int2:  int2,